@@ -0,0 +1,105 @@
+// Command dlq-replay writes the messages in the dead-letter topic back to the topics they came
+// from, so the consumers handle them again.
+//
+// It reads the dead-letter topic as its own consumer group and commits each message once it has
+// been written back, so a second run picks up where the first one stopped. It exits once no
+// message has arrived for -wait.
+//
+//	go run ./cmd/dlq-replay -dry-run
+//	go run ./cmd/dlq-replay -limit 100
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/flash_sale/flash_sale_order_service/config"
+	consumer "github.com/flash_sale/flash_sale_order_service/kafka"
+	"github.com/flash_sale/flash_sale_order_service/outbox"
+	"github.com/segmentio/kafka-go"
+)
+
+// replayGroupID is the consumer group the dead-letter topic is read as.
+const replayGroupID = "dlq-replay-group"
+
+func main() {
+	cfg := config.Load()
+
+	topic := flag.String("topic", cfg.KafkaDeadLetterTopic, "dead-letter topic to replay")
+	limit := flag.Int("limit", 0, "replay at most this many messages, 0 for all")
+	wait := flag.Duration("wait", 5*time.Second, "stop once no message has arrived for this long")
+	dryRun := flag.Bool("dry-run", false, "print the messages without replaying or committing them")
+	flag.Parse()
+
+	if *topic == "" {
+		log.Fatal("no dead-letter topic: set KAFKA_DEAD_LETTER_TOPIC or -topic")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     cfg.KafkaBrokers,
+		Topic:       *topic,
+		GroupID:     replayGroupID,
+		StartOffset: kafka.FirstOffset,
+	})
+	defer reader.Close()
+
+	writer := outbox.NewWriter(cfg.KafkaBrokers)
+	defer writer.Close()
+
+	replayed := 0
+	for *limit == 0 || replayed < *limit {
+		fetchCtx, cancel := context.WithTimeout(ctx, *wait)
+		msg, err := reader.FetchMessage(fetchCtx)
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			break
+		}
+		if err != nil {
+			log.Fatalf("failed to fetch message: %v", err)
+		}
+
+		replay, err := consumer.ReplayMessage(msg)
+		if err != nil {
+			log.Fatalf("failed to replay message: %v", err)
+		}
+
+		fmt.Printf("%d/%d -> %s key=%q error=%q\n", msg.Partition, msg.Offset, replay.Topic, msg.Key, header(msg, consumer.HeaderDLQError))
+		if *dryRun {
+			replayed++
+			continue
+		}
+
+		if err := writer.WriteMessages(ctx, replay); err != nil {
+			log.Fatalf("failed to write message back to %s: %v", replay.Topic, err)
+		}
+		if err := reader.CommitMessages(ctx, msg); err != nil {
+			log.Fatalf("failed to commit message: %v", err)
+		}
+		replayed++
+	}
+
+	if *dryRun {
+		fmt.Printf("%d messages would be replayed\n", replayed)
+		return
+	}
+	fmt.Printf("replayed %d messages\n", replayed)
+}
+
+// header returns the value of a message header, or "" if there is none.
+func header(msg kafka.Message, key string) string {
+	for _, h := range msg.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
@@ -2,29 +2,34 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	_ "expvar"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 
 	"github.com/flash_sale/flash_sale_order_service/config"
+	"github.com/flash_sale/flash_sale_order_service/currency"
 	consumer "github.com/flash_sale/flash_sale_order_service/kafka"
 
 	"github.com/flash_sale/flash_sale_order_service/genproto/order_service"
+	"github.com/flash_sale/flash_sale_order_service/money"
+	"github.com/flash_sale/flash_sale_order_service/outbox"
+	"github.com/flash_sale/flash_sale_order_service/pricing"
+	"github.com/flash_sale/flash_sale_order_service/quote"
 	"github.com/flash_sale/flash_sale_order_service/service"
+	"github.com/flash_sale/flash_sale_order_service/shipping"
 	"github.com/flash_sale/flash_sale_order_service/storage/postgres"
 	"github.com/flash_sale/flash_sale_order_service/storage/redis"
+	"github.com/flash_sale/flash_sale_order_service/tax"
+	"github.com/flash_sale/flash_sale_order_service/worker"
 	"google.golang.org/grpc"
 )
 
 func main() {
 	cfg := config.Load()
 
-	// Initialize PostgreSQL storage
-	pgStorage, err := postgres.NewStoragePg(cfg)
-	if err != nil {
-		log.Fatalf("failed to initialize PostgreSQL storage: %v", err)
-	}
-
 	// Initialize Redis client
 	redisClient, err := redis.Connect(&cfg)
 	if err != nil {
@@ -32,33 +37,143 @@ func main() {
 	}
 	defer redisClient.Close()
 
-	// Initialize Kafka consumers
+	// Exchange rates used to charge baskets in other currencies than the base one
+	exchangeRates := currency.NewStaticProvider(money.DefaultCurrency, nil)
+	if cfg.ExchangeRatesFile != "" {
+		exchangeRates, err = currency.LoadStaticProvider(cfg.ExchangeRatesFile)
+		if err != nil {
+			log.Fatalf("failed to load exchange rates: %v", err)
+		}
+	}
+
+	// Rules deciding which promotions of a line or basket may be combined
+	pricingRules := pricing.DefaultRules()
+	if cfg.PricingRulesFile != "" {
+		pricingRules, err = pricing.LoadRules(cfg.PricingRulesFile)
+		if err != nil {
+			log.Fatalf("failed to load pricing rules: %v", err)
+		}
+	}
+
+	// Tax rates of the regions orders are delivered to
+	var taxes *tax.Table
+	if cfg.TaxRatesFile != "" {
+		taxes, err = tax.LoadTable(cfg.TaxRatesFile)
+		if err != nil {
+			log.Fatalf("failed to load tax rates: %v", err)
+		}
+	}
+
+	// Areas orders are delivered to and what delivering there costs
+	var deliveryZones *shipping.Zones
+	if cfg.DeliveryZonesFile != "" {
+		deliveryZones, err = shipping.LoadZones(cfg.DeliveryZonesFile)
+		if err != nil {
+			log.Fatalf("failed to load delivery zones: %v", err)
+		}
+	}
+
+	// Key price quotes are signed with
+	quoteKey := []byte(cfg.QuoteSigningKey)
+	if len(quoteKey) == 0 {
+		quoteKey = make([]byte, 32)
+		if _, err := rand.Read(quoteKey); err != nil {
+			log.Fatalf("failed to generate quote signing key: %v", err)
+		}
+		log.Println("QUOTE_SIGNING_KEY is not set, price quotes will not survive a restart")
+	}
+
+	// Initialize PostgreSQL storage, with Redis as the flash sale stock gate
+	pgStorage, err := postgres.NewStoragePg(cfg, redisClient, exchangeRates, pricing.NewEngine(pricingRules), quote.NewSigner(quoteKey, cfg.QuoteTTL), taxes, deliveryZones)
+	if err != nil {
+		log.Fatalf("failed to initialize PostgreSQL storage: %v", err)
+	}
+	defer pgStorage.Close()
+
+	// Keep the Redis flash sale stock counters in line with PostgreSQL
+	stockReconciler := redis.NewStockReconciler(redisClient, pgStorage, cfg.FlashSaleStockSyncInterval)
+	go func() {
+		if err := stockReconciler.Run(context.Background()); err != nil {
+			log.Printf("flash sale stock reconciler stopped: %v", err)
+		}
+	}()
+
+	// Give back the stock of flash sale basket items that were never checked out
+	if cfg.FlashSaleHoldEnabled {
+		stockHoldSweeper := worker.NewStockHoldSweeper(pgStorage, cfg.FlashSaleHoldSweepInterval)
+		go func() {
+			if err := stockHoldSweeper.Run(context.Background()); err != nil {
+				log.Printf("stock hold sweeper stopped: %v", err)
+			}
+		}()
+	}
+
+	// Forget the results of idempotent requests once retries are no longer replayed
+	if cfg.IdempotencyKeyTTL > 0 {
+		idempotencyKeySweeper := worker.NewIdempotencyKeySweeper(pgStorage, cfg.IdempotencyKeySweepInterval)
+		go func() {
+			if err := idempotencyKeySweeper.Run(context.Background()); err != nil {
+				log.Printf("idempotency key sweeper stopped: %v", err)
+			}
+		}()
+	}
+
+	// Publish the order and basket events written to the outbox
+	kafkaWriter := outbox.NewWriter(cfg.KafkaBrokers)
+	defer kafkaWriter.Close()
+	outboxRelay := outbox.NewRelay(pgStorage.Outbox(), kafkaWriter, cfg.OutboxRelayInterval, cfg.OutboxBatchSize)
+	go func() {
+		if err := outboxRelay.Run(context.Background()); err != nil {
+			log.Printf("outbox relay stopped: %v", err)
+		}
+	}()
+
+	// Initialize Kafka consumers, retrying failed messages before they go to the dead-letter topic
+	retrier := consumer.NewRetrier(consumer.RetryPolicy{
+		MaxAttempts:    cfg.KafkaRetryMaxAttempts,
+		InitialBackoff: cfg.KafkaRetryInitialBackoff,
+		MaxBackoff:     cfg.KafkaRetryMaxBackoff,
+	}, kafkaWriter, cfg.KafkaDeadLetterTopic)
 	basketItemConsumer := consumer.NewBasketItemConsumer(
 		cfg.KafkaBrokers,
 		"basket_item_topic",
 		pgStorage,
+		retrier,
+		consumer.NewReplier(kafkaWriter, cfg.KafkaBasketReplyTopic),
+		cfg.KafkaConsumerConcurrency,
 	)
 	basketToOrderConsumer := consumer.NewBasketToOrderConsumer(
 		cfg.KafkaBrokers,
 		"basket_to_order_topic",
 		pgStorage,
+		retrier,
+		cfg.KafkaConsumerConcurrency,
 	)
 
 	// Start consumers in separate goroutines
 	go func() {
 		log.Println("basket_item_topic is ready to accept requests.")
-		if err := basketItemConsumer.Consume(context.Background()); err != nil {
+		if err := basketItemConsumer.Run(context.Background()); err != nil {
 			log.Fatalf("basket item consumer error: %v", err)
 		}
 	}()
 
 	go func() {
 		log.Println("basket_to_order_topic is ready to accept requests.")
-		if err := basketToOrderConsumer.Consume(context.Background()); err != nil {
+		if err := basketToOrderConsumer.Run(context.Background()); err != nil {
 			log.Fatalf("basket to order consumer error: %v", err)
 		}
 	}()
 
+	// Serve the expvar metrics, such as the Kafka consumer counters, at /debug/vars
+	if cfg.MetricsAddress != "" {
+		go func() {
+			if err := http.ListenAndServe(cfg.MetricsAddress, nil); err != nil {
+				log.Printf("metrics server stopped: %v", err)
+			}
+		}()
+	}
+
 	// Initialize gRPC server
 	lis, err := net.Listen("tcp", cfg.OrderServicePort)
 	if err != nil {
@@ -72,6 +187,8 @@ func main() {
 	order_service.RegisterBasketItemServiceServer(s, service.NewBasketItemService(pgStorage))
 	order_service.RegisterOrderServiceServer(s, service.NewOrderService(pgStorage, redisClient))
 	order_service.RegisterOrderItemServiceServer(s, service.NewOrderItemService(pgStorage, redisClient))
+	order_service.RegisterCouponServiceServer(s, service.NewCouponService(pgStorage))
+	order_service.RegisterAddressServiceServer(s, service.NewAddressService(pgStorage))
 
 	fmt.Printf("server listening at %v\n", lis.Addr())
 	if err := s.Serve(lis); err != nil {
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/cast"
@@ -18,13 +19,76 @@ type Config struct {
 	PostgresUser     string
 	PostgresPassword string
 	PostgresDB       string
-	KafkaBrokers     []string
-	LOG_PATH         string
+
+	// PostgreSQL connection pool
+	PostgresMaxConns          int32
+	PostgresMinConns          int32
+	PostgresMaxConnLifetime   time.Duration
+	PostgresMaxConnIdleTime   time.Duration
+	PostgresHealthCheckPeriod time.Duration
+
+	KafkaBrokers []string
+	LOG_PATH     string
+
+	// Retries of Kafka messages that fail to be handled, and the dead-letter topic they are sent to
+	// once retries run out; an empty topic drops them
+	KafkaRetryMaxAttempts    int
+	KafkaRetryInitialBackoff time.Duration
+	KafkaRetryMaxBackoff     time.Duration
+	KafkaDeadLetterTopic     string
+
+	// Topic the replies to basket commands received over Kafka are published to; empty means only
+	// commands with a reply-to header are replied to
+	KafkaBasketReplyTopic string
+
+	// How many messages of one partition a Kafka consumer handles at once; above 1 they may be handled out of order
+	KafkaConsumerConcurrency int
+
+	// Address the expvar metrics are served on at /debug/vars; empty means they are not served
+	MetricsAddress string
 
 	// Redis Configuration
 	RedisAddress  string
 	RedisPassword string
 	RedisDB       int
+
+	// Interval at which Redis flash sale stock counters are reconciled with PostgreSQL
+	FlashSaleStockSyncInterval time.Duration
+
+	// Flash sale stock holds placed when an item is added to a basket
+	FlashSaleHoldEnabled       bool
+	FlashSaleHoldTTL           time.Duration
+	FlashSaleHoldSweepInterval time.Duration
+
+	// Per-user flash sale purchase caps, 0 means unlimited; an event or product may set its own
+	FlashSaleMaxPerUserPerProduct int32
+	FlashSaleMaxPerUserPerEvent   int32
+
+	// JSON file of exchange rates from the base currency; empty means only the base currency is accepted
+	ExchangeRatesFile string
+
+	// JSON file of promotion stacking rules; empty means pricing.DefaultRules
+	PricingRulesFile string
+
+	// Key price quotes are signed with and how long they are honored; an empty key is replaced
+	// by a random one on start, which invalidates the quotes issued before a restart
+	QuoteSigningKey string
+	QuoteTTL        time.Duration
+
+	// JSON file of tax regions and rates; empty means orders are not taxed
+	TaxRatesFile string
+
+	// GeoJSON file of delivery zones and their fees; empty means orders are delivered anywhere for free
+	DeliveryZonesFile string
+
+	// How long the result of a request sent with an idempotency key is replayed to retries, 0 turns
+	// idempotency keys off, and how often expired keys are removed
+	IdempotencyKeyTTL           time.Duration
+	IdempotencyKeySweepInterval time.Duration
+
+	// How often the outbox relay publishes order and basket events to Kafka, and how many per batch
+	OutboxRelayInterval time.Duration
+	OutboxBatchSize     int
 }
 
 // Load loads the configuration from environment variables.
@@ -44,13 +108,55 @@ func Load() Config {
 	config.PostgresPassword = cast.ToString(coalesce("POSTGRES_PASSWORD", "example"))
 	config.PostgresDB = cast.ToString(coalesce("POSTGRES_DB", "memory"))
 
+	// PostgreSQL connection pool
+	config.PostgresMaxConns = cast.ToInt32(coalesce("POSTGRES_MAX_CONNS", 20))
+	config.PostgresMinConns = cast.ToInt32(coalesce("POSTGRES_MIN_CONNS", 2))
+	config.PostgresMaxConnLifetime = cast.ToDuration(coalesce("POSTGRES_MAX_CONN_LIFETIME", "1h"))
+	config.PostgresMaxConnIdleTime = cast.ToDuration(coalesce("POSTGRES_MAX_CONN_IDLE_TIME", "30m"))
+	config.PostgresHealthCheckPeriod = cast.ToDuration(coalesce("POSTGRES_HEALTH_CHECK_PERIOD", "1m"))
+
 	// Redis Configuration
 	config.RedisAddress = cast.ToString(coalesce("REDIS_ADDRESS", "redis:6379"))
 	config.RedisPassword = cast.ToString(coalesce("REDIS_PASSWORD", ""))
 	config.RedisDB = cast.ToInt(coalesce("REDIS_DB", 0))
 
+	config.FlashSaleStockSyncInterval = cast.ToDuration(coalesce("FLASH_SALE_STOCK_SYNC_INTERVAL", "5s"))
+
+	config.FlashSaleHoldEnabled = cast.ToBool(coalesce("FLASH_SALE_HOLD_ENABLED", false))
+	config.FlashSaleHoldTTL = cast.ToDuration(coalesce("FLASH_SALE_HOLD_TTL", "10m"))
+	config.FlashSaleHoldSweepInterval = cast.ToDuration(coalesce("FLASH_SALE_HOLD_SWEEP_INTERVAL", "30s"))
+
+	config.FlashSaleMaxPerUserPerProduct = cast.ToInt32(coalesce("FLASH_SALE_MAX_PER_USER_PER_PRODUCT", 0))
+	config.FlashSaleMaxPerUserPerEvent = cast.ToInt32(coalesce("FLASH_SALE_MAX_PER_USER_PER_EVENT", 0))
+
+	config.ExchangeRatesFile = cast.ToString(coalesce("EXCHANGE_RATES_FILE", ""))
+
+	config.PricingRulesFile = cast.ToString(coalesce("PRICING_RULES_FILE", ""))
+
+	config.QuoteSigningKey = cast.ToString(coalesce("QUOTE_SIGNING_KEY", ""))
+	config.QuoteTTL = cast.ToDuration(coalesce("QUOTE_TTL", "5m"))
+
+	config.TaxRatesFile = cast.ToString(coalesce("TAX_RATES_FILE", ""))
+
+	config.DeliveryZonesFile = cast.ToString(coalesce("DELIVERY_ZONES_FILE", ""))
+
+	config.IdempotencyKeyTTL = cast.ToDuration(coalesce("IDEMPOTENCY_KEY_TTL", "24h"))
+	config.IdempotencyKeySweepInterval = cast.ToDuration(coalesce("IDEMPOTENCY_KEY_SWEEP_INTERVAL", "1h"))
+
+	config.OutboxRelayInterval = cast.ToDuration(coalesce("OUTBOX_RELAY_INTERVAL", "1s"))
+	config.OutboxBatchSize = cast.ToInt(coalesce("OUTBOX_BATCH_SIZE", 100))
+
 	config.KafkaBrokers = cast.ToStringSlice(coalesce("KAFKA_BROKERS", []string{"kafka:9092"}))
 
+	config.KafkaRetryMaxAttempts = cast.ToInt(coalesce("KAFKA_RETRY_MAX_ATTEMPTS", 5))
+	config.KafkaRetryInitialBackoff = cast.ToDuration(coalesce("KAFKA_RETRY_INITIAL_BACKOFF", "200ms"))
+	config.KafkaRetryMaxBackoff = cast.ToDuration(coalesce("KAFKA_RETRY_MAX_BACKOFF", "10s"))
+	config.KafkaDeadLetterTopic = cast.ToString(coalesce("KAFKA_DEAD_LETTER_TOPIC", "order_service_dlq_topic"))
+	config.KafkaConsumerConcurrency = cast.ToInt(coalesce("KAFKA_CONSUMER_CONCURRENCY", 1))
+	config.KafkaBasketReplyTopic = cast.ToString(coalesce("KAFKA_BASKET_REPLY_TOPIC", "basket_reply_topic"))
+
+	config.MetricsAddress = cast.ToString(coalesce("METRICS_ADDRESS", ""))
+
 	config.LOG_PATH = cast.ToString(coalesce("LOG_PATH", "logs/info.log"))
 
 	return config
@@ -0,0 +1,139 @@
+// Package currency converts prices between the currencies baskets and orders are kept in.
+package currency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/flash_sale/flash_sale_order_service/money"
+)
+
+// ErrRateNotFound is returned when no rate is known between two currencies.
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+// ExchangeRateProvider gives the rate at which an amount in one currency is converted
+// into another.
+type ExchangeRateProvider interface {
+	Rate(ctx context.Context, from, to string) (money.Rate, error)
+}
+
+// otherExponents lists the ISO 4217 currencies whose minor unit is not a hundredth of
+// the major one. A money.Amount always counts hundredths, so these cannot be priced in.
+var otherExponents = map[string]bool{
+	// No minor unit
+	"BIF": true, "CLP": true, "DJF": true, "GNF": true, "ISK": true, "JPY": true,
+	"KMF": true, "KRW": true, "PYG": true, "RWF": true, "UGX": true, "UYI": true,
+	"VND": true, "VUV": true, "XAF": true, "XOF": true, "XPF": true,
+	// Three decimal places
+	"BHD": true, "IQD": true, "JOD": true, "KWD": true, "LYD": true, "OMR": true,
+	"TND": true,
+	// Four decimal places
+	"CLF": true, "UYW": true,
+}
+
+// IsValidCode reports whether code looks like an ISO 4217 code, three upper case letters,
+// of a currency with two decimal places.
+func IsValidCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return !otherExponents[code]
+}
+
+// StaticProvider is an ExchangeRateProvider with a fixed set of rates, each relative to
+// one base currency.
+type StaticProvider struct {
+	base  string
+	rates map[string]money.Rate
+}
+
+// NewStaticProvider returns a provider that converts one unit of base into rates[code]
+// units of each listed currency. Rates between two listed currencies go through base.
+func NewStaticProvider(base string, rates map[string]money.Rate) *StaticProvider {
+	return &StaticProvider{
+		base:  base,
+		rates: rates,
+	}
+}
+
+// staticRatesFile is the layout of a rates file, e.g.
+//
+//	{"base": "USD", "rates": {"EUR": "0.92", "GBP": "0.79"}}
+type staticRatesFile struct {
+	Base  string            `json:"base"`
+	Rates map[string]string `json:"rates"`
+}
+
+// LoadStaticProvider reads a StaticProvider from a JSON rates file.
+func LoadStaticProvider(path string) (*StaticProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exchange rates file: %w", err)
+	}
+
+	var file staticRatesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse exchange rates file: %w", err)
+	}
+	if !IsValidCode(file.Base) {
+		return nil, fmt.Errorf("exchange rates file has an invalid base currency %q", file.Base)
+	}
+
+	rates := make(map[string]money.Rate, len(file.Rates))
+	for code, value := range file.Rates {
+		if !IsValidCode(code) {
+			return nil, fmt.Errorf("exchange rates file has an invalid currency %q", code)
+		}
+		rate, err := money.ParseRate(value)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("exchange rates file has an invalid rate %q for %s", value, code)
+		}
+		rates[code] = rate
+	}
+
+	return NewStaticProvider(file.Base, rates), nil
+}
+
+// Rate returns the rate from one currency to another.
+func (p *StaticProvider) Rate(ctx context.Context, from, to string) (money.Rate, error) {
+	if from == to {
+		return money.OneToOne, nil
+	}
+
+	fromRate, ok := p.rateFromBase(from)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s to %s", ErrRateNotFound, from, to)
+	}
+	toRate, ok := p.rateFromBase(to)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s to %s", ErrRateNotFound, from, to)
+	}
+	if from == p.base {
+		return toRate, nil
+	}
+
+	// Cross rate; FloatString rounds half away from zero to the precision of a money.Rate
+	cross := big.NewRat(int64(toRate), int64(fromRate))
+	rate, err := money.ParseRate(cross.FloatString(8))
+	if err != nil {
+		return 0, err
+	}
+	return rate, nil
+}
+
+func (p *StaticProvider) rateFromBase(code string) (money.Rate, bool) {
+	if code == p.base {
+		return money.OneToOne, true
+	}
+	rate, ok := p.rates[code]
+	return rate, ok
+}
@@ -0,0 +1,86 @@
+package currency
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/flash_sale/flash_sale_order_service/money"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticProviderRate(t *testing.T) {
+	p := NewStaticProvider("USD", map[string]money.Rate{
+		"EUR": 92000000, // 0.92
+		"GBP": 79000000, // 0.79
+		"UZS": 1.27e12,  // 12700
+	})
+
+	tests := []struct {
+		from, to string
+		want     money.Rate
+		wantErr  error
+	}{
+		{"USD", "USD", money.OneToOne, nil},
+		{"EUR", "EUR", money.OneToOne, nil},
+		{"USD", "EUR", 92000000, nil},
+		{"EUR", "USD", 108695652, nil}, // 1 / 0.92 = 1.086956521...
+		{"EUR", "GBP", 85869565, nil},  // 0.79 / 0.92 = 0.858695652...
+		{"USD", "UZS", 1.27e12, nil},
+		{"USD", "JPY", 0, ErrRateNotFound},
+		{"JPY", "USD", 0, ErrRateNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+"->"+tt.to, func(t *testing.T) {
+			got, err := p.Rate(context.Background(), tt.from, tt.to)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLoadStaticProvider(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "rates.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"base": "USD", "rates": {"EUR": "0.92"}}`), 0o600))
+
+	p, err := LoadStaticProvider(path)
+	assert.NoError(t, err)
+	rate, err := p.Rate(context.Background(), "USD", "EUR")
+	assert.NoError(t, err)
+	assert.Equal(t, money.Rate(92000000), rate)
+
+	for name, content := range map[string]string{
+		"bad-base.json": `{"base": "usd", "rates": {}}`,
+		"bad-code.json": `{"base": "USD", "rates": {"EURO": "0.92"}}`,
+		"bad-rate.json": `{"base": "USD", "rates": {"EUR": "-1"}}`,
+		"bad-json.json": `{"base": `,
+	} {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		_, err := LoadStaticProvider(path)
+		assert.Error(t, err, name)
+	}
+
+	_, err = LoadStaticProvider(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestIsValidCode(t *testing.T) {
+	assert.True(t, IsValidCode("USD"))
+	assert.False(t, IsValidCode("usd"))
+	assert.False(t, IsValidCode("US"))
+	assert.False(t, IsValidCode(""))
+
+	// Amounts are kept in hundredths
+	assert.True(t, IsValidCode("EUR"))
+	assert.False(t, IsValidCode("JPY"))
+	assert.False(t, IsValidCode("KWD"))
+}
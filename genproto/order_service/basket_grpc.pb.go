@@ -25,6 +25,8 @@ const (
 	BasketService_DeleteBasket_FullMethodName       = "/order_service.BasketService/DeleteBasket"
 	BasketService_ListBaskets_FullMethodName        = "/order_service.BasketService/ListBaskets"
 	BasketService_UpdateBasketStatus_FullMethodName = "/order_service.BasketService/UpdateBasketStatus"
+	BasketService_GetBasketSummary_FullMethodName   = "/order_service.BasketService/GetBasketSummary"
+	BasketService_QuoteBasket_FullMethodName        = "/order_service.BasketService/QuoteBasket"
 )
 
 // BasketServiceClient is the client API for BasketService service.
@@ -39,6 +41,8 @@ type BasketServiceClient interface {
 	DeleteBasket(ctx context.Context, in *DeleteBasketRequest, opts ...grpc.CallOption) (*DeleteBasketResponse, error)
 	ListBaskets(ctx context.Context, in *ListBasketsRequest, opts ...grpc.CallOption) (*ListBasketsResponse, error)
 	UpdateBasketStatus(ctx context.Context, in *UpdateBasketStatusRequest, opts ...grpc.CallOption) (*UpdateBasketStatusResponse, error)
+	GetBasketSummary(ctx context.Context, in *GetBasketSummaryRequest, opts ...grpc.CallOption) (*GetBasketSummaryResponse, error)
+	QuoteBasket(ctx context.Context, in *QuoteBasketRequest, opts ...grpc.CallOption) (*QuoteBasketResponse, error)
 }
 
 type basketServiceClient struct {
@@ -109,6 +113,26 @@ func (c *basketServiceClient) UpdateBasketStatus(ctx context.Context, in *Update
 	return out, nil
 }
 
+func (c *basketServiceClient) GetBasketSummary(ctx context.Context, in *GetBasketSummaryRequest, opts ...grpc.CallOption) (*GetBasketSummaryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetBasketSummaryResponse)
+	err := c.cc.Invoke(ctx, BasketService_GetBasketSummary_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *basketServiceClient) QuoteBasket(ctx context.Context, in *QuoteBasketRequest, opts ...grpc.CallOption) (*QuoteBasketResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QuoteBasketResponse)
+	err := c.cc.Invoke(ctx, BasketService_QuoteBasket_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // BasketServiceServer is the server API for BasketService service.
 // All implementations must embed UnimplementedBasketServiceServer
 // for forward compatibility.
@@ -121,6 +145,8 @@ type BasketServiceServer interface {
 	DeleteBasket(context.Context, *DeleteBasketRequest) (*DeleteBasketResponse, error)
 	ListBaskets(context.Context, *ListBasketsRequest) (*ListBasketsResponse, error)
 	UpdateBasketStatus(context.Context, *UpdateBasketStatusRequest) (*UpdateBasketStatusResponse, error)
+	GetBasketSummary(context.Context, *GetBasketSummaryRequest) (*GetBasketSummaryResponse, error)
+	QuoteBasket(context.Context, *QuoteBasketRequest) (*QuoteBasketResponse, error)
 	mustEmbedUnimplementedBasketServiceServer()
 }
 
@@ -149,6 +175,12 @@ func (UnimplementedBasketServiceServer) ListBaskets(context.Context, *ListBasket
 func (UnimplementedBasketServiceServer) UpdateBasketStatus(context.Context, *UpdateBasketStatusRequest) (*UpdateBasketStatusResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateBasketStatus not implemented")
 }
+func (UnimplementedBasketServiceServer) GetBasketSummary(context.Context, *GetBasketSummaryRequest) (*GetBasketSummaryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBasketSummary not implemented")
+}
+func (UnimplementedBasketServiceServer) QuoteBasket(context.Context, *QuoteBasketRequest) (*QuoteBasketResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QuoteBasket not implemented")
+}
 func (UnimplementedBasketServiceServer) mustEmbedUnimplementedBasketServiceServer() {}
 func (UnimplementedBasketServiceServer) testEmbeddedByValue()                       {}
 
@@ -278,6 +310,42 @@ func _BasketService_UpdateBasketStatus_Handler(srv interface{}, ctx context.Cont
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BasketService_GetBasketSummary_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBasketSummaryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BasketServiceServer).GetBasketSummary(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BasketService_GetBasketSummary_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BasketServiceServer).GetBasketSummary(ctx, req.(*GetBasketSummaryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BasketService_QuoteBasket_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QuoteBasketRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BasketServiceServer).QuoteBasket(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BasketService_QuoteBasket_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BasketServiceServer).QuoteBasket(ctx, req.(*QuoteBasketRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // BasketService_ServiceDesc is the grpc.ServiceDesc for BasketService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -309,6 +377,14 @@ var BasketService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UpdateBasketStatus",
 			Handler:    _BasketService_UpdateBasketStatus_Handler,
 		},
+		{
+			MethodName: "GetBasketSummary",
+			Handler:    _BasketService_GetBasketSummary_Handler,
+		},
+		{
+			MethodName: "QuoteBasket",
+			Handler:    _BasketService_QuoteBasket_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "submodule/order_service/basket.proto",
@@ -19,10 +19,13 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	BasketItemService_CreateBasketItem_FullMethodName = "/order_service.BasketItemService/CreateBasketItem"
-	BasketItemService_GetBasketItem_FullMethodName    = "/order_service.BasketItemService/GetBasketItem"
-	BasketItemService_DeleteBasketItem_FullMethodName = "/order_service.BasketItemService/DeleteBasketItem"
-	BasketItemService_ListBasketItems_FullMethodName  = "/order_service.BasketItemService/ListBasketItems"
+	BasketItemService_CreateBasketItem_FullMethodName         = "/order_service.BasketItemService/CreateBasketItem"
+	BasketItemService_GetBasketItem_FullMethodName            = "/order_service.BasketItemService/GetBasketItem"
+	BasketItemService_DeleteBasketItem_FullMethodName         = "/order_service.BasketItemService/DeleteBasketItem"
+	BasketItemService_UpdateBasketItemQuantity_FullMethodName = "/order_service.BasketItemService/UpdateBasketItemQuantity"
+	BasketItemService_ClearBasketItems_FullMethodName         = "/order_service.BasketItemService/ClearBasketItems"
+	BasketItemService_ListBasketItems_FullMethodName          = "/order_service.BasketItemService/ListBasketItems"
+	BasketItemService_GetFlashSaleStock_FullMethodName        = "/order_service.BasketItemService/GetFlashSaleStock"
 )
 
 // BasketItemServiceClient is the client API for BasketItemService service.
@@ -34,7 +37,10 @@ type BasketItemServiceClient interface {
 	CreateBasketItem(ctx context.Context, in *CreateBasketItemRequest, opts ...grpc.CallOption) (*CreateBasketItemResponse, error)
 	GetBasketItem(ctx context.Context, in *GetBasketItemRequest, opts ...grpc.CallOption) (*GetBasketItemResponse, error)
 	DeleteBasketItem(ctx context.Context, in *DeleteBasketItemRequest, opts ...grpc.CallOption) (*DeleteBasketItemResponse, error)
+	UpdateBasketItemQuantity(ctx context.Context, in *UpdateBasketItemQuantityRequest, opts ...grpc.CallOption) (*UpdateBasketItemQuantityResponse, error)
+	ClearBasketItems(ctx context.Context, in *ClearBasketItemsRequest, opts ...grpc.CallOption) (*ClearBasketItemsResponse, error)
 	ListBasketItems(ctx context.Context, in *ListBasketItemsRequest, opts ...grpc.CallOption) (*ListBasketItemsResponse, error)
+	GetFlashSaleStock(ctx context.Context, in *GetFlashSaleStockRequest, opts ...grpc.CallOption) (*GetFlashSaleStockResponse, error)
 }
 
 type basketItemServiceClient struct {
@@ -75,6 +81,26 @@ func (c *basketItemServiceClient) DeleteBasketItem(ctx context.Context, in *Dele
 	return out, nil
 }
 
+func (c *basketItemServiceClient) UpdateBasketItemQuantity(ctx context.Context, in *UpdateBasketItemQuantityRequest, opts ...grpc.CallOption) (*UpdateBasketItemQuantityResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateBasketItemQuantityResponse)
+	err := c.cc.Invoke(ctx, BasketItemService_UpdateBasketItemQuantity_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *basketItemServiceClient) ClearBasketItems(ctx context.Context, in *ClearBasketItemsRequest, opts ...grpc.CallOption) (*ClearBasketItemsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ClearBasketItemsResponse)
+	err := c.cc.Invoke(ctx, BasketItemService_ClearBasketItems_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *basketItemServiceClient) ListBasketItems(ctx context.Context, in *ListBasketItemsRequest, opts ...grpc.CallOption) (*ListBasketItemsResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(ListBasketItemsResponse)
@@ -85,6 +111,16 @@ func (c *basketItemServiceClient) ListBasketItems(ctx context.Context, in *ListB
 	return out, nil
 }
 
+func (c *basketItemServiceClient) GetFlashSaleStock(ctx context.Context, in *GetFlashSaleStockRequest, opts ...grpc.CallOption) (*GetFlashSaleStockResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetFlashSaleStockResponse)
+	err := c.cc.Invoke(ctx, BasketItemService_GetFlashSaleStock_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // BasketItemServiceServer is the server API for BasketItemService service.
 // All implementations must embed UnimplementedBasketItemServiceServer
 // for forward compatibility.
@@ -94,7 +130,10 @@ type BasketItemServiceServer interface {
 	CreateBasketItem(context.Context, *CreateBasketItemRequest) (*CreateBasketItemResponse, error)
 	GetBasketItem(context.Context, *GetBasketItemRequest) (*GetBasketItemResponse, error)
 	DeleteBasketItem(context.Context, *DeleteBasketItemRequest) (*DeleteBasketItemResponse, error)
+	UpdateBasketItemQuantity(context.Context, *UpdateBasketItemQuantityRequest) (*UpdateBasketItemQuantityResponse, error)
+	ClearBasketItems(context.Context, *ClearBasketItemsRequest) (*ClearBasketItemsResponse, error)
 	ListBasketItems(context.Context, *ListBasketItemsRequest) (*ListBasketItemsResponse, error)
+	GetFlashSaleStock(context.Context, *GetFlashSaleStockRequest) (*GetFlashSaleStockResponse, error)
 	mustEmbedUnimplementedBasketItemServiceServer()
 }
 
@@ -114,9 +153,18 @@ func (UnimplementedBasketItemServiceServer) GetBasketItem(context.Context, *GetB
 func (UnimplementedBasketItemServiceServer) DeleteBasketItem(context.Context, *DeleteBasketItemRequest) (*DeleteBasketItemResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteBasketItem not implemented")
 }
+func (UnimplementedBasketItemServiceServer) UpdateBasketItemQuantity(context.Context, *UpdateBasketItemQuantityRequest) (*UpdateBasketItemQuantityResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateBasketItemQuantity not implemented")
+}
+func (UnimplementedBasketItemServiceServer) ClearBasketItems(context.Context, *ClearBasketItemsRequest) (*ClearBasketItemsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ClearBasketItems not implemented")
+}
 func (UnimplementedBasketItemServiceServer) ListBasketItems(context.Context, *ListBasketItemsRequest) (*ListBasketItemsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListBasketItems not implemented")
 }
+func (UnimplementedBasketItemServiceServer) GetFlashSaleStock(context.Context, *GetFlashSaleStockRequest) (*GetFlashSaleStockResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetFlashSaleStock not implemented")
+}
 func (UnimplementedBasketItemServiceServer) mustEmbedUnimplementedBasketItemServiceServer() {}
 func (UnimplementedBasketItemServiceServer) testEmbeddedByValue()                           {}
 
@@ -192,6 +240,42 @@ func _BasketItemService_DeleteBasketItem_Handler(srv interface{}, ctx context.Co
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BasketItemService_UpdateBasketItemQuantity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateBasketItemQuantityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BasketItemServiceServer).UpdateBasketItemQuantity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BasketItemService_UpdateBasketItemQuantity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BasketItemServiceServer).UpdateBasketItemQuantity(ctx, req.(*UpdateBasketItemQuantityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BasketItemService_ClearBasketItems_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClearBasketItemsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BasketItemServiceServer).ClearBasketItems(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BasketItemService_ClearBasketItems_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BasketItemServiceServer).ClearBasketItems(ctx, req.(*ClearBasketItemsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _BasketItemService_ListBasketItems_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ListBasketItemsRequest)
 	if err := dec(in); err != nil {
@@ -210,6 +294,24 @@ func _BasketItemService_ListBasketItems_Handler(srv interface{}, ctx context.Con
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BasketItemService_GetFlashSaleStock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetFlashSaleStockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BasketItemServiceServer).GetFlashSaleStock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BasketItemService_GetFlashSaleStock_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BasketItemServiceServer).GetFlashSaleStock(ctx, req.(*GetFlashSaleStockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // BasketItemService_ServiceDesc is the grpc.ServiceDesc for BasketItemService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -229,10 +331,22 @@ var BasketItemService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteBasketItem",
 			Handler:    _BasketItemService_DeleteBasketItem_Handler,
 		},
+		{
+			MethodName: "UpdateBasketItemQuantity",
+			Handler:    _BasketItemService_UpdateBasketItemQuantity_Handler,
+		},
+		{
+			MethodName: "ClearBasketItems",
+			Handler:    _BasketItemService_ClearBasketItems_Handler,
+		},
 		{
 			MethodName: "ListBasketItems",
 			Handler:    _BasketItemService_ListBasketItems_Handler,
 		},
+		{
+			MethodName: "GetFlashSaleStock",
+			Handler:    _BasketItemService_GetFlashSaleStock_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "submodule/order_service/basket_items.proto",
@@ -23,6 +23,7 @@ const (
 	OrderItemService_ListOrderItems_FullMethodName            = "/order_service.OrderItemService/ListOrderItems"
 	OrderItemService_ConvertBasketToOrderItems_FullMethodName = "/order_service.OrderItemService/ConvertBasketToOrderItems"
 	OrderItemService_DeleteOrderItem_FullMethodName           = "/order_service.OrderItemService/DeleteOrderItem"
+	OrderItemService_CancelOrderItem_FullMethodName           = "/order_service.OrderItemService/CancelOrderItem"
 )
 
 // OrderItemServiceClient is the client API for OrderItemService service.
@@ -35,6 +36,7 @@ type OrderItemServiceClient interface {
 	ListOrderItems(ctx context.Context, in *ListOrderItemsRequest, opts ...grpc.CallOption) (*ListOrderItemsResponse, error)
 	ConvertBasketToOrderItems(ctx context.Context, in *ConvertBasketToOrderItemsRequest, opts ...grpc.CallOption) (*ConvertBasketToOrderItemsResponse, error)
 	DeleteOrderItem(ctx context.Context, in *DeleteOrderItemRequest, opts ...grpc.CallOption) (*DeleteOrderItemResponse, error)
+	CancelOrderItem(ctx context.Context, in *CancelOrderItemRequest, opts ...grpc.CallOption) (*CancelOrderItemResponse, error)
 }
 
 type orderItemServiceClient struct {
@@ -85,6 +87,16 @@ func (c *orderItemServiceClient) DeleteOrderItem(ctx context.Context, in *Delete
 	return out, nil
 }
 
+func (c *orderItemServiceClient) CancelOrderItem(ctx context.Context, in *CancelOrderItemRequest, opts ...grpc.CallOption) (*CancelOrderItemResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelOrderItemResponse)
+	err := c.cc.Invoke(ctx, OrderItemService_CancelOrderItem_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // OrderItemServiceServer is the server API for OrderItemService service.
 // All implementations must embed UnimplementedOrderItemServiceServer
 // for forward compatibility.
@@ -95,6 +107,7 @@ type OrderItemServiceServer interface {
 	ListOrderItems(context.Context, *ListOrderItemsRequest) (*ListOrderItemsResponse, error)
 	ConvertBasketToOrderItems(context.Context, *ConvertBasketToOrderItemsRequest) (*ConvertBasketToOrderItemsResponse, error)
 	DeleteOrderItem(context.Context, *DeleteOrderItemRequest) (*DeleteOrderItemResponse, error)
+	CancelOrderItem(context.Context, *CancelOrderItemRequest) (*CancelOrderItemResponse, error)
 	mustEmbedUnimplementedOrderItemServiceServer()
 }
 
@@ -117,6 +130,9 @@ func (UnimplementedOrderItemServiceServer) ConvertBasketToOrderItems(context.Con
 func (UnimplementedOrderItemServiceServer) DeleteOrderItem(context.Context, *DeleteOrderItemRequest) (*DeleteOrderItemResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteOrderItem not implemented")
 }
+func (UnimplementedOrderItemServiceServer) CancelOrderItem(context.Context, *CancelOrderItemRequest) (*CancelOrderItemResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelOrderItem not implemented")
+}
 func (UnimplementedOrderItemServiceServer) mustEmbedUnimplementedOrderItemServiceServer() {}
 func (UnimplementedOrderItemServiceServer) testEmbeddedByValue()                          {}
 
@@ -210,6 +226,24 @@ func _OrderItemService_DeleteOrderItem_Handler(srv interface{}, ctx context.Cont
 	return interceptor(ctx, in, info, handler)
 }
 
+func _OrderItemService_CancelOrderItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelOrderItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrderItemServiceServer).CancelOrderItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: OrderItemService_CancelOrderItem_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrderItemServiceServer).CancelOrderItem(ctx, req.(*CancelOrderItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // OrderItemService_ServiceDesc is the grpc.ServiceDesc for OrderItemService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -233,6 +267,10 @@ var OrderItemService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteOrderItem",
 			Handler:    _OrderItemService_DeleteOrderItem_Handler,
 		},
+		{
+			MethodName: "CancelOrderItem",
+			Handler:    _OrderItemService_CancelOrderItem_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "submodule/order_service/order_items.proto",
@@ -4,58 +4,111 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 
 	"github.com/flash_sale/flash_sale_order_service/genproto/order_service"
+	"github.com/flash_sale/flash_sale_order_service/outbox"
 	"github.com/flash_sale/flash_sale_order_service/storage"
-	"github.com/segmentio/kafka-go"
 )
 
-// BasketItemConsumer consumes Kafka messages related to basket items.
-type BasketItemConsumer struct {
-	reader  *kafka.Reader
-	storage storage.StorageI
-}
+// basketItemGroupID is the consumer group of the basket item consumer.
+const basketItemGroupID = "basket-item-group"
 
-// NewBasketItemConsumer creates a new BasketItemConsumer instance.
-func NewBasketItemConsumer(kafkaBrokers []string, topic string, storage storage.StorageI) *BasketItemConsumer {
-	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers: kafkaBrokers,
-		Topic:   topic,
-		GroupID: "basket-item-group", // Choose a suitable group ID
-	})
-	return &BasketItemConsumer{reader: reader, storage: storage}
+// basketItemSchema is the schema of messages carrying a basket item. Legacy producers sent its
+// prices as plain numbers, from before they were Money; they are ignored on create, so they are dropped.
+var basketItemSchema = Schema{
+	Version: outbox.SchemaVersion,
+	Migrations: map[int]Migration{
+		LegacySchemaVersion: dropNumericPrices,
+	},
 }
 
-// Consume starts consuming messages from the Kafka topic.
-func (c *BasketItemConsumer) Consume(ctx context.Context) error {
-	for {
-		msg, err := c.reader.FetchMessage(ctx)
-		if err != nil {
-			return fmt.Errorf("error fetching message: %w", err)
+func dropNumericPrices(value map[string]any) error {
+	item, ok := value["basket_item"].(map[string]any)
+	if !ok {
+		return nil
+	}
+	for _, field := range []string{"unit_price", "total_price"} {
+		if _, isNumber := item[field].(json.Number); isNumber {
+			delete(item, field)
 		}
+	}
+	return nil
+}
+
+// BasketItemHandlers returns the handlers of the commands for managing baskets and their items.
+// Each command is replied to through replier once it is done with.
+func BasketItemHandlers(storage storage.StorageI, replier *Replier) *Registry {
+	return NewRegistry().
+		Register("basket.create", Command(replier, CurrentSchema, func(ctx context.Context, createModel *order_service.CreateBasketRequest, reply *order_service.BasketCommandReply) error {
+			if createModel.Basket == nil || createModel.Basket.UserId == "" {
+				return fmt.Errorf("%w: basket.user_id is required", errInvalidCommand)
+			}
+			if createModel.Basket.Status == "" {
+				createModel.Basket.Status = "OPEN"
+			}
+
+			basket, err := storage.Basket().CreateBasket(ctx, createModel)
+			if err != nil {
+				return fmt.Errorf("error creating basket: %w", err)
+			}
+			reply.Basket = basket
+			return nil
+		})).
+		Register("basket.clear", Command(replier, CurrentSchema, func(ctx context.Context, clearModel *order_service.ClearBasketItemsRequest, reply *order_service.BasketCommandReply) error {
+			if clearModel.BasketId == "" {
+				return fmt.Errorf("%w: basket_id is required", errInvalidCommand)
+			}
 
-		switch string(msg.Key) {
-		case "basket_item.create":
-			var createModel order_service.CreateBasketItemRequest
-			if err := json.Unmarshal(msg.Value, &createModel); err != nil {
-				log.Printf("error unmarshalling create basket item message: %v", err)
-				continue
+			if _, err := storage.BasketItem().ClearBasketItems(ctx, clearModel); err != nil {
+				return fmt.Errorf("error clearing basket: %w", err)
+			}
+			return nil
+		})).
+		Register("basket_item.create", Command(replier, basketItemSchema, func(ctx context.Context, createModel *order_service.CreateBasketItemRequest, reply *order_service.BasketCommandReply) error {
+			item := createModel.BasketItem
+			if item == nil || item.BasketId == "" || item.ProductId == "" {
+				return fmt.Errorf("%w: basket_item.basket_id and basket_item.product_id are required", errInvalidCommand)
 			}
 
 			// Create the basket item in the database
-			if _, err := c.storage.BasketItem().CreateBasketItem(ctx, &createModel); err != nil {
-				log.Printf("error creating basket item: %v", err)
-				continue
+			basketItem, err := storage.BasketItem().CreateBasketItem(ctx, createModel)
+			if err != nil {
+				return fmt.Errorf("error creating basket item: %w", err)
+			}
+			reply.BasketItem = basketItem
+			return nil
+		})).
+		Register("basket_item.update_quantity", Command(replier, CurrentSchema, func(ctx context.Context, updateModel *order_service.UpdateBasketItemQuantityRequest, reply *order_service.BasketCommandReply) error {
+			if updateModel.Id == "" {
+				return fmt.Errorf("%w: id is required", errInvalidCommand)
 			}
 
-		default:
-			log.Printf("unknown message key: %s", msg.Key)
-		}
+			basketItem, err := storage.BasketItem().UpdateBasketItemQuantity(ctx, updateModel)
+			if err != nil {
+				return fmt.Errorf("error updating basket item quantity: %w", err)
+			}
+			reply.BasketItem = basketItem
+			return nil
+		})).
+		Register("basket_item.delete", Command(replier, CurrentSchema, func(ctx context.Context, deleteModel *order_service.DeleteBasketItemRequest, reply *order_service.BasketCommandReply) error {
+			if deleteModel.Id == "" {
+				return fmt.Errorf("%w: id is required", errInvalidCommand)
+			}
 
-		// Commit the message
-		if err := c.reader.CommitMessages(ctx, msg); err != nil {
-			return fmt.Errorf("error committing message: %w", err)
-		}
-	}
+			if _, err := storage.BasketItem().DeleteBasketItem(ctx, deleteModel); err != nil {
+				return fmt.Errorf("error deleting basket item: %w", err)
+			}
+			return nil
+		}))
+}
+
+// NewBasketItemConsumer creates a Runner for the commands for managing baskets and their items.
+// Messages that cannot be handled are retried and then dead-lettered by retrier.
+func NewBasketItemConsumer(kafkaBrokers []string, topic string, storage storage.StorageI, retrier *Retrier, replier *Replier, concurrency int) *Runner {
+	return NewRunner(RunnerConfig{
+		Brokers:     kafkaBrokers,
+		Topic:       topic,
+		GroupID:     basketItemGroupID,
+		Concurrency: concurrency,
+	}, BasketItemHandlers(storage, replier).Handle, retrier, DefaultMiddleware(basketItemGroupID)...)
 }
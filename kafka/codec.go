@@ -0,0 +1,128 @@
+package consumer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strconv"
+
+	"github.com/flash_sale/flash_sale_order_service/outbox"
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// LegacySchemaVersion is the schema version of messages sent without a content-type header,
+// whose values are decoded with encoding/json the way they were before messages were versioned.
+const LegacySchemaVersion = 0
+
+// Migration rewrites a JSON message value of one schema version into the next one.
+type Migration func(value map[string]any) error
+
+// Schema is the schema version a kind of message is expected in, and how values of older
+// versions are upgraded to it.
+type Schema struct {
+	Version int
+	// Migrations upgrade a JSON value from the version they are keyed by to the next one.
+	// Versions without a migration read the same as the next one.
+	Migrations map[int]Migration
+}
+
+// CurrentSchema is the Schema of messages that have not changed since they were versioned.
+var CurrentSchema = Schema{Version: outbox.SchemaVersion}
+
+// protoMessage is satisfied by *T for a generated protobuf message type T.
+type protoMessage[T any] interface {
+	*T
+	proto.Message
+}
+
+// Proto returns a Handler that decodes the message value into a new T, as told by its headers
+// and schema, and passes it to fn. A value that does not decode is a permanent failure.
+func Proto[T any, PT protoMessage[T]](schema Schema, fn func(ctx context.Context, msg kafka.Message, value PT) error) Handler {
+	return func(ctx context.Context, msg kafka.Message) error {
+		value := PT(new(T))
+		if err := schema.Decode(msg, value); err != nil {
+			return permanent(fmt.Errorf("error decoding %s message: %w", msg.Key, err))
+		}
+		return fn(ctx, msg, value)
+	}
+}
+
+// Decode decodes the value of msg into value. Binary protobuf and protojson values are told
+// apart by the content-type header, and legacy values have none. JSON values of an older schema
+// version are migrated first; newer versions, and older binary ones, are refused.
+func (s Schema) Decode(msg kafka.Message, value proto.Message) error {
+	contentType := messageHeader(msg, outbox.HeaderContentType)
+	if contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return fmt.Errorf("invalid content type %q: %w", contentType, err)
+		}
+		contentType = mediaType
+	}
+
+	version := s.Version
+	if contentType == "" {
+		version = LegacySchemaVersion
+	}
+	if header := messageHeader(msg, outbox.HeaderSchemaVersion); header != "" {
+		parsed, err := strconv.Atoi(header)
+		if err != nil {
+			return fmt.Errorf("invalid schema version %q", header)
+		}
+		version = parsed
+	}
+	if version > s.Version {
+		return fmt.Errorf("schema version %d is newer than %d", version, s.Version)
+	}
+
+	switch contentType {
+	case outbox.ContentTypeProtobuf:
+		if version < s.Version {
+			return fmt.Errorf("binary values of schema version %d cannot be migrated to %d", version, s.Version)
+		}
+		return proto.Unmarshal(msg.Value, value)
+	case outbox.ContentTypeProtoJSON:
+		data, err := s.migrate(msg.Value, version)
+		if err != nil {
+			return err
+		}
+		return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, value)
+	case "":
+		data, err := s.migrate(msg.Value, version)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(data, value)
+	}
+
+	return fmt.Errorf("unsupported content type %q", contentType)
+}
+
+// migrate upgrades a JSON value from version to the version of the schema.
+func (s Schema) migrate(data []byte, version int) ([]byte, error) {
+	if version == s.Version {
+		return data, nil
+	}
+
+	var value map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber() // Kept as they were sent, so large integers do not lose precision
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	for ; version < s.Version; version++ {
+		migration, ok := s.Migrations[version]
+		if !ok {
+			continue
+		}
+		if err := migration(value); err != nil {
+			return nil, fmt.Errorf("failed to migrate from schema version %d: %w", version, err)
+		}
+	}
+
+	return json.Marshal(value)
+}
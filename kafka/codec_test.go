@@ -0,0 +1,99 @@
+package consumer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flash_sale/flash_sale_order_service/genproto/order_service"
+	"github.com/flash_sale/flash_sale_order_service/outbox"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var createdAt = time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC)
+
+// encodedMessage returns a message carrying value encoded as contentType, at the given schema
+// version; an empty version leaves the header out.
+func encodedMessage(t *testing.T, value proto.Message, contentType, version string) kafka.Message {
+	var (
+		data []byte
+		err  error
+	)
+	if contentType == outbox.ContentTypeProtobuf {
+		data, err = proto.Marshal(value)
+	} else {
+		data, err = protojson.Marshal(value)
+	}
+	assert.NoError(t, err)
+
+	msg := kafka.Message{
+		Key:     []byte("basket_item.create"),
+		Value:   data,
+		Headers: []kafka.Header{{Key: outbox.HeaderContentType, Value: []byte(contentType)}},
+	}
+	if version != "" {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: outbox.HeaderSchemaVersion, Value: []byte(version)})
+	}
+	return msg
+}
+
+func TestSchemaDecode(t *testing.T) {
+	sent := &order_service.CreateBasketItemRequest{
+		BasketItem: &order_service.BasketItem{
+			BasketId:  "basket-1",
+			ProductId: "product-1",
+			Quantity:  2,
+			CreatedAt: timestamppb.New(createdAt),
+		},
+	}
+
+	for _, msg := range []kafka.Message{
+		encodedMessage(t, sent, outbox.ContentTypeProtobuf, "1"),
+		encodedMessage(t, sent, outbox.ContentTypeProtoJSON, "1"),
+		encodedMessage(t, sent, "application/json; charset=utf-8", ""),
+	} {
+		received := &order_service.CreateBasketItemRequest{}
+		assert.NoError(t, basketItemSchema.Decode(msg, received))
+		assert.True(t, proto.Equal(sent, received), "%s", msg.Headers)
+		assert.Equal(t, createdAt, received.BasketItem.CreatedAt.AsTime())
+	}
+}
+
+func TestSchemaDecodeLegacy(t *testing.T) {
+	// Sent by a producer from before messages were versioned, with its prices as plain numbers
+	msg := kafka.Message{
+		Key:   []byte("basket_item.create"),
+		Value: []byte(`{"basket_item": {"basket_id": "basket-1", "product_id": "product-1", "quantity": 2, "unit_price": 18.5, "total_price": 37}}`),
+	}
+
+	received := &order_service.CreateBasketItemRequest{}
+	assert.NoError(t, basketItemSchema.Decode(msg, received))
+	assert.Equal(t, "basket-1", received.BasketItem.BasketId)
+	assert.Equal(t, int32(2), received.BasketItem.Quantity)
+	assert.Nil(t, received.BasketItem.UnitPrice)
+
+	// Without the migration it does not decode
+	assert.Error(t, CurrentSchema.Decode(msg, &order_service.CreateBasketItemRequest{}))
+}
+
+func TestSchemaDecodeRefuses(t *testing.T) {
+	sent := &order_service.DeleteBasketItemRequest{Id: "item-1"}
+
+	// A value that is not in the content type it is labelled with
+	mislabelled := encodedMessage(t, sent, outbox.ContentTypeProtoJSON, "1")
+	mislabelled.Headers[0].Value = []byte(outbox.ContentTypeProtobuf)
+
+	for name, msg := range map[string]kafka.Message{
+		"newer version":        encodedMessage(t, sent, outbox.ContentTypeProtoJSON, "2"),
+		"older binary version": encodedMessage(t, sent, outbox.ContentTypeProtobuf, "0"),
+		"invalid version":      encodedMessage(t, sent, outbox.ContentTypeProtoJSON, "one"),
+		"unknown content type": encodedMessage(t, sent, "application/avro", "1"),
+		"invalid content type": encodedMessage(t, sent, "application json", "1"),
+		"mislabelled":          mislabelled,
+	} {
+		assert.Error(t, CurrentSchema.Decode(msg, &order_service.DeleteBasketItemRequest{}), name)
+	}
+}
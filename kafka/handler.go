@@ -0,0 +1,126 @@
+package consumer
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sort"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Handler handles one message. Errors are retried unless marked permanent.
+type Handler func(ctx context.Context, msg kafka.Message) error
+
+// Middleware wraps a Handler with behaviour shared by every message type.
+type Middleware func(next Handler) Handler
+
+// Chain wraps handler in middleware, the first one outermost.
+func Chain(handler Handler, middleware ...Middleware) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler
+}
+
+// Registry routes messages to handlers by their key.
+type Registry struct {
+	handlers map[string]Handler
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		handlers: make(map[string]Handler),
+	}
+}
+
+// Register routes the messages with key to handler. It panics if key already has a handler.
+func (r *Registry) Register(key string, handler Handler) *Registry {
+	if _, ok := r.handlers[key]; ok {
+		panic(fmt.Sprintf("consumer: a handler for %q is already registered", key))
+	}
+	r.handlers[key] = handler
+	return r
+}
+
+// Keys returns the registered message keys, sorted.
+func (r *Registry) Keys() []string {
+	keys := make([]string, 0, len(r.handlers))
+	for key := range r.handlers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Handle passes a message to the handler of its key. Messages with an unknown key are logged
+// and skipped, since a topic may carry messages meant for other services.
+func (r *Registry) Handle(ctx context.Context, msg kafka.Message) error {
+	handler, ok := r.handlers[string(msg.Key)]
+	if !ok {
+		log.Printf("unknown message key: %s", msg.Key)
+		return nil
+	}
+	return handler(ctx, msg)
+}
+
+// Recover turns a panic in a handler into a permanent failure, so the message is dead-lettered
+// instead of taking the service down.
+func Recover() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg kafka.Message) (err error) {
+			defer func() {
+				if p := recover(); p != nil {
+					log.Printf("panic handling %s message at %d/%d: %v\n%s", msg.Key, msg.Partition, msg.Offset, p, debug.Stack())
+					err = permanent(fmt.Errorf("panic: %v", p))
+				}
+			}()
+			return next(ctx, msg)
+		}
+	}
+}
+
+// Logging logs every failed attempt at handling a message.
+func Logging(consumer string) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg kafka.Message) error {
+			start := time.Now()
+			err := next(ctx, msg)
+			if err != nil {
+				log.Printf("%s: failed to handle %s message at %d/%d after %v: %v", consumer, msg.Key, msg.Partition, msg.Offset, time.Since(start), err)
+			}
+			return err
+		}
+	}
+}
+
+// consumerMetrics holds the counters of the Metrics middleware, published by expvar as
+// "kafka_consumers" with keys of the form "<consumer>.<message key>.<counter>".
+var consumerMetrics = expvar.NewMap("kafka_consumers")
+
+// Metrics counts the messages handled and failed, and the time spent handling them.
+func Metrics(consumer string) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, msg kafka.Message) error {
+			prefix := consumer + "." + string(msg.Key) + "."
+			start := time.Now()
+			err := next(ctx, msg)
+			consumerMetrics.Add(prefix+"duration_ms", time.Since(start).Milliseconds())
+			if err != nil {
+				consumerMetrics.Add(prefix+"failed", 1)
+			} else {
+				consumerMetrics.Add(prefix+"handled", 1)
+			}
+			return err
+		}
+	}
+}
+
+// DefaultMiddleware is the middleware every consumer runs its handlers in.
+func DefaultMiddleware(consumer string) []Middleware {
+	return []Middleware{Logging(consumer), Metrics(consumer), Recover()}
+}
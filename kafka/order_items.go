@@ -2,60 +2,68 @@ package consumer
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"log"
 
 	"github.com/flash_sale/flash_sale_order_service/genproto/order_service"
 	"github.com/flash_sale/flash_sale_order_service/storage"
 	"github.com/segmentio/kafka-go"
 )
 
-// BasketToOrderConsumer consumes Kafka messages for converting basket items to order items.
-type BasketToOrderConsumer struct {
-	reader  *kafka.Reader
-	storage storage.StorageI
-}
-
-// NewBasketToOrderConsumer creates a new BasketToOrderConsumer instance.
-func NewBasketToOrderConsumer(kafkaBrokers []string, topic string, storage storage.StorageI) *BasketToOrderConsumer {
-	reader := kafka.NewReader(kafka.ReaderConfig{
-		Brokers: kafkaBrokers,
-		Topic:   topic,
-		GroupID: "basket-to-order-group", // Choose a suitable group ID
-	})
-	return &BasketToOrderConsumer{reader: reader, storage: storage}
-}
+// idempotencyKeyHeader is the message header producers may send an idempotency key in.
+const idempotencyKeyHeader = "idempotency-key"
 
-// Consume starts consuming messages from the Kafka topic.
-func (c *BasketToOrderConsumer) Consume(ctx context.Context) error {
-	for {
-		msg, err := c.reader.FetchMessage(ctx)
-		if err != nil {
-			return fmt.Errorf("error fetching message: %w", err)
-		}
+// basketToOrderGroupID is the consumer group of the basket to order consumer.
+const basketToOrderGroupID = "basket-to-order-group"
 
-		switch string(msg.Key) {
-		case "basket.convert_to_order":
-			var convertModel order_service.ConvertBasketToOrderItemsRequest
-			if err := json.Unmarshal(msg.Value, &convertModel); err != nil {
-				log.Printf("error unmarshalling convert basket to order message: %v", err)
-				continue
+// BasketToOrderHandlers returns the handlers of the messages for converting basket items to
+// order items. A conversion that is rejected, such as one with sold out items, is not retried.
+func BasketToOrderHandlers(storage storage.StorageI) *Registry {
+	return NewRegistry().
+		Register("basket.convert_to_order", Proto(CurrentSchema, func(ctx context.Context, msg kafka.Message, convertModel *order_service.ConvertBasketToOrderItemsRequest) error {
+			// A redelivered message, or one the producer sent twice with the same key, converts the basket once
+			if convertModel.IdempotencyKey == "" {
+				convertModel.IdempotencyKey = messageIdempotencyKey(msg)
 			}
 
 			// Convert basket items to order items
-			if _, err := c.storage.OrderItem().ConvertBasketToOrderItems(ctx, &convertModel); err != nil {
-				log.Printf("error converting basket to order: %v", err)
-				continue
+			if _, err := storage.OrderItem().ConvertBasketToOrderItems(ctx, convertModel); err != nil {
+				return permanentIfRejected(fmt.Errorf("error converting basket to order: %w", err))
 			}
+			return nil
+		}))
+}
 
-		default:
-			log.Printf("unknown message key: %s", msg.Key)
-		}
+// NewBasketToOrderConsumer creates a Runner for the messages for converting basket items to
+// order items. Messages that cannot be handled are retried and then dead-lettered by retrier.
+func NewBasketToOrderConsumer(kafkaBrokers []string, topic string, storage storage.StorageI, retrier *Retrier, concurrency int) *Runner {
+	return NewRunner(RunnerConfig{
+		Brokers:     kafkaBrokers,
+		Topic:       topic,
+		GroupID:     basketToOrderGroupID,
+		Concurrency: concurrency,
+	}, BasketToOrderHandlers(storage).Handle, retrier, DefaultMiddleware(basketToOrderGroupID)...)
+}
+
+// messageIdempotencyKey returns the idempotency key header of a message, or else its position.
+func messageIdempotencyKey(msg kafka.Message) string {
+	if key := messageHeader(msg, idempotencyKeyHeader); key != "" {
+		return key
+	}
+	return messagePosition(msg)
+}
+
+// messagePosition identifies a message by where it is in its topic, which is the same each time
+// it is redelivered.
+func messagePosition(msg kafka.Message) string {
+	return fmt.Sprintf("kafka:%s/%d/%d", msg.Topic, msg.Partition, msg.Offset)
+}
 
-		// Commit the message
-		if err := c.reader.CommitMessages(ctx, msg); err != nil {
-			return fmt.Errorf("error committing message: %w", err)
+// messageHeader returns the value of a message header, or "" if the message does not have it.
+func messageHeader(msg kafka.Message, key string) string {
+	for _, header := range msg.Headers {
+		if header.Key == key && len(header.Value) > 0 {
+			return string(header.Value)
 		}
 	}
+	return ""
 }
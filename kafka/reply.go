@@ -0,0 +1,141 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/flash_sale/flash_sale_order_service/genproto/order_service"
+	"github.com/flash_sale/flash_sale_order_service/outbox"
+	"github.com/flash_sale/flash_sale_order_service/service"
+	"github.com/jackc/pgx/v5"
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Headers of command messages and their replies.
+const (
+	// HeaderCorrelationID is copied from a command to its reply, so the producer can match them.
+	HeaderCorrelationID = "correlation-id"
+	// HeaderReplyTo names the topic a producer wants the reply to its command on, instead of
+	// the default reply topic.
+	HeaderReplyTo = "reply-to"
+)
+
+// errInvalidCommand is returned for a command message that does not decode or is missing a field.
+var errInvalidCommand = errors.New("invalid command")
+
+// Replier publishes the replies to commands sent over Kafka.
+type Replier struct {
+	publisher outbox.Publisher
+	topic     string
+}
+
+// NewReplier creates a new Replier instance. Replies go to topic unless a command names another
+// one in its reply-to header; with neither, no reply is sent.
+func NewReplier(publisher outbox.Publisher, topic string) *Replier {
+	return &Replier{
+		publisher: publisher,
+		topic:     topic,
+	}
+}
+
+// Command returns a Handler that decodes the message value into a new T, as told by its headers
+// and schema, passes it to fn to carry out, and replies with the outcome. fn fills in the result
+// it has to report in reply.
+//
+// Commands that succeed and commands that are rejected, such as one that does not decode or asks
+// for stock that is sold out, are replied to and done with. Any other error is returned to be
+// retried; a command that is dead-lettered gets no reply.
+func Command[T any, PT protoMessage[T]](replier *Replier, schema Schema, fn func(ctx context.Context, value PT, reply *order_service.BasketCommandReply) error) Handler {
+	return func(ctx context.Context, msg kafka.Message) error {
+		reply := &order_service.BasketCommandReply{
+			CorrelationId: correlationID(msg),
+			Command:       string(msg.Key),
+		}
+
+		value := PT(new(T))
+		err := schema.Decode(msg, value)
+		if err != nil {
+			err = fmt.Errorf("%w: %v", errInvalidCommand, err)
+		} else {
+			err = fn(ctx, value, reply)
+		}
+
+		if err != nil {
+			code := rejectionCode(err)
+			if code == codes.Unknown {
+				return err
+			}
+			reply.ErrorCode = code.String()
+			reply.Error = err.Error()
+		} else {
+			reply.Ok = true
+		}
+
+		// The command has been carried out, so a reply that cannot be sent is not worth doing it again
+		if err := replier.publish(ctx, msg, reply); err != nil {
+			log.Printf("failed to reply to %s message at %d/%d: %v", msg.Key, msg.Partition, msg.Offset, err)
+		}
+		return nil
+	}
+}
+
+// publish writes reply to the reply topic of msg.
+func (r *Replier) publish(ctx context.Context, msg kafka.Message, reply *order_service.BasketCommandReply) error {
+	topic := messageHeader(msg, HeaderReplyTo)
+	if topic == "" {
+		topic = r.topic
+	}
+	if topic == "" {
+		return nil
+	}
+
+	value, err := protojson.Marshal(reply)
+	if err != nil {
+		return fmt.Errorf("failed to marshal reply: %w", err)
+	}
+
+	return r.publisher.WriteMessages(ctx, kafka.Message{
+		Topic: topic,
+		Key:   []byte(reply.CorrelationId),
+		Value: value,
+		Headers: []kafka.Header{
+			{Key: HeaderCorrelationID, Value: []byte(reply.CorrelationId)},
+			{Key: outbox.HeaderContentType, Value: []byte(outbox.ContentTypeProtoJSON)},
+			{Key: outbox.HeaderSchemaVersion, Value: []byte(strconv.Itoa(outbox.SchemaVersion))},
+		},
+	})
+}
+
+// rejectionCode returns the gRPC code of an error that rejects a command, or codes.Unknown for
+// an error that may go away when the command is retried.
+func rejectionCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, errInvalidCommand):
+		return codes.InvalidArgument
+	case errors.Is(err, pgx.ErrNoRows):
+		return codes.NotFound
+	}
+	return service.ErrorCode(err)
+}
+
+// permanentIfRejected marks err as permanent when it rejects the message, so a message that
+// fails the same way every time is dead-lettered without being retried.
+func permanentIfRejected(err error) error {
+	if rejectionCode(err) != codes.Unknown {
+		return permanent(err)
+	}
+	return err
+}
+
+// correlationID returns the correlation ID header of a message, or else its position.
+func correlationID(msg kafka.Message) string {
+	if id := messageHeader(msg, HeaderCorrelationID); id != "" {
+		return id
+	}
+	return messagePosition(msg)
+}
@@ -0,0 +1,127 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/flash_sale/flash_sale_order_service/genproto/order_service"
+	"github.com/flash_sale/flash_sale_order_service/storage"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// decodeReply returns the reply carried by msg.
+func decodeReply(t *testing.T, msg kafka.Message) *order_service.BasketCommandReply {
+	reply := &order_service.BasketCommandReply{}
+	assert.NoError(t, protojson.Unmarshal(msg.Value, reply))
+	return reply
+}
+
+func TestCommandReplies(t *testing.T) {
+	publisher := &recordingPublisher{}
+	replier := NewReplier(publisher, "replies")
+
+	var err error
+	handler := Command(replier, CurrentSchema, func(ctx context.Context, value *order_service.UpdateBasketItemQuantityRequest, reply *order_service.BasketCommandReply) error {
+		reply.BasketItem = &order_service.BasketItem{Id: value.Id, Quantity: value.Quantity}
+		return err
+	})
+	msg := kafka.Message{
+		Topic:   "basket_item_topic",
+		Key:     []byte("basket_item.update_quantity"),
+		Value:   []byte(`{"id": "item-1", "quantity": 3}`),
+		Headers: []kafka.Header{{Key: HeaderCorrelationID, Value: []byte("request-1")}},
+	}
+
+	// Done with, so it is acknowledged
+	assert.NoError(t, handler(context.Background(), msg))
+	assert.Len(t, publisher.messages, 1)
+	assert.Equal(t, "replies", publisher.messages[0].Topic)
+	assert.Equal(t, "request-1", string(publisher.messages[0].Key))
+	reply := decodeReply(t, publisher.messages[0])
+	assert.True(t, reply.Ok)
+	assert.Equal(t, "request-1", reply.CorrelationId)
+	assert.Equal(t, "basket_item.update_quantity", reply.Command)
+	assert.Equal(t, int32(3), reply.BasketItem.Quantity)
+
+	// Rejected, so it is replied to instead of being retried
+	err = fmt.Errorf("error updating basket item quantity: %w", storage.ErrSoldOut)
+	assert.NoError(t, handler(context.Background(), msg))
+	assert.Len(t, publisher.messages, 2)
+	reply = decodeReply(t, publisher.messages[1])
+	assert.False(t, reply.Ok)
+	assert.Equal(t, "FailedPrecondition", reply.ErrorCode)
+	assert.Contains(t, reply.Error, "sold out")
+
+	// Failed for a reason that may go away, so it is retried without a reply
+	err = errors.New("connection reset")
+	assert.Error(t, handler(context.Background(), msg))
+	assert.Len(t, publisher.messages, 2)
+}
+
+func TestPermanentIfRejected(t *testing.T) {
+	var permanentErr permanentError
+
+	// A sold out basket is sold out on every attempt
+	err := permanentIfRejected(fmt.Errorf("error converting basket to order: %w", storage.ErrSoldOut))
+	assert.ErrorAs(t, err, &permanentErr)
+	assert.ErrorIs(t, err, storage.ErrSoldOut)
+
+	assert.False(t, errors.As(permanentIfRejected(errors.New("connection reset")), &permanentErr))
+}
+
+func TestCommandRejectsInvalidMessages(t *testing.T) {
+	publisher := &recordingPublisher{}
+	handlers := BasketItemHandlers(nil, NewReplier(publisher, "replies"))
+
+	messages := []kafka.Message{
+		{Key: []byte("basket_item.update_quantity"), Value: []byte(`{`)},
+		{Key: []byte("basket_item.update_quantity"), Value: []byte(`{"quantity": 3}`)},
+		{Key: []byte("basket_item.delete"), Value: []byte(`{}`)},
+		{Key: []byte("basket_item.create"), Value: []byte(`{"basket_item": {"quantity": 1}}`)},
+		{Key: []byte("basket.clear"), Value: []byte(`{}`)},
+		{Key: []byte("basket.create"), Value: []byte(`{"basket": {}}`)},
+	}
+	for i, msg := range messages {
+		msg.Topic = "basket_item_topic"
+		msg.Offset = int64(i)
+		assert.NoError(t, handlers.Handle(context.Background(), msg))
+
+		reply := decodeReply(t, publisher.messages[i])
+		assert.False(t, reply.Ok)
+		assert.Equal(t, "InvalidArgument", reply.ErrorCode)
+		assert.Equal(t, string(msg.Key), reply.Command)
+		// Without a correlation-id header, the position of the message stands in for it
+		assert.Equal(t, fmt.Sprintf("kafka:basket_item_topic/0/%d", i), reply.CorrelationId)
+	}
+}
+
+func TestCommandReplyTo(t *testing.T) {
+	publisher := &recordingPublisher{}
+	handler := Command(NewReplier(publisher, ""), CurrentSchema, func(ctx context.Context, value *order_service.ClearBasketItemsRequest, reply *order_service.BasketCommandReply) error {
+		return nil
+	})
+
+	// Without a reply topic, there is no one to reply to
+	assert.NoError(t, handler(context.Background(), kafka.Message{Key: []byte("basket.clear"), Value: []byte(`{}`)}))
+	assert.Empty(t, publisher.messages)
+
+	assert.NoError(t, handler(context.Background(), kafka.Message{
+		Key:     []byte("basket.clear"),
+		Value:   []byte(`{}`),
+		Headers: []kafka.Header{{Key: HeaderReplyTo, Value: []byte("gateway_replies")}},
+	}))
+	assert.Len(t, publisher.messages, 1)
+	assert.Equal(t, "gateway_replies", publisher.messages[0].Topic)
+
+	// A reply that cannot be sent does not get the command carried out again
+	publisher.err = errors.New("leader not available")
+	assert.NoError(t, handler(context.Background(), kafka.Message{
+		Key:     []byte("basket.clear"),
+		Value:   []byte(`{}`),
+		Headers: []kafka.Header{{Key: HeaderReplyTo, Value: []byte("gateway_replies")}},
+	}))
+}
@@ -0,0 +1,182 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/flash_sale/flash_sale_order_service/outbox"
+	"github.com/segmentio/kafka-go"
+)
+
+// Headers added to a message sent to the dead-letter queue.
+const (
+	HeaderDLQTopic     = "dlq-original-topic"
+	HeaderDLQPartition = "dlq-original-partition"
+	HeaderDLQOffset    = "dlq-original-offset"
+	HeaderDLQGroup     = "dlq-consumer-group"
+	HeaderDLQError     = "dlq-error"
+	HeaderDLQAttempts  = "dlq-attempts"
+	HeaderDLQFailedAt  = "dlq-failed-at" // RFC 3339
+)
+
+// RetryPolicy is how often, and how far apart, a message that failed is handled again.
+type RetryPolicy struct {
+	// MaxAttempts is how many times a message is handled before it is given up on, at least once.
+	MaxAttempts int
+	// InitialBackoff is the wait before the second attempt. Each further wait is twice the last,
+	// up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Backoff returns the wait after the given failed attempt, counting from 1.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < attempt && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return backoff
+}
+
+// permanentError marks a failure retrying cannot fix, such as a message that does not parse.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// permanent marks err as a failure that is not retried.
+func permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// Retrier handles messages under a RetryPolicy and sends the ones that keep failing to a
+// dead-letter queue.
+type Retrier struct {
+	policy    RetryPolicy
+	publisher outbox.Publisher
+	dlqTopic  string
+	group     string
+	sleep     func(ctx context.Context, d time.Duration) error
+}
+
+// NewRetrier creates a new Retrier instance. Messages that keep failing are written to dlqTopic
+// through publisher; with an empty dlqTopic they are logged and dropped.
+func NewRetrier(policy RetryPolicy, publisher outbox.Publisher, dlqTopic string) *Retrier {
+	return &Retrier{
+		policy:    policy,
+		publisher: publisher,
+		dlqTopic:  dlqTopic,
+		sleep:     sleep,
+	}
+}
+
+// forGroup returns a copy of the Retrier that records group as the consumer group of the
+// messages it dead-letters.
+func (r *Retrier) forGroup(group string) *Retrier {
+	copied := *r
+	copied.group = group
+	return &copied
+}
+
+// Process calls handle until it succeeds or the retry policy gives up, in which case the message
+// goes to the dead-letter queue. A nil error means the message is done with and its offset can
+// be committed; otherwise it has to be fetched again.
+func (r *Retrier) Process(ctx context.Context, msg kafka.Message, handle func(ctx context.Context, msg kafka.Message) error) error {
+	attempts := 0
+	for {
+		attempts++
+		err := handle(ctx, msg)
+		if err == nil {
+			return nil
+		}
+
+		var permanentErr permanentError
+		if errors.As(err, &permanentErr) || attempts >= r.policy.MaxAttempts {
+			return r.deadLetter(ctx, msg, err, attempts)
+		}
+
+		backoff := r.policy.Backoff(attempts)
+		log.Printf("retrying %s message at %d/%d in %v", msg.Topic, msg.Partition, msg.Offset, backoff)
+		if err := r.sleep(ctx, backoff); err != nil {
+			return err
+		}
+	}
+}
+
+// deadLetter writes a message that could not be handled to the dead-letter queue, along with
+// where it came from and why it failed.
+func (r *Retrier) deadLetter(ctx context.Context, msg kafka.Message, cause error, attempts int) error {
+	if r.dlqTopic == "" {
+		consumerMetrics.Add(r.group+".dropped", 1)
+		log.Printf("dropping %s message at %d/%d after %d attempts: %v", msg.Topic, msg.Partition, msg.Offset, attempts, cause)
+		return nil
+	}
+
+	headers := append([]kafka.Header{}, msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: HeaderDLQTopic, Value: []byte(msg.Topic)},
+		kafka.Header{Key: HeaderDLQPartition, Value: []byte(strconv.Itoa(msg.Partition))},
+		kafka.Header{Key: HeaderDLQOffset, Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+		kafka.Header{Key: HeaderDLQGroup, Value: []byte(r.group)},
+		kafka.Header{Key: HeaderDLQError, Value: []byte(cause.Error())},
+		kafka.Header{Key: HeaderDLQAttempts, Value: []byte(strconv.Itoa(attempts))},
+		kafka.Header{Key: HeaderDLQFailedAt, Value: []byte(time.Now().UTC().Format(time.RFC3339))},
+	)
+	err := r.publisher.WriteMessages(ctx, kafka.Message{
+		Topic:   r.dlqTopic,
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send %s message at %d/%d to the dead-letter queue: %w", msg.Topic, msg.Partition, msg.Offset, err)
+	}
+
+	consumerMetrics.Add(r.group+".dead_lettered", 1)
+	log.Printf("sent %s message at %d/%d to %s after %d attempts: %v", msg.Topic, msg.Partition, msg.Offset, r.dlqTopic, attempts, cause)
+	return nil
+}
+
+// ReplayMessage returns the message a dead-letter queue message was made from, ready to be
+// written back to its original topic.
+func ReplayMessage(msg kafka.Message) (kafka.Message, error) {
+	replay := kafka.Message{
+		Key:   msg.Key,
+		Value: msg.Value,
+	}
+	for _, header := range msg.Headers {
+		switch header.Key {
+		case HeaderDLQTopic:
+			replay.Topic = string(header.Value)
+		case HeaderDLQPartition, HeaderDLQOffset, HeaderDLQGroup, HeaderDLQError, HeaderDLQAttempts, HeaderDLQFailedAt:
+		default:
+			replay.Headers = append(replay.Headers, header)
+		}
+	}
+	if replay.Topic == "" {
+		return kafka.Message{}, fmt.Errorf("message at %d/%d has no %s header", msg.Partition, msg.Offset, HeaderDLQTopic)
+	}
+	return replay, nil
+}
+
+// sleep waits for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
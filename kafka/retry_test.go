@@ -0,0 +1,150 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingPublisher keeps the messages written to it, failing every write while err is set.
+type recordingPublisher struct {
+	messages []kafka.Message
+	err      error
+}
+
+func (p *recordingPublisher) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.messages = append(p.messages, msgs...)
+	return nil
+}
+
+var policy = RetryPolicy{MaxAttempts: 4, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+
+// newTestRetrier returns a Retrier that records its waits instead of sleeping.
+func newTestRetrier(publisher *recordingPublisher, dlqTopic string) (*Retrier, *[]time.Duration) {
+	var waits []time.Duration
+	retrier := NewRetrier(policy, publisher, dlqTopic).forGroup("test-group")
+	retrier.sleep = func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+	return retrier, &waits
+}
+
+var failedMessage = kafka.Message{
+	Topic:     "basket_item_topic",
+	Partition: 2,
+	Offset:    42,
+	Key:       []byte("basket_item.create"),
+	Value:     []byte(`{"basket_item": {}}`),
+	Headers:   []kafka.Header{{Key: "trace-id", Value: []byte("abc")}},
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 100*time.Millisecond, policy.Backoff(1))
+	assert.Equal(t, 200*time.Millisecond, policy.Backoff(2))
+	assert.Equal(t, 300*time.Millisecond, policy.Backoff(3)) // capped
+	assert.Equal(t, 300*time.Millisecond, policy.Backoff(10))
+}
+
+func TestProcessRetries(t *testing.T) {
+	publisher := &recordingPublisher{}
+	retrier, waits := newTestRetrier(publisher, "dlq")
+
+	calls := 0
+	err := retrier.Process(context.Background(), failedMessage, func(ctx context.Context, msg kafka.Message) error {
+		calls++
+		if calls < 3 {
+			return errors.New("connection reset")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}, *waits)
+	assert.Empty(t, publisher.messages)
+}
+
+func TestProcessDeadLetters(t *testing.T) {
+	publisher := &recordingPublisher{}
+	retrier, waits := newTestRetrier(publisher, "dlq")
+
+	calls := 0
+	err := retrier.Process(context.Background(), failedMessage, func(ctx context.Context, msg kafka.Message) error {
+		calls++
+		return errors.New("sold out")
+	})
+	assert.NoError(t, err) // Dead-lettered, so it can be committed
+	assert.Equal(t, policy.MaxAttempts, calls)
+	assert.Len(t, *waits, policy.MaxAttempts-1)
+
+	assert.Len(t, publisher.messages, 1)
+	dead := publisher.messages[0]
+	assert.Equal(t, "dlq", dead.Topic)
+	assert.Equal(t, failedMessage.Key, dead.Key)
+	assert.Equal(t, failedMessage.Value, dead.Value)
+
+	headers := make(map[string]string)
+	for _, header := range dead.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+	assert.Equal(t, "abc", headers["trace-id"])
+	assert.Equal(t, "basket_item_topic", headers[HeaderDLQTopic])
+	assert.Equal(t, "2", headers[HeaderDLQPartition])
+	assert.Equal(t, "42", headers[HeaderDLQOffset])
+	assert.Equal(t, "test-group", headers[HeaderDLQGroup])
+	assert.Equal(t, "sold out", headers[HeaderDLQError])
+	assert.Equal(t, "4", headers[HeaderDLQAttempts])
+	assert.NotEmpty(t, headers[HeaderDLQFailedAt])
+
+	// Written back, it is the message that failed
+	replay, err := ReplayMessage(dead)
+	assert.NoError(t, err)
+	assert.Equal(t, kafka.Message{
+		Topic:   failedMessage.Topic,
+		Key:     failedMessage.Key,
+		Value:   failedMessage.Value,
+		Headers: failedMessage.Headers,
+	}, replay)
+
+	_, err = ReplayMessage(failedMessage)
+	assert.Error(t, err)
+}
+
+func TestProcessPermanentError(t *testing.T) {
+	publisher := &recordingPublisher{}
+	retrier, waits := newTestRetrier(publisher, "dlq")
+
+	calls := 0
+	err := retrier.Process(context.Background(), failedMessage, func(ctx context.Context, msg kafka.Message) error {
+		calls++
+		return permanent(errors.New("unexpected end of JSON input"))
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+	assert.Empty(t, *waits)
+	assert.Len(t, publisher.messages, 1)
+}
+
+func TestProcessDeadLetterFails(t *testing.T) {
+	publisher := &recordingPublisher{err: errors.New("leader not available")}
+	retrier, _ := newTestRetrier(publisher, "dlq")
+
+	err := retrier.Process(context.Background(), failedMessage, func(ctx context.Context, msg kafka.Message) error {
+		return permanent(errors.New("bad message"))
+	})
+	assert.Error(t, err) // Not committed, so it is fetched again
+
+	// Without a dead-letter topic the message is dropped
+	retrier, _ = newTestRetrier(publisher, "")
+	err = retrier.Process(context.Background(), failedMessage, func(ctx context.Context, msg kafka.Message) error {
+		return permanent(errors.New("bad message"))
+	})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,207 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// RunnerConfig is where a Runner reads from and how many messages it handles at once.
+type RunnerConfig struct {
+	Brokers []string
+	Topic   string
+	GroupID string
+	// Concurrency is how many messages of one partition are handled at the same time. With
+	// more than 1, messages of a partition may be handled out of order, but offsets are still
+	// committed in order. Partitions are handled side by side, but they share one fetch loop:
+	// while a partition has Concurrency messages in flight, no partition gets new messages.
+	Concurrency int
+}
+
+// messageReader is the part of *kafka.Reader a Runner uses.
+type messageReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// Runner fetches the messages of a topic and hands them to a handler, retrying and
+// dead-lettering them through a Retrier. A message is only committed once it and every
+// message before it in its partition are done with, so none is ever skipped.
+type Runner struct {
+	reader      messageReader
+	handler     Handler
+	retrier     *Retrier
+	concurrency int
+}
+
+// NewRunner creates a new Runner for handler, wrapped in middleware.
+func NewRunner(cfg RunnerConfig, handler Handler, retrier *Retrier, middleware ...Middleware) *Runner {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: cfg.Brokers,
+		Topic:   cfg.Topic,
+		GroupID: cfg.GroupID,
+	})
+	return newRunner(reader, cfg, handler, retrier, middleware...)
+}
+
+func newRunner(reader messageReader, cfg RunnerConfig, handler Handler, retrier *Retrier, middleware ...Middleware) *Runner {
+	return &Runner{
+		reader:      reader,
+		handler:     Chain(handler, middleware...),
+		retrier:     retrier.forGroup(cfg.GroupID),
+		concurrency: max(cfg.Concurrency, 1),
+	}
+}
+
+// Run handles messages until ctx is done or a message can neither be handled nor
+// dead-lettered. Messages being handled are finished before it returns.
+func (r *Runner) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg           sync.WaitGroup
+		failOnce     sync.Once
+		failure      error
+		partitionsMu sync.Mutex
+		partitions   = make(map[int]*partitionOffsets)
+	)
+	fail := func(err error) {
+		failOnce.Do(func() {
+			failure = err
+			cancel()
+		})
+	}
+	// forget drops a partition taken away by a rebalance. Its messages are fetched again, by
+	// this reader or another, from the last committed offset, and are tracked anew.
+	forget := func(id int, partition *partitionOffsets) {
+		partition.revoke()
+		partitionsMu.Lock()
+		if partitions[id] == partition {
+			delete(partitions, id)
+		}
+		partitionsMu.Unlock()
+	}
+
+	for {
+		msg, err := r.reader.FetchMessage(ctx)
+		if err != nil {
+			fail(fmt.Errorf("error fetching message: %w", err))
+			break
+		}
+
+		partitionsMu.Lock()
+		partition, ok := partitions[msg.Partition]
+		if !ok {
+			partition = newPartitionOffsets(r.concurrency)
+			partitions[msg.Partition] = partition
+		}
+		partitionsMu.Unlock()
+		if !partition.acquire(ctx, msg) {
+			fail(ctx.Err())
+			break
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer partition.release()
+
+			if err := r.retrier.Process(ctx, msg, r.handler); err != nil {
+				fail(err)
+				return
+			}
+			err := partition.done(ctx, r.reader, msg)
+			if isRevoked(err) {
+				log.Printf("partition %d was revoked, its uncommitted messages are fetched again: %v", msg.Partition, err)
+				forget(msg.Partition, partition)
+				return
+			}
+			if err != nil {
+				fail(fmt.Errorf("error committing message: %w", err))
+			}
+		}()
+	}
+
+	wg.Wait()
+	return failure
+}
+
+// isRevoked reports whether a commit failed because the partition was assigned elsewhere by a
+// rebalance of the consumer group.
+func isRevoked(err error) bool {
+	return errors.Is(err, kafka.RebalanceInProgress) ||
+		errors.Is(err, kafka.IllegalGeneration) ||
+		errors.Is(err, kafka.UnknownMemberId)
+}
+
+// partitionOffsets tracks the messages of one partition being handled, so offsets are committed
+// in order however the handling finishes.
+type partitionOffsets struct {
+	slots chan struct{}
+
+	mu       sync.Mutex
+	pending  []kafka.Message // Fetched and not committed, in offset order
+	finished map[int64]bool
+	revoked  bool // Set once the partition is taken away; nothing more is committed
+}
+
+func newPartitionOffsets(concurrency int) *partitionOffsets {
+	return &partitionOffsets{
+		slots:    make(chan struct{}, concurrency),
+		finished: make(map[int64]bool),
+	}
+}
+
+// acquire waits for a free slot to handle msg in, reporting false if ctx is done first.
+func (p *partitionOffsets) acquire(ctx context.Context, msg kafka.Message) bool {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return false
+	}
+
+	p.mu.Lock()
+	p.pending = append(p.pending, msg)
+	p.mu.Unlock()
+	return true
+}
+
+func (p *partitionOffsets) release() {
+	<-p.slots
+}
+
+// revoke stops the messages still being handled from committing, since a later offset could
+// skip one of the messages that will be fetched again.
+func (p *partitionOffsets) revoke() {
+	p.mu.Lock()
+	p.revoked = true
+	p.mu.Unlock()
+}
+
+// done marks msg as handled and commits the messages handled without a gap before them.
+// The lock is held while committing so a later offset is never overtaken by an earlier one.
+func (p *partitionOffsets) done(ctx context.Context, reader messageReader, msg kafka.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.revoked {
+		return nil
+	}
+
+	p.finished[msg.Offset] = true
+	var last *kafka.Message
+	for len(p.pending) > 0 && p.finished[p.pending[0].Offset] {
+		head := p.pending[0]
+		delete(p.finished, head.Offset)
+		p.pending = p.pending[1:]
+		last = &head
+	}
+	if last == nil {
+		return nil
+	}
+	return reader.CommitMessages(ctx, *last)
+}
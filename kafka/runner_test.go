@@ -0,0 +1,182 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flash_sale/flash_sale_order_service/genproto/order_service"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// queueReader hands out a fixed list of messages, then blocks until ctx is done.
+type queueReader struct {
+	mu        sync.Mutex
+	messages  []kafka.Message
+	committed map[int][]int64
+	commitErr error // Returned by the next commit, then cleared
+}
+
+func (r *queueReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	r.mu.Lock()
+	if len(r.messages) > 0 {
+		msg := r.messages[0]
+		r.messages = r.messages[1:]
+		r.mu.Unlock()
+		return msg, nil
+	}
+	r.mu.Unlock()
+
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (r *queueReader) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.commitErr; err != nil {
+		r.commitErr = nil
+		return err
+	}
+	for _, msg := range msgs {
+		r.committed[msg.Partition] = append(r.committed[msg.Partition], msg.Offset)
+	}
+	return nil
+}
+
+func (r *queueReader) lastCommitted(partition int) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	offsets := r.committed[partition]
+	if len(offsets) == 0 {
+		return -1
+	}
+	return offsets[len(offsets)-1]
+}
+
+func TestRunnerCommitsInOrder(t *testing.T) {
+	reader := &queueReader{committed: make(map[int][]int64)}
+	for offset := int64(0); offset < 6; offset++ {
+		for partition := 0; partition < 2; partition++ {
+			reader.messages = append(reader.messages, kafka.Message{Partition: partition, Offset: offset, Key: []byte("test")})
+		}
+	}
+
+	// Earlier offsets take longer, so they finish after later ones
+	handler := func(ctx context.Context, msg kafka.Message) error {
+		time.Sleep(time.Duration(6-msg.Offset) * 5 * time.Millisecond)
+		return nil
+	}
+	retrier, _ := newTestRetrier(&recordingPublisher{}, "dlq")
+	runner := newRunner(reader, RunnerConfig{GroupID: "test-group", Concurrency: 3}, handler, retrier)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- runner.Run(ctx) }()
+
+	assert.Eventually(t, func() bool {
+		return reader.lastCommitted(0) == 5 && reader.lastCommitted(1) == 5
+	}, time.Second, 5*time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	for partition, offsets := range reader.committed {
+		for i := 1; i < len(offsets); i++ {
+			assert.Greater(t, offsets[i], offsets[i-1], "partition %d", partition)
+		}
+	}
+}
+
+func TestRunnerForgetsRevokedPartition(t *testing.T) {
+	reader := &queueReader{committed: make(map[int][]int64), commitErr: kafka.RebalanceInProgress}
+	for offset := int64(0); offset < 3; offset++ {
+		reader.messages = append(reader.messages, kafka.Message{Offset: offset, Key: []byte("test")})
+	}
+
+	handler := func(ctx context.Context, msg kafka.Message) error { return nil }
+	retrier, _ := newTestRetrier(&recordingPublisher{}, "dlq")
+	runner := newRunner(reader, RunnerConfig{GroupID: "test-group"}, handler, retrier)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- runner.Run(ctx) }()
+
+	// The failed commit does not stop the runner, and later messages are committed again
+	assert.Eventually(t, func() bool {
+		return reader.lastCommitted(0) == 2
+	}, time.Second, 5*time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.NotContains(t, reader.committed[0], int64(0))
+}
+
+func TestRunnerStopsWhenDeadLetterFails(t *testing.T) {
+	reader := &queueReader{
+		messages:  []kafka.Message{{Offset: 0, Key: []byte("test")}},
+		committed: make(map[int][]int64),
+	}
+	handler := func(ctx context.Context, msg kafka.Message) error {
+		return permanent(errors.New("bad message"))
+	}
+	retrier, _ := newTestRetrier(&recordingPublisher{err: errors.New("leader not available")}, "dlq")
+	runner := newRunner(reader, RunnerConfig{GroupID: "test-group"}, handler, retrier)
+
+	assert.Error(t, runner.Run(context.Background()))
+	assert.Empty(t, reader.committed) // Fetched again on restart
+}
+
+func TestRegistry(t *testing.T) {
+	var got []string
+	registry := NewRegistry().
+		Register("item.create", Proto(CurrentSchema, func(ctx context.Context, msg kafka.Message, item *order_service.GetBasketItemRequest) error {
+			got = append(got, "create "+item.Id)
+			return nil
+		})).
+		Register("item.delete", Proto(CurrentSchema, func(ctx context.Context, msg kafka.Message, item *order_service.DeleteBasketItemRequest) error {
+			got = append(got, "delete "+item.Id)
+			return nil
+		}))
+	assert.Equal(t, []string{"item.create", "item.delete"}, registry.Keys())
+
+	ctx := context.Background()
+	assert.NoError(t, registry.Handle(ctx, kafka.Message{Key: []byte("item.create"), Value: []byte(`{"id": "1"}`)}))
+	assert.NoError(t, registry.Handle(ctx, kafka.Message{Key: []byte("item.delete"), Value: []byte(`{"id": "2"}`)}))
+	assert.NoError(t, registry.Handle(ctx, kafka.Message{Key: []byte("item.unknown"), Value: []byte(`{}`)}))
+	assert.Equal(t, []string{"create 1", "delete 2"}, got)
+
+	// A value that does not decode is not retried
+	err := registry.Handle(ctx, kafka.Message{Key: []byte("item.create"), Value: []byte(`{`)})
+	var permanentErr permanentError
+	assert.ErrorAs(t, err, &permanentErr)
+
+	assert.Panics(t, func() {
+		registry.Register("item.create", func(ctx context.Context, msg kafka.Message) error { return nil })
+	})
+}
+
+func TestMiddleware(t *testing.T) {
+	handler := Chain(func(ctx context.Context, msg kafka.Message) error {
+		if string(msg.Value) == "panic" {
+			panic("nil map")
+		}
+		if string(msg.Value) == "fail" {
+			return errors.New("sold out")
+		}
+		return nil
+	}, DefaultMiddleware("test-consumer")...)
+
+	ctx := context.Background()
+	assert.NoError(t, handler(ctx, kafka.Message{Key: []byte("metrics"), Value: []byte("ok")}))
+	assert.Error(t, handler(ctx, kafka.Message{Key: []byte("metrics"), Value: []byte("fail")}))
+
+	// A panic is a permanent failure
+	err := handler(ctx, kafka.Message{Key: []byte("metrics"), Value: []byte("panic")})
+	var permanentErr permanentError
+	assert.ErrorAs(t, err, &permanentErr)
+
+	assert.Equal(t, "1", consumerMetrics.Get("test-consumer.metrics.handled").String())
+	assert.Equal(t, "2", consumerMetrics.Get("test-consumer.metrics.failed").String())
+}
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS stock_holds (
+    id UUID PRIMARY KEY,
+    basket_item_id UUID NOT NULL REFERENCES basket_items(id),
+    flash_sale_event_product_id UUID NOT NULL REFERENCES flash_sale_event_products(id),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE', -- 'ACTIVE', 'RELEASED', 'CONSUMED'
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- A basket item holds stock at most once at a time
+CREATE UNIQUE INDEX IF NOT EXISTS stock_holds_active_basket_item_idx
+    ON stock_holds (basket_item_id) WHERE status = 'ACTIVE';
+
+CREATE INDEX IF NOT EXISTS stock_holds_active_expires_at_idx
+    ON stock_holds (expires_at) WHERE status = 'ACTIVE';
+
+CREATE INDEX IF NOT EXISTS stock_holds_flash_sale_event_product_idx
+    ON stock_holds (flash_sale_event_product_id) WHERE status = 'ACTIVE';
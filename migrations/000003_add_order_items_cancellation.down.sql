@@ -0,0 +1,5 @@
+ALTER TABLE order_items DROP CONSTRAINT IF EXISTS order_items_cancelled_quantity_check;
+
+ALTER TABLE order_items
+    DROP COLUMN IF EXISTS cancellation_reason,
+    DROP COLUMN IF EXISTS cancelled_quantity;
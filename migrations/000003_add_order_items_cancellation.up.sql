@@ -0,0 +1,7 @@
+ALTER TABLE order_items
+    ADD COLUMN IF NOT EXISTS cancelled_quantity INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS cancellation_reason TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE order_items
+    ADD CONSTRAINT order_items_cancelled_quantity_check
+        CHECK (cancelled_quantity >= 0 AND cancelled_quantity <= quantity);
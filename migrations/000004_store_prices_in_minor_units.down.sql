@@ -0,0 +1,11 @@
+ALTER TABLE order_items
+    ALTER COLUMN unit_price TYPE REAL USING unit_price / 100.0,
+    ALTER COLUMN total_price TYPE REAL USING total_price / 100.0,
+    ALTER COLUMN discount_applied TYPE REAL USING discount_applied / 100.0;
+
+ALTER TABLE orders
+    ALTER COLUMN total_price TYPE REAL USING total_price / 100.0;
+
+ALTER TABLE basket_items
+    ALTER COLUMN unit_price TYPE REAL USING unit_price / 100.0,
+    ALTER COLUMN total_price TYPE REAL USING total_price / 100.0;
@@ -0,0 +1,12 @@
+-- Prices owned by this service are kept as whole cents (see package money).
+ALTER TABLE basket_items
+    ALTER COLUMN unit_price TYPE BIGINT USING ROUND(unit_price::numeric * 100)::bigint,
+    ALTER COLUMN total_price TYPE BIGINT USING ROUND(total_price::numeric * 100)::bigint;
+
+ALTER TABLE orders
+    ALTER COLUMN total_price TYPE BIGINT USING ROUND(total_price::numeric * 100)::bigint;
+
+ALTER TABLE order_items
+    ALTER COLUMN unit_price TYPE BIGINT USING ROUND(unit_price::numeric * 100)::bigint,
+    ALTER COLUMN total_price TYPE BIGINT USING ROUND(total_price::numeric * 100)::bigint,
+    ALTER COLUMN discount_applied TYPE BIGINT USING ROUND(discount_applied::numeric * 100)::bigint;
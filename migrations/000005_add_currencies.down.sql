@@ -0,0 +1,7 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS exchange_rate,
+    DROP COLUMN IF EXISTS currency_code;
+
+ALTER TABLE order_items DROP COLUMN IF EXISTS currency_code;
+ALTER TABLE basket_items DROP COLUMN IF EXISTS currency_code;
+ALTER TABLE baskets DROP COLUMN IF EXISTS currency_code;
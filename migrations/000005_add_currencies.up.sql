@@ -0,0 +1,16 @@
+-- Baskets and orders carry the currency they are charged in. Line items copy it so
+-- their amounts can be read on their own.
+ALTER TABLE baskets ADD COLUMN IF NOT EXISTS currency_code CHAR(3) NOT NULL DEFAULT 'USD';
+ALTER TABLE basket_items ADD COLUMN IF NOT EXISTS currency_code CHAR(3) NOT NULL DEFAULT 'USD';
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS currency_code CHAR(3) NOT NULL DEFAULT 'USD';
+
+-- exchange_rate converts base currency prices into the order currency, in units of
+-- 10^-8 (see money.Rate). It is 0 until it is fixed at checkout.
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS currency_code CHAR(3) NOT NULL DEFAULT 'USD',
+    ADD COLUMN IF NOT EXISTS exchange_rate BIGINT NOT NULL DEFAULT 0;
+
+-- Orders checked out before currencies existed were charged in the base currency
+UPDATE orders o
+SET exchange_rate = 100000000
+WHERE EXISTS (SELECT 1 FROM order_items oi WHERE oi.order_id = o.id);
@@ -0,0 +1,3 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS discount_total;
+DROP TABLE IF EXISTS order_promotions;
+DROP TABLE IF EXISTS coupons;
@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS coupons (
+    id UUID PRIMARY KEY,
+    code VARCHAR(64) NOT NULL, -- Stored upper case
+    effect VARCHAR(20) NOT NULL, -- 'PERCENTAGE', 'FIXED_AMOUNT', 'FREE_SHIPPING'
+    percentage BIGINT NOT NULL DEFAULT 0, -- Hundredths of a percent, for PERCENTAGE
+    amount BIGINT NOT NULL DEFAULT 0, -- Minor units of currency_code, for FIXED_AMOUNT
+    min_basket_value BIGINT NOT NULL DEFAULT 0, -- Minor units of currency_code
+    currency_code CHAR(3) NOT NULL DEFAULT 'USD',
+    max_uses INT NOT NULL DEFAULT 0, -- 0 means unlimited
+    max_uses_per_user INT NOT NULL DEFAULT 0, -- 0 means unlimited
+    times_used INT NOT NULL DEFAULT 0,
+    starts_at TIMESTAMP NOT NULL,
+    ends_at TIMESTAMP NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    deleted_at BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS coupons_code_idx
+    ON coupons (code) WHERE deleted_at = 0;
+
+-- A coupon redeemed against an order
+CREATE TABLE IF NOT EXISTS order_promotions (
+    id UUID PRIMARY KEY,
+    order_id UUID NOT NULL REFERENCES orders(id),
+    coupon_id UUID NOT NULL REFERENCES coupons(id),
+    user_id UUID NOT NULL,
+    code VARCHAR(64) NOT NULL,
+    effect VARCHAR(20) NOT NULL,
+    discount BIGINT NOT NULL DEFAULT 0, -- Minor units of currency_code taken off the order
+    currency_code CHAR(3) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS order_promotions_order_id_idx
+    ON order_promotions (order_id);
+
+CREATE INDEX IF NOT EXISTS order_promotions_coupon_user_idx
+    ON order_promotions (coupon_id, user_id);
+
+-- Sum of order_promotions.discount, taken off the items when the total is computed
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS discount_total BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS price_quotes (
+    id UUID PRIMARY KEY,
+    basket_id UUID NOT NULL REFERENCES baskets(id),
+    currency_code CHAR(3) NOT NULL,
+    total_price BIGINT NOT NULL, -- Minor units of currency_code
+    expires_at TIMESTAMP NOT NULL, -- UTC, whole seconds
+    signature VARCHAR(64) NOT NULL, -- Hex HMAC-SHA256 of the quote
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS price_quotes_basket_id_idx
+    ON price_quotes (basket_id);
+
+-- The frozen price of one basket item
+CREATE TABLE IF NOT EXISTS price_quote_lines (
+    quote_id UUID NOT NULL REFERENCES price_quotes(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    basket_item_id UUID NOT NULL,
+    quantity INT NOT NULL,
+    base_price BIGINT NOT NULL, -- Minor units, before promotions
+    unit_price BIGINT NOT NULL, -- Minor units
+    promotions TEXT[] NOT NULL DEFAULT '{}', -- Kinds of the promotions unit_price includes
+    PRIMARY KEY (quote_id, position)
+);
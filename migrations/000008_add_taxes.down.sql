@@ -0,0 +1,6 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS tax_inclusive,
+    DROP COLUMN IF EXISTS tax_total;
+
+ALTER TABLE order_items DROP COLUMN IF EXISTS tax_amount;
+ALTER TABLE products DROP COLUMN IF EXISTS tax_category;
@@ -0,0 +1,11 @@
+-- Products are taxed at the rate of their category in the region an order is delivered to
+ALTER TABLE products ADD COLUMN IF NOT EXISTS tax_category VARCHAR(32) NOT NULL DEFAULT 'STANDARD';
+
+-- Tax owed on the item, in minor units of currency_code
+ALTER TABLE order_items ADD COLUMN IF NOT EXISTS tax_amount BIGINT NOT NULL DEFAULT 0;
+
+-- tax_total is the sum of the item taxes. When tax_inclusive is set it is already part
+-- of the item prices; otherwise it was added to total_price.
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS tax_total BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tax_inclusive BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,5 @@
+-- shipping_zone is the delivery zone resolved from the delivery coordinates when the order
+-- is created. shipping_fee is in minor units of currency_code and is part of total_price.
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS shipping_zone VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS shipping_fee BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,33 @@
+-- A client's address book
+CREATE TABLE IF NOT EXISTS addresses (
+    id UUID PRIMARY KEY,
+    client_id UUID NOT NULL,
+    label VARCHAR(64) NOT NULL DEFAULT '',
+    street VARCHAR(255) NOT NULL,
+    city VARCHAR(128) NOT NULL,
+    postal_code VARCHAR(20) NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    recipient_phone VARCHAR(16) NOT NULL DEFAULT '', -- E.164
+    latitude DOUBLE PRECISION NOT NULL,
+    longitude DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    deleted_at BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS addresses_client_id_idx
+    ON addresses (client_id) WHERE deleted_at = 0;
+
+-- The address an order is delivered to. It is a copy, so editing or deleting a saved
+-- address never changes orders already placed with it.
+CREATE TABLE IF NOT EXISTS order_addresses (
+    order_id UUID PRIMARY KEY REFERENCES orders(id),
+    address_id UUID REFERENCES addresses(id), -- The saved address it was copied from, if any
+    street VARCHAR(255) NOT NULL,
+    city VARCHAR(128) NOT NULL,
+    postal_code VARCHAR(20) NOT NULL DEFAULT '',
+    notes TEXT NOT NULL DEFAULT '',
+    recipient_phone VARCHAR(16) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
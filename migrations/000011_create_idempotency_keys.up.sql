@@ -0,0 +1,13 @@
+-- The results of requests sent with an idempotency key, replayed when the request is retried
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    operation VARCHAR(64) NOT NULL, -- e.g. "CreateOrder"
+    key VARCHAR(255) NOT NULL,
+    request_hash BYTEA NOT NULL, -- SHA-256 of the request, so a key cannot be reused for another request
+    response BYTEA, -- Protobuf encoded; NULL until the request succeeds
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (operation, key)
+);
+
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx
+    ON idempotency_keys (expires_at);
@@ -0,0 +1,11 @@
+-- Domain events waiting to be published to Kafka. Rows are written in the transaction of the
+-- change they describe and deleted once published.
+CREATE TABLE IF NOT EXISTS outbox (
+    id BIGSERIAL PRIMARY KEY, -- Publishing order
+    event_id UUID NOT NULL UNIQUE, -- Sent along, so consumers can drop redeliveries
+    topic VARCHAR(128) NOT NULL,
+    aggregate_type VARCHAR(32) NOT NULL, -- 'order' or 'basket'
+    aggregate_id UUID NOT NULL, -- Message key
+    payload BYTEA NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
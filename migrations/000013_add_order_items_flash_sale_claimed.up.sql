@@ -0,0 +1,8 @@
+-- Whether the units of an order item were taken from its flash sale, so only those are given back to it.
+-- Items from before this column are taken to be claimed when they were sold as flash sale items.
+ALTER TABLE order_items
+    ADD COLUMN IF NOT EXISTS flash_sale_claimed BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE order_items
+SET flash_sale_claimed = TRUE
+WHERE product_type = 'FLASH_SALE' AND flash_sale_event_product_id IS NOT NULL;
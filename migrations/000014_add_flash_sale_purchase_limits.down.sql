@@ -0,0 +1,2 @@
+ALTER TABLE flash_sale_event_products DROP COLUMN IF EXISTS max_per_user;
+ALTER TABLE flash_sale_events DROP COLUMN IF EXISTS max_per_user;
@@ -0,0 +1,7 @@
+-- Per-user purchase caps of a flash sale event, across its products, and of a single product in it.
+-- NULL falls back to the caps in the service configuration.
+ALTER TABLE flash_sale_events
+    ADD COLUMN IF NOT EXISTS max_per_user INTEGER CHECK (max_per_user > 0);
+
+ALTER TABLE flash_sale_event_products
+    ADD COLUMN IF NOT EXISTS max_per_user INTEGER CHECK (max_per_user > 0);
@@ -1,75 +1,94 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/flash_sale/flash_sale_order_service/money"
+)
 
 // Basket represents a shopping basket model for the database.
 type Basket struct {
-	Id        string    `db:"id"`
-	UserId    string    `db:"user_id"`
-	Status    string    `db:"status"` // Possible values: 'OPEN', 'CHECKED_OUT'
-	CreatedAt time.Time `db:"created_at"`
-	UpdatedAt time.Time `db:"updated_at"`
-	DeletedAt int64     `db:"deleted_at"`
+	Id           string    `db:"id"`
+	UserId       string    `db:"user_id"`
+	Status       string    `db:"status"` // Possible values: 'OPEN', 'CHECKED_OUT'
+	CurrencyCode string    `db:"currency_code"`
+	CreatedAt    time.Time `db:"created_at"`
+	UpdatedAt    time.Time `db:"updated_at"`
+	DeletedAt    int64     `db:"deleted_at"`
 }
 
 // BasketItem represents a basket item model for the database.
 type BasketItem struct {
-	Id                      string    `db:"id"`
-	BasketId                string    `db:"basket_id"`
-	ProductId               string    `db:"product_id"`
-	FlashSaleEventProductId string    `db:"flash_sale_event_product_id"`
-	DiscountProductId       string    `db:"discount_product_id"`
-	Quantity                int32     `db:"quantity"`
-	UnitPrice               float32   `db:"unit_price"`
-	TotalPrice              float32   `db:"total_price"`
-	ProductType             string    `db:"product_type"` // Possible values: 'REGULAR', 'FLASH_SALE', 'DISCOUNT'
-	CreatedAt               time.Time `db:"created_at"`
-	UpdatedAt               time.Time `db:"updated_at"`
-	DeletedAt               int64     `db:"deleted_at"`
+	Id                      string       `db:"id"`
+	BasketId                string       `db:"basket_id"`
+	ProductId               string       `db:"product_id"`
+	FlashSaleEventProductId string       `db:"flash_sale_event_product_id"`
+	DiscountProductId       string       `db:"discount_product_id"`
+	Quantity                int32        `db:"quantity"`
+	UnitPrice               money.Amount `db:"unit_price"`
+	TotalPrice              money.Amount `db:"total_price"`
+	CurrencyCode            string       `db:"currency_code"`
+	ProductType             string       `db:"product_type"` // Possible values: 'REGULAR', 'FLASH_SALE', 'DISCOUNT'
+	CreatedAt               time.Time    `db:"created_at"`
+	UpdatedAt               time.Time    `db:"updated_at"`
+	DeletedAt               int64        `db:"deleted_at"`
 }
 
 // Order represents an order model for the database.
 type Order struct {
-	Id                string    `db:"id"`
-	ClientId          string    `db:"client_id"`
-	DeliveryLatitude  float64   `db:"delivery_latitude"`
-	DeliveryLongitude float64   `db:"delivery_longitude"`
-	TotalPrice        float32   `db:"total_price"`
-	Status            string    `db:"status"` // Possible values: 'PENDING', 'PROCESSING', 'SHIPPED', 'DELIVERED', 'CANCELLED'
-	CreatedAt         time.Time `db:"created_at"`
-	UpdatedAt         time.Time `db:"updated_at"`
-	DeletedAt         int64     `db:"deleted_at"`
+	Id                string       `db:"id"`
+	ClientId          string       `db:"client_id"`
+	DeliveryLatitude  float64      `db:"delivery_latitude"`
+	DeliveryLongitude float64      `db:"delivery_longitude"`
+	TotalPrice        money.Amount `db:"total_price"`
+	CurrencyCode      string       `db:"currency_code"`
+	ExchangeRate      money.Rate   `db:"exchange_rate"` // From the base currency, fixed at checkout; 0 until then
+	DiscountTotal     money.Amount `db:"discount_total"`
+	TaxTotal          money.Amount `db:"tax_total"`
+	TaxInclusive      bool         `db:"tax_inclusive"`
+	ShippingZone      string       `db:"shipping_zone"`
+	ShippingFee       money.Amount `db:"shipping_fee"`
+	Status            string       `db:"status"` // Possible values: 'PENDING', 'PROCESSING', 'SHIPPED', 'DELIVERED', 'CANCELLED'
+	CreatedAt         time.Time    `db:"created_at"`
+	UpdatedAt         time.Time    `db:"updated_at"`
+	DeletedAt         int64        `db:"deleted_at"`
 }
 
 // OrderItem represents an order item model for the database.
 type OrderItem struct {
-	Id                      string    `db:"id"`
-	OrderId                 string    `db:"order_id"`
-	ProductId               string    `db:"product_id"`
-	FlashSaleEventProductId string    `db:"flash_sale_event_product_id"`
-	DiscountProductId       string    `db:"discount_product_id"`
-	Quantity                int32     `db:"quantity"`
-	UnitPrice               float32   `db:"unit_price"`
-	TotalPrice              float32   `db:"total_price"`
-	DiscountApplied         float32   `db:"discount_applied"`
-	ProductType             string    `db:"product_type"` // Possible values: 'REGULAR', 'FLASH_SALE', 'DISCOUNT'
-	CreatedAt               time.Time `db:"created_at"`
-	UpdatedAt               time.Time `db:"updated_at"`
-	DeletedAt               int64     `db:"deleted_at"`
+	Id                      string       `db:"id"`
+	OrderId                 string       `db:"order_id"`
+	ProductId               string       `db:"product_id"`
+	FlashSaleEventProductId string       `db:"flash_sale_event_product_id"`
+	DiscountProductId       string       `db:"discount_product_id"`
+	Quantity                int32        `db:"quantity"`
+	UnitPrice               money.Amount `db:"unit_price"`
+	TotalPrice              money.Amount `db:"total_price"`
+	DiscountApplied         money.Amount `db:"discount_applied"`
+	TaxAmount               money.Amount `db:"tax_amount"`
+	CurrencyCode            string       `db:"currency_code"`
+	ProductType             string       `db:"product_type"`       // Possible values: 'REGULAR', 'FLASH_SALE', 'DISCOUNT'
+	FlashSaleClaimed        bool         `db:"flash_sale_claimed"` // Units were taken from the flash sale
+	CancelledQuantity       int32        `db:"cancelled_quantity"`
+	CancellationReason      string       `db:"cancellation_reason"`
+	CreatedAt               time.Time    `db:"created_at"`
+	UpdatedAt               time.Time    `db:"updated_at"`
+	DeletedAt               int64        `db:"deleted_at"`
 }
 
 // Database Model
 type Product struct {
-	Id            string    `db:"id"`
-	Name          string    `db:"name"`
-	Description   string    `db:"description"`
-	BasePrice     float32   `db:"base_price"`
-	CurrentPrice  float32   `db:"current_price"`
-	ImageUrl      string    `db:"image_url"`
-	StockQuantity int32     `db:"stock_quantity"`
-	CreatedAt     time.Time `db:"created_at"`
-	UpdatedAt     time.Time `db:"updated_at"`
-	DeletedAt     int64     `db:"deleted_at"`
+	Id            string       `db:"id"`
+	Name          string       `db:"name"`
+	Description   string       `db:"description"`
+	BasePrice     money.Amount `db:"base_price"`
+	CurrentPrice  money.Amount `db:"current_price"`
+	ImageUrl      string       `db:"image_url"`
+	StockQuantity int32        `db:"stock_quantity"`
+	TaxCategory   string       `db:"tax_category"`
+	CreatedAt     time.Time    `db:"created_at"`
+	UpdatedAt     time.Time    `db:"updated_at"`
+	DeletedAt     int64        `db:"deleted_at"`
 }
 
 // Discount represents a discount model for the database.
@@ -77,8 +96,8 @@ type Discount struct {
 	Id            string    `db:"id"`
 	Name          string    `db:"name"`
 	Description   string    `db:"description"`
-	DiscountType  string    `db:"discount_type"` // Possible values: 'PERCENTAGE', 'FIXED_AMOUNT'
-	DiscountValue float32   `db:"discount_value"`
+	DiscountType  string    `db:"discount_type"`  // Possible values: 'PERCENTAGE', 'FIXED_AMOUNT'
+	DiscountValue string    `db:"discount_value"` // A percentage or an amount, depending on DiscountType
 	StartDate     time.Time `db:"start_date"`
 	EndDate       time.Time `db:"end_date"`
 	IsActive      bool      `db:"is_active"`
@@ -113,14 +132,105 @@ type ProductDiscount struct {
 
 // FlashSaleEventProduct represents a flash sale event product model for the database.
 type FlashSaleEventProduct struct {
-	Id                 string    `db:"id"`
-	EventId            string    `db:"event_id"`
-	ProductId          string    `db:"product_id"`
-	DiscountPercentage float32   `db:"discount_percentage"`
-	SalePrice          float32   `db:"sale_price"`
-	AvailableQuantity  int32     `db:"available_quantity"`
-	OriginalStock      int32     `db:"original_stock"`
-	CreatedAt          time.Time `db:"created_at"`
-	UpdatedAt          time.Time `db:"updated_at"`
-	DeletedAt          int64     `db:"deleted_at"`
+	Id                 string        `db:"id"`
+	EventId            string        `db:"event_id"`
+	ProductId          string        `db:"product_id"`
+	DiscountPercentage money.Percent `db:"discount_percentage"`
+	SalePrice          money.Amount  `db:"sale_price"`
+	AvailableQuantity  int32         `db:"available_quantity"`
+	OriginalStock      int32         `db:"original_stock"`
+	CreatedAt          time.Time     `db:"created_at"`
+	UpdatedAt          time.Time     `db:"updated_at"`
+	DeletedAt          int64         `db:"deleted_at"`
+}
+
+// FlashSaleStock is the live stock of a flash sale event product together with the end of its event.
+type FlashSaleStock struct {
+	FlashSaleEventProductId string    `db:"flash_sale_event_product_id"`
+	AvailableQuantity       int32     `db:"available_quantity"`
+	EndTime                 time.Time `db:"end_time"`
+}
+
+// StockHold represents flash sale units set aside for a basket item until it is checked out or the hold expires.
+type StockHold struct {
+	Id                      string    `db:"id"`
+	BasketItemId            string    `db:"basket_item_id"`
+	FlashSaleEventProductId string    `db:"flash_sale_event_product_id"`
+	Quantity                int32     `db:"quantity"`
+	Status                  string    `db:"status"` // Possible values: 'ACTIVE', 'RELEASED', 'CONSUMED'
+	ExpiresAt               time.Time `db:"expires_at"`
+	CreatedAt               time.Time `db:"created_at"`
+	UpdatedAt               time.Time `db:"updated_at"`
+}
+
+// OrderStatusChange represents one entry of an order's status history.
+type OrderStatusChange struct {
+	Id         string    `db:"id"`
+	OrderId    string    `db:"order_id"`
+	FromStatus string    `db:"from_status"` // Empty for the status the order was created with
+	ToStatus   string    `db:"to_status"`
+	Actor      string    `db:"actor"`
+	Reason     string    `db:"reason"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// Coupon represents a promo code model for the database.
+type Coupon struct {
+	Id             string        `db:"id"`
+	Code           string        `db:"code"`
+	Effect         string        `db:"effect"` // Possible values: 'PERCENTAGE', 'FIXED_AMOUNT', 'FREE_SHIPPING'
+	Percentage     money.Percent `db:"percentage"`
+	Amount         money.Amount  `db:"amount"`
+	MinBasketValue money.Amount  `db:"min_basket_value"`
+	CurrencyCode   string        `db:"currency_code"`
+	MaxUses        int32         `db:"max_uses"`
+	MaxUsesPerUser int32         `db:"max_uses_per_user"`
+	TimesUsed      int32         `db:"times_used"`
+	StartsAt       time.Time     `db:"starts_at"`
+	EndsAt         time.Time     `db:"ends_at"`
+	IsActive       bool          `db:"is_active"`
+	CreatedAt      time.Time     `db:"created_at"`
+	UpdatedAt      time.Time     `db:"updated_at"`
+	DeletedAt      int64         `db:"deleted_at"`
+}
+
+// OrderPromotion is a coupon redeemed against an order.
+type OrderPromotion struct {
+	Id           string       `db:"id"`
+	OrderId      string       `db:"order_id"`
+	CouponId     string       `db:"coupon_id"`
+	UserId       string       `db:"user_id"`
+	Code         string       `db:"code"`
+	Effect       string       `db:"effect"`
+	Discount     money.Amount `db:"discount"`
+	CurrencyCode string       `db:"currency_code"`
+	CreatedAt    time.Time    `db:"created_at"`
+}
+
+// Address represents an address in a client's address book.
+type Address struct {
+	Id             string    `db:"id"`
+	ClientId       string    `db:"client_id"`
+	Label          string    `db:"label"`
+	Street         string    `db:"street"`
+	City           string    `db:"city"`
+	PostalCode     string    `db:"postal_code"`
+	Notes          string    `db:"notes"`
+	RecipientPhone string    `db:"recipient_phone"`
+	Latitude       float64   `db:"latitude"`
+	Longitude      float64   `db:"longitude"`
+	CreatedAt      time.Time `db:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at"`
+	DeletedAt      int64     `db:"deleted_at"`
+}
+
+// OutboxEvent represents a domain event waiting in the outbox to be published.
+type OutboxEvent struct {
+	Id            int64     `db:"id"`
+	EventId       string    `db:"event_id"`
+	Topic         string    `db:"topic"`
+	AggregateType string    `db:"aggregate_type"` // Possible values: 'order', 'basket'
+	AggregateId   string    `db:"aggregate_id"`
+	Payload       []byte    `db:"payload"`
+	CreatedAt     time.Time `db:"created_at"`
 }
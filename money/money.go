@@ -0,0 +1,211 @@
+// Package money represents sums of money exactly, as integer counts of minor units.
+//
+// Rounding rules:
+//   - Every currency is assumed to have two decimal places, so an Amount counts cents.
+//     Currencies with any other number are rejected by currency.IsValidCode.
+//   - Decimal prices with more than two decimal places are rounded half away from zero
+//     to the nearest cent when they are parsed ("0.125" becomes 0.13, "-0.125" becomes -0.13).
+//   - A percentage discount is rounded the same way to the nearest cent before it is
+//     taken off the price, so the customer never pays a fraction of a cent.
+//   - Discounts never take a price below zero.
+//   - Tax is rounded half away from zero to the nearest cent, once per order item.
+//   - Exchange rates have eight decimal places. A converted amount is rounded half away
+//     from zero to the nearest cent; prices are converted per unit, before promotions
+//     and quantities are applied.
+//   - Line totals, refunds and order totals are sums and products of whole cents and
+//     are never rounded.
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the ISO 4217 code of the currency prices are kept in.
+const DefaultCurrency = "USD"
+
+// ErrInvalidAmount is returned for a string that is not a decimal number.
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// Number of decimal places of an Amount, a Percent and a Rate.
+const (
+	amountScale = 2
+	rateScale   = 8
+)
+
+// Amount is a sum of money in minor units (cents).
+type Amount int64
+
+// Parse reads a decimal number of major units, such as "19.99", rounding it half away
+// from zero to the nearest cent.
+func Parse(s string) (Amount, error) {
+	v, err := parseDecimal(s, amountScale)
+	if err != nil {
+		return 0, err
+	}
+	return Amount(v), nil
+}
+
+// Times returns the amount multiplied by a quantity.
+func (a Amount) Times(quantity int32) Amount {
+	return a * Amount(quantity)
+}
+
+// PercentOff returns the amount left after taking p off it. The discount is rounded
+// half away from zero to the nearest cent.
+func (a Amount) PercentOff(p Percent) Amount {
+	return a.Minus(a.Percentage(p))
+}
+
+// Percentage returns p of the amount, rounded half away from zero to the nearest cent.
+func (a Amount) Percentage(p Percent) Amount {
+	return Amount(divRound(int64(a)*int64(p), 100*100))
+}
+
+// IncludedPercentage returns the part of the amount that is a p surcharge on a smaller
+// amount, such as the tax included in a gross price, rounded the same way.
+func (a Amount) IncludedPercentage(p Percent) Amount {
+	return a - Amount(divRound(int64(a)*100*100, 100*100+int64(p)))
+}
+
+// Share returns the part of the amount that falls to part out of whole, rounded half away
+// from zero to the nearest cent. whole must be positive.
+func (a Amount) Share(part, whole int64) Amount {
+	return Amount(divRound(int64(a)*part, whole))
+}
+
+// Minus returns a-b, or zero when b is larger than a.
+func (a Amount) Minus(b Amount) Amount {
+	if b > a {
+		return 0
+	}
+	return a - b
+}
+
+// String formats the amount in major units, such as "19.99".
+func (a Amount) String() string {
+	sign := ""
+	v := int64(a)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/100, v%100)
+}
+
+// Percent is a percentage in hundredths of a percent, so 1250 is 12.5%.
+type Percent int64
+
+// ParsePercent reads a decimal percentage, such as "12.5", rounding it half away from
+// zero to the nearest hundredth of a percent.
+func ParsePercent(s string) (Percent, error) {
+	v, err := parseDecimal(s, amountScale)
+	if err != nil {
+		return 0, err
+	}
+	return Percent(v), nil
+}
+
+// String formats the percentage as a decimal, such as "12.50".
+func (p Percent) String() string {
+	return Amount(p).String()
+}
+
+// Rate is an exchange rate in units of 10^-8, so 1.5 is 150000000.
+type Rate int64
+
+// OneToOne is the rate between a currency and itself.
+const OneToOne Rate = 100000000
+
+// ParseRate reads a decimal exchange rate, such as "0.92", rounding it half away from
+// zero to eight decimal places.
+func ParseRate(s string) (Rate, error) {
+	v, err := parseDecimal(s, rateScale)
+	if err != nil {
+		return 0, err
+	}
+	return Rate(v), nil
+}
+
+// String formats the rate as a decimal with eight decimal places, such as "0.92000000".
+func (r Rate) String() string {
+	sign := ""
+	v := int64(r)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%08d", sign, v/int64(OneToOne), v%int64(OneToOne))
+}
+
+// Convert returns the amount in another currency at rate r, rounded half away from zero
+// to the nearest cent.
+func (a Amount) Convert(r Rate) Amount {
+	n := new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(int64(r)))
+	d := big.NewInt(int64(OneToOne))
+	q, m := new(big.Int).QuoRem(n, d, new(big.Int))
+	// Round away from zero when the remainder is at least half of the divisor
+	if m.Abs(m).Mul(m, big.NewInt(2)).Cmp(d) >= 0 {
+		if n.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return Amount(q.Int64())
+}
+
+// parseDecimal reads a decimal number and returns it multiplied by 10^scale, rounded
+// half away from zero.
+func parseDecimal(s string, scale int) (int64, error) {
+	str := strings.TrimSpace(s)
+	negative := false
+	switch {
+	case strings.HasPrefix(str, "-"):
+		negative = true
+		str = str[1:]
+	case strings.HasPrefix(str, "+"):
+		str = str[1:]
+	}
+
+	whole, frac, _ := strings.Cut(str, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	for _, r := range whole + frac {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+		}
+	}
+
+	// Round on the first dropped digit; anything after it cannot change the result.
+	roundUp := false
+	if len(frac) > scale {
+		roundUp = frac[scale] >= '5'
+		frac = frac[:scale]
+	}
+	frac += strings.Repeat("0", scale-len(frac))
+
+	v, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if roundUp {
+		v++
+	}
+	if negative {
+		v = -v
+	}
+	return v, nil
+}
+
+// divRound divides n by d, rounding half away from zero. d must be positive.
+func divRound(n, d int64) int64 {
+	if n < 0 {
+		return -((-n + d/2) / d)
+	}
+	return (n + d/2) / d
+}
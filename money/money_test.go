@@ -0,0 +1,156 @@
+package money
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Amount
+		wantErr bool
+	}{
+		{"19.99", 1999, false},
+		{"20", 2000, false},
+		{"0.1", 10, false},
+		{".5", 50, false},
+		{"+3.00", 300, false},
+		{"-4.25", -425, false},
+
+		// Half away from zero on the third decimal place
+		{"0.125", 13, false},
+		{"0.124999", 12, false},
+		{"0.115", 12, false},
+		{"-0.125", -13, false},
+		{"9.995", 1000, false},
+
+		// Floats printed with their full precision round to the cent they stand for
+		{"0.10000000149011612", 10, false},
+		{"19.989999771118164", 1999, false},
+
+		{"", 0, true},
+		{".", 0, true},
+		{"1,50", 0, true},
+		{"1.2.3", 0, true},
+		{"abc", 0, true},
+		{"1e3", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidAmount)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPercentOff(t *testing.T) {
+	tests := []struct {
+		price   Amount
+		percent string
+		want    Amount
+	}{
+		{1000, "10", 900},
+		{1999, "10", 1799},   // 1.999 off rounds to 2.00
+		{1999, "12.5", 1749}, // 2.49875 off rounds to 2.50
+		{1, "50", 0},         // half a cent off rounds up to a whole cent
+		{3, "50", 1},         // 1.5 cents off rounds to 2
+		{1000, "0", 1000},
+		{1000, "100", 0},
+		{1000, "150", 0}, // never below zero
+		{0, "10", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.price.String()+"-"+tt.percent+"%", func(t *testing.T) {
+			p, err := ParsePercent(tt.percent)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, tt.price.PercentOff(p))
+		})
+	}
+}
+
+func TestPercentage(t *testing.T) {
+	twelve, err := ParsePercent("12")
+	assert.NoError(t, err)
+	assert.Equal(t, Amount(120), Amount(1000).Percentage(twelve))
+	assert.Equal(t, Amount(2), Amount(13).Percentage(twelve)) // 1.56 cents rounds to 2
+
+	// 11.20 with 12% included is 10.00 plus 1.20 of tax
+	assert.Equal(t, Amount(120), Amount(1120).IncludedPercentage(twelve))
+	assert.Equal(t, Amount(107), Amount(999).IncludedPercentage(twelve)) // 999 / 1.12 = 891.96
+	assert.Equal(t, Amount(0), Amount(1000).IncludedPercentage(0))
+}
+
+func TestMinus(t *testing.T) {
+	assert.Equal(t, Amount(750), Amount(1000).Minus(250))
+	assert.Equal(t, Amount(0), Amount(1000).Minus(1000))
+	assert.Equal(t, Amount(0), Amount(1000).Minus(1500))
+}
+
+func TestShare(t *testing.T) {
+	assert.Equal(t, Amount(250), Amount(1000).Share(1, 4))
+	assert.Equal(t, Amount(333), Amount(1000).Share(1, 3))
+	assert.Equal(t, Amount(667), Amount(1000).Share(2, 3))
+	assert.Equal(t, Amount(0), Amount(1000).Share(0, 3))
+}
+
+func TestTimes(t *testing.T) {
+	assert.Equal(t, Amount(5997), Amount(1999).Times(3))
+	assert.Equal(t, Amount(0), Amount(1999).Times(0))
+}
+
+func TestString(t *testing.T) {
+	assert.Equal(t, "19.99", Amount(1999).String())
+	assert.Equal(t, "0.05", Amount(5).String())
+	assert.Equal(t, "0.00", Amount(0).String())
+	assert.Equal(t, "-4.25", Amount(-425).String())
+	assert.Equal(t, "12.50", Percent(1250).String())
+}
+
+func TestParseRate(t *testing.T) {
+	r, err := ParseRate("0.92")
+	assert.NoError(t, err)
+	assert.Equal(t, Rate(92000000), r)
+	assert.Equal(t, "0.92000000", r.String())
+
+	r, err = ParseRate("1.234567895")
+	assert.NoError(t, err)
+	assert.Equal(t, Rate(123456790), r)
+
+	_, err = ParseRate("one")
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		amount Amount
+		rate   string
+		want   Amount
+	}{
+		{1999, "1", 1999},
+		{1999, "0.92", 1839},  // 18.3908 rounds down
+		{1000, "0.9215", 922}, // 9.215 rounds half away from zero
+		{1000, "0.9214", 921},
+		{-1000, "0.9215", -922},
+		{1, "0.5", 1}, // half a cent rounds up
+		{1, "0.49999999", 0},
+		{1999, "151.37", 302589}, // 3025.8863 rounds up
+		{0, "0.92", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount.String()+"@"+tt.rate, func(t *testing.T) {
+			r, err := ParseRate(tt.rate)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, tt.amount.Convert(r))
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// Package orderstatus defines the lifecycle of an order and the status changes allowed within it.
+package orderstatus
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Order statuses.
+const (
+	Pending    = "PENDING"
+	Processing = "PROCESSING"
+	Shipped    = "SHIPPED"
+	Delivered  = "DELIVERED"
+	Cancelled  = "CANCELLED"
+)
+
+var (
+	// ErrUnknownStatus is returned for a status that is not part of the lifecycle.
+	ErrUnknownStatus = errors.New("unknown order status")
+
+	// ErrInvalidTransition is returned when an order cannot move from its status to the requested one.
+	ErrInvalidTransition = errors.New("invalid order status transition")
+
+	// ErrInvalidInitialStatus is returned when an order is created in a status other than PENDING.
+	ErrInvalidInitialStatus = errors.New("invalid initial order status")
+)
+
+// transitions lists the statuses each status can move to. DELIVERED and CANCELLED are final.
+var transitions = map[string][]string{
+	Pending:    {Processing, Cancelled},
+	Processing: {Shipped, Cancelled},
+	Shipped:    {Delivered},
+	Delivered:  {},
+	Cancelled:  {},
+}
+
+// IsValid reports whether status is part of the order lifecycle.
+func IsValid(status string) bool {
+	_, ok := transitions[status]
+	return ok
+}
+
+// CanTransition reports whether an order can move from one status to another.
+// Staying in the same status is always allowed.
+func CanTransition(from, to string) bool {
+	return Transition(from, to) == nil
+}
+
+// Transition checks the move from one status to another and explains why it is refused.
+func Transition(from, to string) error {
+	if !IsValid(from) {
+		return fmt.Errorf("%w: %q", ErrUnknownStatus, from)
+	}
+	if !IsValid(to) {
+		return fmt.Errorf("%w: %q", ErrUnknownStatus, to)
+	}
+	if from == to {
+		return nil
+	}
+
+	for _, next := range transitions[from] {
+		if next == to {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, from, to)
+}
+
+// IsFinal reports whether no further status change is possible.
+func IsFinal(status string) bool {
+	next, ok := transitions[status]
+	return ok && len(next) == 0
+}
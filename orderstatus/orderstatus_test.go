@@ -0,0 +1,52 @@
+package orderstatus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		wantErr  error
+	}{
+		{Pending, Processing, nil},
+		{Pending, Cancelled, nil},
+		{Processing, Shipped, nil},
+		{Processing, Cancelled, nil},
+		{Shipped, Delivered, nil},
+		{Pending, Pending, nil},
+		{Delivered, Delivered, nil},
+
+		{Pending, Shipped, ErrInvalidTransition},
+		{Pending, Delivered, ErrInvalidTransition},
+		{Shipped, Cancelled, ErrInvalidTransition},
+		{Delivered, Pending, ErrInvalidTransition},
+		{Cancelled, Pending, ErrInvalidTransition},
+		{Processing, Pending, ErrInvalidTransition},
+
+		{Processing, "SHIPED", ErrUnknownStatus},
+		{"", Pending, ErrUnknownStatus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+"->"+tt.to, func(t *testing.T) {
+			err := Transition(tt.from, tt.to)
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+				assert.True(t, CanTransition(tt.from, tt.to))
+				return
+			}
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.False(t, CanTransition(tt.from, tt.to))
+		})
+	}
+}
+
+func TestIsFinal(t *testing.T) {
+	assert.True(t, IsFinal(Delivered))
+	assert.True(t, IsFinal(Cancelled))
+	assert.False(t, IsFinal(Pending))
+	assert.False(t, IsFinal("SHIPED"))
+}
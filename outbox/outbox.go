@@ -0,0 +1,47 @@
+// Package outbox publishes the domain events the storage layer writes to its outbox table.
+//
+// Events are written in the same transaction as the change they describe, and a Relay
+// publishes them to Kafka afterwards. Delivery is at least once: an event is removed from
+// the outbox only after Kafka has acknowledged it, so a crash in between publishes it again.
+// Consumers can drop redeliveries by the event ID header.
+package outbox
+
+// Topics events are published to.
+const (
+	// TopicOrderCreated carries an order_service.OrderCreated.
+	TopicOrderCreated = "order.created"
+	// TopicOrderStatusChanged carries an order_service.OrderStatusChanged.
+	TopicOrderStatusChanged = "order.status_changed"
+	// TopicBasketCheckedOut carries an order_service.BasketCheckedOut.
+	TopicBasketCheckedOut = "basket.checked_out"
+)
+
+// Aggregate types; messages are keyed by the ID of their aggregate.
+const (
+	AggregateOrder  = "order"
+	AggregateBasket = "basket"
+)
+
+// Message headers.
+const (
+	// HeaderEventID holds the ID of the event, the same on every redelivery.
+	HeaderEventID = "event-id"
+	// HeaderAggregateType holds the type of the aggregate the event is about.
+	HeaderAggregateType = "aggregate-type"
+	// HeaderContentType holds how the message value is encoded, one of the content types below.
+	HeaderContentType = "content-type"
+	// HeaderSchemaVersion holds the version of the schema the message value follows.
+	HeaderSchemaVersion = "schema-version"
+)
+
+// Content types of message values.
+const (
+	// ContentTypeProtobuf is the binary protobuf encoding.
+	ContentTypeProtobuf = "application/x-protobuf"
+	// ContentTypeProtoJSON is the protojson encoding.
+	ContentTypeProtoJSON = "application/json"
+)
+
+// SchemaVersion is the version of the schemas of the messages this service publishes. It goes
+// up when a message changes in a way older consumers cannot read.
+const SchemaVersion = 1
@@ -0,0 +1,100 @@
+package outbox
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/flash_sale/flash_sale_order_service/models"
+	"github.com/flash_sale/flash_sale_order_service/storage"
+	"github.com/segmentio/kafka-go"
+)
+
+// Publisher writes messages to Kafka. *kafka.Writer is one.
+type Publisher interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// NewWriter returns a Kafka writer for a Relay. Messages go to the partition of their key and
+// are only acknowledged once every in-sync replica has them.
+func NewWriter(brokers []string) *kafka.Writer {
+	return &kafka.Writer{
+		Addr:                   kafka.TCP(brokers...),
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireAll,
+		AllowAutoTopicCreation: true,
+	}
+}
+
+// Relay publishes the events in the outbox to Kafka.
+type Relay struct {
+	outbox    storage.OutboxI
+	publisher Publisher
+	interval  time.Duration
+	batchSize int
+}
+
+// NewRelay creates a new Relay instance.
+func NewRelay(outbox storage.OutboxI, publisher Publisher, interval time.Duration, batchSize int) *Relay {
+	return &Relay{
+		outbox:    outbox,
+		publisher: publisher,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Run empties the outbox every interval until ctx is done.
+func (r *Relay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		if _, err := r.Flush(ctx); err != nil {
+			log.Printf("failed to publish outbox events: %v", err)
+		}
+	}
+}
+
+// Flush publishes batches of events until the outbox is empty, returning how many were published.
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		published, err := r.outbox.PublishOutboxEvents(ctx, r.batchSize, r.publish)
+		total += published
+		if err != nil || published < r.batchSize {
+			return total, err
+		}
+	}
+}
+
+func (r *Relay) publish(ctx context.Context, events []models.OutboxEvent) error {
+	messages := make([]kafka.Message, 0, len(events))
+	for _, event := range events {
+		messages = append(messages, makeMessage(event))
+	}
+	return r.publisher.WriteMessages(ctx, messages...)
+}
+
+// makeMessage turns an event into the Kafka message it is published as.
+func makeMessage(event models.OutboxEvent) kafka.Message {
+	return kafka.Message{
+		Topic: event.Topic,
+		Key:   []byte(event.AggregateId),
+		Value: event.Payload,
+		Headers: []kafka.Header{
+			{Key: HeaderEventID, Value: []byte(event.EventId)},
+			{Key: HeaderAggregateType, Value: []byte(event.AggregateType)},
+			{Key: HeaderContentType, Value: []byte(ContentTypeProtoJSON)},
+			{Key: HeaderSchemaVersion, Value: []byte(strconv.Itoa(SchemaVersion))},
+		},
+		Time: event.CreatedAt,
+	}
+}
@@ -0,0 +1,102 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/flash_sale/flash_sale_order_service/models"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryOutbox is an outbox kept in a slice.
+type memoryOutbox struct {
+	events []models.OutboxEvent
+}
+
+func (o *memoryOutbox) PublishOutboxEvents(ctx context.Context, limit int, publish func(ctx context.Context, events []models.OutboxEvent) error) (int, error) {
+	batch := o.events[:min(limit, len(o.events))]
+	if len(batch) == 0 {
+		return 0, nil
+	}
+	if err := publish(ctx, batch); err != nil {
+		return 0, err
+	}
+	o.events = o.events[len(batch):]
+	return len(batch), nil
+}
+
+// recordingPublisher keeps the messages written to it, failing every write while err is set.
+type recordingPublisher struct {
+	messages []kafka.Message
+	err      error
+}
+
+func (p *recordingPublisher) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.messages = append(p.messages, msgs...)
+	return nil
+}
+
+func newEvents(n int) []models.OutboxEvent {
+	events := make([]models.OutboxEvent, 0, n)
+	for i := 1; i <= n; i++ {
+		events = append(events, models.OutboxEvent{
+			Id:            int64(i),
+			EventId:       fmt.Sprintf("event-%d", i),
+			Topic:         TopicOrderStatusChanged,
+			AggregateType: AggregateOrder,
+			AggregateId:   fmt.Sprintf("order-%d", i%2),
+			Payload:       []byte(fmt.Sprintf(`{"n": %d}`, i)),
+			CreatedAt:     time.Unix(int64(i), 0),
+		})
+	}
+	return events
+}
+
+func TestRelayFlush(t *testing.T) {
+	store := &memoryOutbox{events: newEvents(5)}
+	publisher := &recordingPublisher{}
+	relay := NewRelay(store, publisher, time.Second, 2)
+
+	published, err := relay.Flush(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 5, published)
+	assert.Empty(t, store.events)
+
+	// Published in the order they were written, keyed by their aggregate
+	assert.Len(t, publisher.messages, 5)
+	for i, msg := range publisher.messages {
+		assert.Equal(t, TopicOrderStatusChanged, msg.Topic)
+		assert.Equal(t, fmt.Sprintf("order-%d", (i+1)%2), string(msg.Key))
+		assert.Equal(t, fmt.Sprintf(`{"n": %d}`, i+1), string(msg.Value))
+		assert.Equal(t, []kafka.Header{
+			{Key: HeaderEventID, Value: []byte(fmt.Sprintf("event-%d", i+1))},
+			{Key: HeaderAggregateType, Value: []byte(AggregateOrder)},
+			{Key: HeaderContentType, Value: []byte(ContentTypeProtoJSON)},
+			{Key: HeaderSchemaVersion, Value: []byte("1")},
+		}, msg.Headers)
+	}
+}
+
+func TestRelayFlushKeepsRefusedEvents(t *testing.T) {
+	store := &memoryOutbox{events: newEvents(3)}
+	publisher := &recordingPublisher{err: errors.New("leader not available")}
+	relay := NewRelay(store, publisher, time.Second, 10)
+
+	published, err := relay.Flush(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 0, published)
+	assert.Len(t, store.events, 3)
+
+	// The next round publishes them
+	publisher.err = nil
+	published, err = relay.Flush(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 3, published)
+}
@@ -0,0 +1,357 @@
+// Package pricing works out what basket lines cost once every promotion they are eligible
+// for has been weighed against the others.
+//
+// Promotions are applied in order of the priority of their kind. A promotion is only
+// applied when its kind stacks with every kind already applied to the same line, in both
+// directions; otherwise it is skipped and the breakdown says why. Line promotions change the
+// unit price; basket promotions, such as coupons, come off the total of the lines whose
+// promotions they stack with.
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/flash_sale/flash_sale_order_service/money"
+)
+
+// Kind is where a promotion comes from.
+type Kind string
+
+// Promotion kinds.
+const (
+	FlashSale Kind = "FLASH_SALE"
+	Discount  Kind = "DISCOUNT"
+	Coupon    Kind = "COUPON"
+)
+
+// Effect is what a promotion does to a price.
+type Effect string
+
+// Promotion effects.
+const (
+	// FixedPrice sets the unit price to Promotion.Price, unless it is already lower.
+	FixedPrice Effect = "FIXED_PRICE"
+	// Percentage takes Promotion.Percent off.
+	Percentage Effect = "PERCENTAGE"
+	// FixedAmount takes Promotion.Amount off each unit of a line, or once off a basket.
+	FixedAmount Effect = "FIXED_AMOUNT"
+	// FreeShipping takes nothing off the items.
+	FreeShipping Effect = "FREE_SHIPPING"
+)
+
+// Promotion is a price reduction a line or a basket may be eligible for.
+type Promotion struct {
+	ID      string
+	Kind    Kind
+	Effect  Effect
+	Price   money.Amount
+	Percent money.Percent
+	Amount  money.Amount
+
+	// Ineligible says why the promotion cannot be applied at all, such as a flash sale
+	// that has ended. It is reported in the breakdown instead of being dropped silently.
+	Ineligible string
+}
+
+// Rule says when promotions of one kind are applied and what they may be combined with.
+type Rule struct {
+	// Priority orders the kinds; lower values are applied first.
+	Priority int `json:"priority"`
+	// StacksWith lists the kinds this one may be combined with, itself included if two
+	// promotions of the kind may apply together.
+	StacksWith []Kind `json:"stacks_with"`
+}
+
+// Rules holds the rule of each kind. Kinds without a rule are applied last and stack
+// with nothing.
+type Rules map[Kind]Rule
+
+// DefaultRules lets a line have either a flash sale or a product discount, the flash sale
+// winning, with any number of coupons on top.
+func DefaultRules() Rules {
+	return Rules{
+		FlashSale: {Priority: 10, StacksWith: []Kind{Coupon}},
+		Discount:  {Priority: 20, StacksWith: []Kind{Coupon}},
+		Coupon:    {Priority: 30, StacksWith: []Kind{FlashSale, Discount, Coupon}},
+	}
+}
+
+// LoadRules reads Rules from a JSON file, e.g.
+//
+//	{"FLASH_SALE": {"priority": 10, "stacks_with": ["DISCOUNT", "COUPON"]}}
+func LoadRules(path string) (Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pricing rules file: %w", err)
+	}
+
+	var rules Rules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse pricing rules file: %w", err)
+	}
+	for kind, rule := range rules {
+		if !isKnownKind(kind) {
+			return nil, fmt.Errorf("pricing rules file has an unknown promotion kind %q", kind)
+		}
+		for _, other := range rule.StacksWith {
+			if !isKnownKind(other) {
+				return nil, fmt.Errorf("pricing rules file has an unknown promotion kind %q", other)
+			}
+		}
+	}
+
+	return rules, nil
+}
+
+func isKnownKind(kind Kind) bool {
+	return kind == FlashSale || kind == Discount || kind == Coupon
+}
+
+func (r Rules) priority(kind Kind) int {
+	rule, ok := r[kind]
+	if !ok {
+		return math.MaxInt
+	}
+	return rule.Priority
+}
+
+// stacks reports whether promotions of kinds a and b may be combined.
+func (r Rules) stacks(a, b Kind) bool {
+	return r.lists(a, b) && r.lists(b, a)
+}
+
+func (r Rules) lists(kind, other Kind) bool {
+	for _, k := range r[kind].StacksWith {
+		if k == other {
+			return true
+		}
+	}
+	return false
+}
+
+// Step is one promotion in a pricing breakdown.
+type Step struct {
+	PromotionID string
+	Kind        Kind
+	Effect      Effect
+	Applied     bool
+	// Discount is what the promotion took off the line total or the basket.
+	Discount money.Amount
+	// Reason says why a promotion was not applied.
+	Reason string
+}
+
+// Line is a basket line with the promotions it is eligible for.
+type Line struct {
+	ID         string
+	BasePrice  money.Amount
+	Quantity   int32
+	Promotions []Promotion
+}
+
+// LineResult is the price of a line and how it was reached.
+type LineResult struct {
+	ID        string
+	BasePrice money.Amount
+	UnitPrice money.Amount
+	Quantity  int32
+	Total     money.Amount
+	Steps     []Step
+}
+
+// Savings returns what the line promotions took off the line at base price.
+func (l LineResult) Savings() money.Amount {
+	return l.BasePrice.Times(l.Quantity) - l.Total
+}
+
+// Applied returns the kinds of the promotions applied to the line.
+func (l LineResult) Applied() []Kind {
+	var kinds []Kind
+	for _, step := range l.Steps {
+		if step.Applied {
+			kinds = append(kinds, step.Kind)
+		}
+	}
+	return kinds
+}
+
+// Has reports whether a promotion of kind was applied to the line.
+func (l LineResult) Has(kind Kind) bool {
+	for _, step := range l.Steps {
+		if step.Applied && step.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Result is the price of a basket.
+type Result struct {
+	Lines []LineResult
+	// Subtotal is the sum of the line totals, before basket promotions.
+	Subtotal money.Amount
+	// Discount is what the basket promotions took off the subtotal.
+	Discount     money.Amount
+	Total        money.Amount
+	FreeShipping bool
+	// Steps is the breakdown of the basket promotions.
+	Steps []Step
+}
+
+// Engine prices lines and baskets under a set of Rules.
+type Engine struct {
+	rules Rules
+}
+
+// NewEngine returns an Engine applying rules.
+func NewEngine(rules Rules) *Engine {
+	return &Engine{
+		rules: rules,
+	}
+}
+
+// PriceLine works out the unit price of a line from its base price and promotions.
+func (e *Engine) PriceLine(line Line) LineResult {
+	result := LineResult{
+		ID:        line.ID,
+		BasePrice: line.BasePrice,
+		UnitPrice: line.BasePrice,
+		Quantity:  line.Quantity,
+	}
+
+	var applied []Kind
+	for _, promotion := range e.sorted(line.Promotions) {
+		step := Step{PromotionID: promotion.ID, Kind: promotion.Kind, Effect: promotion.Effect}
+		if step.Reason = e.skipReason(promotion, applied); step.Reason != "" {
+			result.Steps = append(result.Steps, step)
+			continue
+		}
+
+		unitPrice := result.UnitPrice
+		switch promotion.Effect {
+		case FixedPrice:
+			if promotion.Price < unitPrice {
+				unitPrice = promotion.Price
+			}
+		case Percentage:
+			unitPrice = unitPrice.PercentOff(promotion.Percent)
+		case FixedAmount:
+			unitPrice = unitPrice.Minus(promotion.Amount)
+		case FreeShipping:
+		default:
+			step.Reason = fmt.Sprintf("unknown effect %q", promotion.Effect)
+			result.Steps = append(result.Steps, step)
+			continue
+		}
+
+		step.Applied = true
+		step.Discount = (result.UnitPrice - unitPrice).Times(line.Quantity)
+		result.UnitPrice = unitPrice
+		applied = append(applied, promotion.Kind)
+		result.Steps = append(result.Steps, step)
+	}
+
+	result.Total = result.UnitPrice.Times(line.Quantity)
+	return result
+}
+
+// Price prices every line, then takes the basket promotions off them.
+func (e *Engine) Price(lines []Line, promotions []Promotion) Result {
+	priced := make([]LineResult, 0, len(lines))
+	for _, line := range lines {
+		priced = append(priced, e.PriceLine(line))
+	}
+	return e.PriceBasket(priced, promotions)
+}
+
+// PriceBasket takes the basket promotions off lines that are already priced. Each promotion
+// only counts the lines whose own promotions it stacks with, and its discount is taken off
+// those lines in order, so each later promotion sees what the ones before it left.
+func (e *Engine) PriceBasket(lines []LineResult, promotions []Promotion) Result {
+	result := Result{Lines: lines}
+	remaining := make([]money.Amount, len(lines))
+	for i, line := range lines {
+		result.Subtotal += line.Total
+		remaining[i] = line.Total
+	}
+
+	var applied []Kind
+	for _, promotion := range e.sorted(promotions) {
+		step := Step{PromotionID: promotion.ID, Kind: promotion.Kind, Effect: promotion.Effect}
+		if step.Reason = e.skipReason(promotion, applied); step.Reason != "" {
+			result.Steps = append(result.Steps, step)
+			continue
+		}
+
+		// Only the lines whose own promotions stack with this one count towards it
+		var eligible []int
+		var base money.Amount
+		for i, line := range result.Lines {
+			if e.skipReason(promotion, line.Applied()) == "" {
+				eligible = append(eligible, i)
+				base += remaining[i]
+			}
+		}
+		if len(eligible) == 0 && len(lines) > 0 {
+			step.Reason = fmt.Sprintf("%s does not stack with the promotions on any line", promotion.Kind)
+			result.Steps = append(result.Steps, step)
+			continue
+		}
+
+		var discount money.Amount
+		switch promotion.Effect {
+		case Percentage:
+			discount = base - base.PercentOff(promotion.Percent)
+		case FixedAmount:
+			discount = base - base.Minus(promotion.Amount)
+		case FreeShipping:
+			result.FreeShipping = true
+		default:
+			step.Reason = fmt.Sprintf("%s cannot apply to a whole basket", promotion.Effect)
+			result.Steps = append(result.Steps, step)
+			continue
+		}
+
+		left := discount
+		for _, i := range eligible {
+			taken := remaining[i] - remaining[i].Minus(left)
+			remaining[i] -= taken
+			left -= taken
+		}
+
+		step.Applied = true
+		step.Discount = discount
+		result.Discount += discount
+		applied = append(applied, promotion.Kind)
+		result.Steps = append(result.Steps, step)
+	}
+
+	result.Total = result.Subtotal - result.Discount
+	return result
+}
+
+// sorted returns the promotions by priority, keeping the given order between equals.
+func (e *Engine) sorted(promotions []Promotion) []Promotion {
+	sorted := append([]Promotion(nil), promotions...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return e.rules.priority(sorted[i].Kind) < e.rules.priority(sorted[j].Kind)
+	})
+	return sorted
+}
+
+// skipReason says why promotion cannot join the already applied kinds, or returns "".
+func (e *Engine) skipReason(promotion Promotion, applied []Kind) string {
+	if promotion.Ineligible != "" {
+		return promotion.Ineligible
+	}
+	for _, kind := range applied {
+		if !e.rules.stacks(promotion.Kind, kind) {
+			return fmt.Sprintf("%s does not stack with %s", promotion.Kind, kind)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,192 @@
+package pricing
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/flash_sale/flash_sale_order_service/money"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	flashSale = Promotion{ID: "fs", Kind: FlashSale, Effect: FixedPrice, Price: 1800}
+	discount  = Promotion{ID: "d", Kind: Discount, Effect: Percentage, Percent: 1000} // 10%
+	tenOff    = Promotion{ID: "c10", Kind: Coupon, Effect: Percentage, Percent: 1000}
+	fiveOff   = Promotion{ID: "c5", Kind: Coupon, Effect: FixedAmount, Amount: 500}
+)
+
+func TestPriceLine(t *testing.T) {
+	engine := NewEngine(DefaultRules())
+
+	tests := []struct {
+		name       string
+		promotions []Promotion
+		wantUnit   money.Amount
+		wantSteps  []Step
+	}{
+		{
+			name:     "no promotions",
+			wantUnit: 2000,
+		},
+		{
+			name:       "flash sale",
+			promotions: []Promotion{flashSale},
+			wantUnit:   1800,
+			wantSteps: []Step{
+				{PromotionID: "fs", Kind: FlashSale, Effect: FixedPrice, Applied: true, Discount: 400},
+			},
+		},
+		{
+			name:       "flash sale wins over a product discount whatever the order",
+			promotions: []Promotion{discount, flashSale},
+			wantUnit:   1800,
+			wantSteps: []Step{
+				{PromotionID: "fs", Kind: FlashSale, Effect: FixedPrice, Applied: true, Discount: 400},
+				{PromotionID: "d", Kind: Discount, Effect: Percentage, Reason: "DISCOUNT does not stack with FLASH_SALE"},
+			},
+		},
+		{
+			name:       "ended flash sale falls back to the product discount",
+			promotions: []Promotion{withIneligible(flashSale, "flash sale has ended"), discount},
+			wantUnit:   1800,
+			wantSteps: []Step{
+				{PromotionID: "fs", Kind: FlashSale, Effect: FixedPrice, Reason: "flash sale has ended"},
+				{PromotionID: "d", Kind: Discount, Effect: Percentage, Applied: true, Discount: 400},
+			},
+		},
+		{
+			name:       "sale price above the base price changes nothing",
+			promotions: []Promotion{{ID: "fs", Kind: FlashSale, Effect: FixedPrice, Price: 2500}},
+			wantUnit:   2000,
+			wantSteps: []Step{
+				{PromotionID: "fs", Kind: FlashSale, Effect: FixedPrice, Applied: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := engine.PriceLine(Line{ID: "line", BasePrice: 2000, Quantity: 2, Promotions: tt.promotions})
+			assert.Equal(t, tt.wantUnit, got.UnitPrice)
+			assert.Equal(t, tt.wantUnit.Times(2), got.Total)
+			assert.Equal(t, tt.wantSteps, got.Steps)
+		})
+	}
+}
+
+func TestPriceLineWithStackingRules(t *testing.T) {
+	rules := DefaultRules()
+	rules[FlashSale] = Rule{Priority: 10, StacksWith: []Kind{Discount, Coupon}}
+	rules[Discount] = Rule{Priority: 20, StacksWith: []Kind{FlashSale, Coupon}}
+	engine := NewEngine(rules)
+
+	got := engine.PriceLine(Line{BasePrice: 2000, Quantity: 1, Promotions: []Promotion{discount, flashSale}})
+	assert.Equal(t, money.Amount(1620), got.UnitPrice) // 18.00, then 10% off
+	assert.Equal(t, []Kind{FlashSale, Discount}, got.Applied())
+	assert.True(t, got.Has(Discount))
+	assert.False(t, got.Has(Coupon))
+	assert.Equal(t, money.Amount(380), got.Savings())
+
+	// Stacking has to be allowed from both sides
+	rules[Discount] = Rule{Priority: 20, StacksWith: []Kind{Coupon}}
+	got = engine.PriceLine(Line{BasePrice: 2000, Quantity: 1, Promotions: []Promotion{discount, flashSale}})
+	assert.Equal(t, money.Amount(1800), got.UnitPrice)
+}
+
+func TestPrice(t *testing.T) {
+	engine := NewEngine(DefaultRules())
+
+	lines := []Line{
+		{ID: "regular", BasePrice: 1000, Quantity: 2},
+		{ID: "flash", BasePrice: 2000, Quantity: 1, Promotions: []Promotion{flashSale}},
+	}
+
+	got := engine.Price(lines, []Promotion{tenOff, fiveOff})
+	assert.Equal(t, money.Amount(3800), got.Subtotal)
+	assert.Equal(t, money.Amount(880), got.Discount) // 3.80, then 5.00 off the 34.20 left
+	assert.Equal(t, money.Amount(2920), got.Total)
+	assert.False(t, got.FreeShipping)
+	assert.Equal(t, []Step{
+		{PromotionID: "c10", Kind: Coupon, Effect: Percentage, Applied: true, Discount: 380},
+		{PromotionID: "c5", Kind: Coupon, Effect: FixedAmount, Applied: true, Discount: 500},
+	}, got.Steps)
+}
+
+func TestPriceSkipsLinesACouponDoesNotStackWith(t *testing.T) {
+	rules := DefaultRules()
+	rules[FlashSale] = Rule{Priority: 10}
+	engine := NewEngine(rules)
+
+	lines := []Line{
+		{ID: "regular", BasePrice: 1000, Quantity: 2},
+		{ID: "flash", BasePrice: 2000, Quantity: 1, Promotions: []Promotion{flashSale}},
+	}
+
+	got := engine.Price(lines, []Promotion{tenOff})
+	assert.Equal(t, money.Amount(200), got.Discount) // only the regular line counts
+	assert.Equal(t, money.Amount(3600), got.Total)
+
+	got = engine.Price(lines[1:], []Promotion{tenOff})
+	assert.Equal(t, money.Amount(0), got.Discount)
+	assert.Equal(t, "COUPON does not stack with the promotions on any line", got.Steps[0].Reason)
+}
+
+func TestPriceNeverGoesBelowZero(t *testing.T) {
+	engine := NewEngine(DefaultRules())
+
+	got := engine.Price([]Line{{BasePrice: 300, Quantity: 1}}, []Promotion{
+		fiveOff,
+		{ID: "ship", Kind: Coupon, Effect: FreeShipping},
+	})
+	assert.Equal(t, money.Amount(300), got.Discount)
+	assert.Equal(t, money.Amount(0), got.Total)
+	assert.True(t, got.FreeShipping)
+}
+
+func TestExclusiveCoupons(t *testing.T) {
+	rules := DefaultRules()
+	rules[Coupon] = Rule{Priority: 30, StacksWith: []Kind{FlashSale, Discount}}
+	engine := NewEngine(rules)
+
+	got := engine.Price([]Line{{BasePrice: 2000, Quantity: 1}}, []Promotion{tenOff, fiveOff})
+	assert.Equal(t, money.Amount(200), got.Discount)
+	assert.True(t, got.Steps[0].Applied)
+	assert.False(t, got.Steps[1].Applied)
+	assert.Equal(t, "COUPON does not stack with COUPON", got.Steps[1].Reason)
+}
+
+func TestLoadRules(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "rules.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{
+		"FLASH_SALE": {"priority": 10, "stacks_with": ["DISCOUNT", "COUPON"]},
+		"DISCOUNT": {"priority": 20, "stacks_with": ["FLASH_SALE"]}
+	}`), 0o600))
+
+	rules, err := LoadRules(path)
+	assert.NoError(t, err)
+	assert.Equal(t, Rules{
+		FlashSale: {Priority: 10, StacksWith: []Kind{Discount, Coupon}},
+		Discount:  {Priority: 20, StacksWith: []Kind{FlashSale}},
+	}, rules)
+
+	for name, content := range map[string]string{
+		"unknown kind":       `{"LOYALTY": {"priority": 1}}`,
+		"unknown stack kind": `{"COUPON": {"priority": 1, "stacks_with": ["LOYALTY"]}}`,
+		"not json":           `priority: 1`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, "bad.json")
+			assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+			_, err := LoadRules(path)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func withIneligible(p Promotion, reason string) Promotion {
+	p.Ineligible = reason
+	return p
+}
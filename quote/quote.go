@@ -0,0 +1,86 @@
+// Package quote signs price quotes, so prices frozen for a basket can be trusted when the
+// basket is checked out later.
+package quote
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+	"time"
+
+	"github.com/flash_sale/flash_sale_order_service/money"
+)
+
+// Line is the frozen price of one basket item.
+type Line struct {
+	BasketItemID string
+	Quantity     int32
+	BasePrice    money.Amount
+	UnitPrice    money.Amount
+	// Promotions lists the kinds of the promotions the unit price includes.
+	Promotions []string
+}
+
+// Quote freezes the prices of a basket until ExpiresAt.
+type Quote struct {
+	ID           string
+	BasketID     string
+	CurrencyCode string
+	Lines        []Line
+	Total        money.Amount
+	ExpiresAt    time.Time
+}
+
+// Expired reports whether the quote can no longer be honored at now.
+func (q Quote) Expired(now time.Time) bool {
+	return !now.Before(q.ExpiresAt)
+}
+
+// Signer issues and checks quote signatures with an HMAC-SHA256 key.
+type Signer struct {
+	key []byte
+	ttl time.Duration
+}
+
+// NewSigner returns a Signer for quotes that are valid for ttl.
+func NewSigner(key []byte, ttl time.Duration) *Signer {
+	return &Signer{
+		key: key,
+		ttl: ttl,
+	}
+}
+
+// ExpiresAt returns when a quote issued at now expires. It is whole seconds, which is
+// the precision the signature covers.
+func (s *Signer) ExpiresAt(now time.Time) time.Time {
+	return now.Add(s.ttl).UTC().Truncate(time.Second)
+}
+
+// Sign returns the hex encoded signature of a quote.
+func (s *Signer) Sign(q Quote) string {
+	mac := hmac.New(sha256.New, s.key)
+	writeQuote(mac, q)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature was issued for exactly this quote.
+func (s *Signer) Verify(q Quote, signature string) bool {
+	want, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, s.key)
+	writeQuote(mac, q)
+	return hmac.Equal(mac.Sum(nil), want)
+}
+
+// writeQuote writes every field of a quote in a fixed layout, one per line.
+func writeQuote(h hash.Hash, q Quote) {
+	fmt.Fprintf(h, "%s\n%s\n%s\n%d\n%d\n", q.ID, q.BasketID, q.CurrencyCode, q.Total, q.ExpiresAt.Unix())
+	for _, line := range q.Lines {
+		fmt.Fprintf(h, "%s|%d|%d|%d|%s\n", line.BasketItemID, line.Quantity, line.BasePrice, line.UnitPrice, strings.Join(line.Promotions, ","))
+	}
+}
@@ -0,0 +1,53 @@
+package quote
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	signer := NewSigner([]byte("secret"), 5*time.Minute)
+	now := time.Date(2024, 11, 11, 10, 0, 0, 500, time.UTC)
+
+	q := Quote{
+		ID:           "quote",
+		BasketID:     "basket",
+		CurrencyCode: "USD",
+		Lines: []Line{
+			{BasketItemID: "item-1", Quantity: 2, BasePrice: 1000, UnitPrice: 900, Promotions: []string{"DISCOUNT"}},
+			{BasketItemID: "item-2", Quantity: 1, BasePrice: 2000, UnitPrice: 1800, Promotions: []string{"FLASH_SALE"}},
+		},
+		Total:     3600,
+		ExpiresAt: signer.ExpiresAt(now),
+	}
+	assert.Equal(t, time.Date(2024, 11, 11, 10, 5, 0, 0, time.UTC), q.ExpiresAt)
+
+	signature := signer.Sign(q)
+	assert.Len(t, signature, 64)
+	assert.True(t, signer.Verify(q, signature))
+
+	// Any change to the quote breaks the signature
+	changed := q
+	changed.Lines = append([]Line(nil), q.Lines...)
+	changed.Lines[1].UnitPrice = 1
+	assert.False(t, signer.Verify(changed, signature))
+
+	changed = q
+	changed.ExpiresAt = q.ExpiresAt.Add(time.Hour)
+	assert.False(t, signer.Verify(changed, signature))
+
+	// So does another key or a malformed signature
+	assert.False(t, NewSigner([]byte("other"), time.Minute).Verify(q, signature))
+	assert.False(t, signer.Verify(q, "not hex"))
+}
+
+func TestExpired(t *testing.T) {
+	expiresAt := time.Date(2024, 11, 11, 10, 5, 0, 0, time.UTC)
+	q := Quote{ExpiresAt: expiresAt}
+
+	assert.False(t, q.Expired(expiresAt.Add(-time.Second)))
+	assert.True(t, q.Expired(expiresAt))
+	assert.True(t, q.Expired(expiresAt.Add(time.Second)))
+}
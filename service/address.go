@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+
+	"github.com/flash_sale/flash_sale_order_service/genproto/order_service"
+	"github.com/flash_sale/flash_sale_order_service/storage"
+)
+
+// AddressService implements the order_service.AddressServiceServer interface.
+type AddressService struct {
+	storage storage.StorageI
+	order_service.UnimplementedAddressServiceServer
+}
+
+// NewAddressService creates a new AddressService instance.
+func NewAddressService(storage storage.StorageI) *AddressService {
+	return &AddressService{
+		storage: storage,
+	}
+}
+
+// CreateAddress saves a new address to a client's address book.
+func (s *AddressService) CreateAddress(ctx context.Context, req *order_service.CreateAddressRequest) (*order_service.CreateAddressResponse, error) {
+	address, err := s.storage.Address().CreateAddress(ctx, req)
+	if err != nil {
+		return nil, wrapError(err, "failed to create address")
+	}
+
+	return &order_service.CreateAddressResponse{
+		Address: address,
+	}, nil
+}
+
+// GetAddress retrieves a saved address by its ID.
+func (s *AddressService) GetAddress(ctx context.Context, req *order_service.GetAddressRequest) (*order_service.GetAddressResponse, error) {
+	address, err := s.storage.Address().GetAddress(ctx, req)
+	if err != nil {
+		return nil, wrapError(err, "failed to get address")
+	}
+
+	return &order_service.GetAddressResponse{
+		Address: address,
+	}, nil
+}
+
+// UpdateAddress updates a saved address.
+func (s *AddressService) UpdateAddress(ctx context.Context, req *order_service.UpdateAddressRequest) (*order_service.UpdateAddressResponse, error) {
+	address, err := s.storage.Address().UpdateAddress(ctx, req)
+	if err != nil {
+		return nil, wrapError(err, "failed to update address")
+	}
+
+	return &order_service.UpdateAddressResponse{
+		Address: address,
+	}, nil
+}
+
+// DeleteAddress deletes a saved address by its ID.
+func (s *AddressService) DeleteAddress(ctx context.Context, req *order_service.DeleteAddressRequest) (*order_service.DeleteAddressResponse, error) {
+	response, err := s.storage.Address().DeleteAddress(ctx, req)
+	if err != nil {
+		return nil, wrapError(err, "failed to delete address")
+	}
+
+	return response, nil
+}
+
+// ListAddresses retrieves the address book of a client.
+func (s *AddressService) ListAddresses(ctx context.Context, req *order_service.ListAddressesRequest) (*order_service.ListAddressesResponse, error) {
+	response, err := s.storage.Address().ListAddresses(ctx, req)
+	if err != nil {
+		return nil, wrapError(err, "failed to list addresses")
+	}
+
+	return response, nil
+}
@@ -29,7 +29,7 @@ func NewBasketService(storage storage.StorageI, redisClient *redis.Client) *Bask
 func (s *BasketService) CreateBasket(ctx context.Context, req *order_service.CreateBasketRequest) (*order_service.CreateBasketResponse, error) {
 	basket, err := s.storage.Basket().CreateBasket(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create basket: %w", err)
+		return nil, wrapError(err, "failed to create basket")
 	}
 
 	return &order_service.CreateBasketResponse{
@@ -53,7 +53,7 @@ func (s *BasketService) GetBasket(ctx context.Context, req *order_service.GetBas
 func (s *BasketService) UpdateBasket(ctx context.Context, req *order_service.UpdateBasketRequest) (*order_service.UpdateBasketResponse, error) {
 	basket, err := s.storage.Basket().UpdateBasket(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update basket: %w", err)
+		return nil, wrapError(err, "failed to update basket")
 	}
 
 	return &order_service.UpdateBasketResponse{
@@ -85,7 +85,7 @@ func (s *BasketService) ListBaskets(ctx context.Context, req *order_service.List
 func (s *BasketService) UpdateBasketStatus(ctx context.Context, req *order_service.UpdateBasketStatusRequest) (*order_service.UpdateBasketStatusResponse, error) {
 	basket, err := s.storage.Basket().UpdateBasketStatus(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update basket status: %w", err)
+		return nil, wrapError(err, "failed to update basket status")
 	}
 
 	// Send notification to the user
@@ -99,3 +99,23 @@ func (s *BasketService) UpdateBasketStatus(ctx context.Context, req *order_servi
 		Basket: basket,
 	}, nil
 }
+
+// GetBasketSummary prices a basket at current prices.
+func (s *BasketService) GetBasketSummary(ctx context.Context, req *order_service.GetBasketSummaryRequest) (*order_service.GetBasketSummaryResponse, error) {
+	summary, err := s.storage.Basket().GetBasketSummary(ctx, req)
+	if err != nil {
+		return nil, wrapError(err, "failed to get basket summary")
+	}
+
+	return summary, nil
+}
+
+// QuoteBasket freezes the current prices of a basket for a while.
+func (s *BasketService) QuoteBasket(ctx context.Context, req *order_service.QuoteBasketRequest) (*order_service.QuoteBasketResponse, error) {
+	quote, err := s.storage.Basket().QuoteBasket(ctx, req)
+	if err != nil {
+		return nil, wrapError(err, "failed to quote basket")
+	}
+
+	return &order_service.QuoteBasketResponse{Quote: quote}, nil
+}
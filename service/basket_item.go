@@ -25,7 +25,7 @@ func NewBasketItemService(storage storage.StorageI) *BasketItemService {
 func (s *BasketItemService) CreateBasketItem(ctx context.Context, req *order_service.CreateBasketItemRequest) (*order_service.CreateBasketItemResponse, error) {
 	basketItem, err := s.storage.BasketItem().CreateBasketItem(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create basket item: %w", err)
+		return nil, wrapError(err, "failed to create basket item")
 	}
 
 	return &order_service.CreateBasketItemResponse{
@@ -55,6 +55,28 @@ func (s *BasketItemService) DeleteBasketItem(ctx context.Context, req *order_ser
 	return response, nil
 }
 
+// UpdateBasketItemQuantity changes the quantity of a basket item.
+func (s *BasketItemService) UpdateBasketItemQuantity(ctx context.Context, req *order_service.UpdateBasketItemQuantityRequest) (*order_service.UpdateBasketItemQuantityResponse, error) {
+	basketItem, err := s.storage.BasketItem().UpdateBasketItemQuantity(ctx, req)
+	if err != nil {
+		return nil, wrapError(err, "failed to update basket item quantity")
+	}
+
+	return &order_service.UpdateBasketItemQuantityResponse{
+		BasketItem: basketItem,
+	}, nil
+}
+
+// ClearBasketItems deletes every item of a basket.
+func (s *BasketItemService) ClearBasketItems(ctx context.Context, req *order_service.ClearBasketItemsRequest) (*order_service.ClearBasketItemsResponse, error) {
+	response, err := s.storage.BasketItem().ClearBasketItems(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clear basket items: %w", err)
+	}
+
+	return response, nil
+}
+
 // ListBasketItems retrieves a list of basket items.
 func (s *BasketItemService) ListBasketItems(ctx context.Context, req *order_service.ListBasketItemsRequest) (*order_service.ListBasketItemsResponse, error) {
 	response, err := s.storage.BasketItem().ListBasketItems(ctx, req)
@@ -64,3 +86,13 @@ func (s *BasketItemService) ListBasketItems(ctx context.Context, req *order_serv
 
 	return response, nil
 }
+
+// GetFlashSaleStock retrieves the available and reserved stock of a flash sale event product.
+func (s *BasketItemService) GetFlashSaleStock(ctx context.Context, req *order_service.GetFlashSaleStockRequest) (*order_service.GetFlashSaleStockResponse, error) {
+	response, err := s.storage.FlashSale().GetFlashSaleStock(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flash sale stock: %w", err)
+	}
+
+	return response, nil
+}
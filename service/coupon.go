@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flash_sale/flash_sale_order_service/genproto/order_service"
+	"github.com/flash_sale/flash_sale_order_service/storage"
+)
+
+// CouponService implements the order_service.CouponServiceServer interface.
+type CouponService struct {
+	storage storage.StorageI
+	order_service.UnimplementedCouponServiceServer
+}
+
+// NewCouponService creates a new CouponService instance.
+func NewCouponService(storage storage.StorageI) *CouponService {
+	return &CouponService{
+		storage: storage,
+	}
+}
+
+// CreateCoupon creates a new coupon.
+func (s *CouponService) CreateCoupon(ctx context.Context, req *order_service.CreateCouponRequest) (*order_service.CreateCouponResponse, error) {
+	coupon, err := s.storage.Coupon().CreateCoupon(ctx, req)
+	if err != nil {
+		return nil, wrapError(err, "failed to create coupon")
+	}
+
+	return &order_service.CreateCouponResponse{
+		Coupon: coupon,
+	}, nil
+}
+
+// GetCoupon retrieves a coupon by its ID.
+func (s *CouponService) GetCoupon(ctx context.Context, req *order_service.GetCouponRequest) (*order_service.GetCouponResponse, error) {
+	coupon, err := s.storage.Coupon().GetCoupon(ctx, req)
+	if err != nil {
+		return nil, wrapError(err, "failed to get coupon")
+	}
+
+	return &order_service.GetCouponResponse{
+		Coupon: coupon,
+	}, nil
+}
+
+// UpdateCoupon updates an existing coupon.
+func (s *CouponService) UpdateCoupon(ctx context.Context, req *order_service.UpdateCouponRequest) (*order_service.UpdateCouponResponse, error) {
+	coupon, err := s.storage.Coupon().UpdateCoupon(ctx, req)
+	if err != nil {
+		return nil, wrapError(err, "failed to update coupon")
+	}
+
+	return &order_service.UpdateCouponResponse{
+		Coupon: coupon,
+	}, nil
+}
+
+// DeleteCoupon deletes a coupon by its ID.
+func (s *CouponService) DeleteCoupon(ctx context.Context, req *order_service.DeleteCouponRequest) (*order_service.DeleteCouponResponse, error) {
+	response, err := s.storage.Coupon().DeleteCoupon(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete coupon: %w", err)
+	}
+
+	return response, nil
+}
+
+// ListCoupons retrieves a list of coupons.
+func (s *CouponService) ListCoupons(ctx context.Context, req *order_service.ListCouponsRequest) (*order_service.ListCouponsResponse, error) {
+	response, err := s.storage.Coupon().ListCoupons(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list coupons: %w", err)
+	}
+
+	return response, nil
+}
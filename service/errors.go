@@ -0,0 +1,55 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/flash_sale/flash_sale_order_service/currency"
+	"github.com/flash_sale/flash_sale_order_service/orderstatus"
+	"github.com/flash_sale/flash_sale_order_service/storage"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// wrapError prefixes err with msg. Errors the caller can act on are turned into a gRPC
+// status with a matching code; anything else is left for gRPC to report as Unknown.
+func wrapError(err error, msg string) error {
+	if code := ErrorCode(err); code != codes.Unknown {
+		return status.Errorf(code, "%s: %v", msg, err)
+	}
+
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// ErrorCode returns the gRPC code matching an error the caller can act on, or codes.Unknown
+// for any other error.
+func ErrorCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, storage.ErrSoldOut),
+		errors.Is(err, storage.ErrPurchaseLimitExceeded),
+		errors.Is(err, orderstatus.ErrInvalidTransition),
+		errors.Is(err, currency.ErrRateNotFound),
+		errors.Is(err, storage.ErrCouponNotApplicable),
+		errors.Is(err, storage.ErrQuoteExpired),
+		errors.Is(err, storage.ErrOutsideDeliveryZone):
+		return codes.FailedPrecondition
+	case errors.Is(err, orderstatus.ErrUnknownStatus),
+		errors.Is(err, orderstatus.ErrInvalidInitialStatus),
+		errors.Is(err, storage.ErrInvalidQuantity),
+		errors.Is(err, storage.ErrUnsupportedCurrency),
+		errors.Is(err, storage.ErrInvalidBasketStatus),
+		errors.Is(err, storage.ErrInvalidCoupon),
+		errors.Is(err, storage.ErrInvalidQuote),
+		errors.Is(err, storage.ErrInvalidCoordinates),
+		errors.Is(err, storage.ErrInvalidAddress),
+		errors.Is(err, storage.ErrInvalidIdempotencyKey),
+		errors.Is(err, storage.ErrIdempotencyKeyReused):
+		return codes.InvalidArgument
+	case errors.Is(err, storage.ErrCouponNotFound),
+		errors.Is(err, storage.ErrQuoteNotFound),
+		errors.Is(err, storage.ErrAddressNotFound):
+		return codes.NotFound
+	}
+
+	return codes.Unknown
+}
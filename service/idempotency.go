@@ -0,0 +1,21 @@
+package service
+
+import (
+	"context"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// idempotencyKeyHeader is the gRPC metadata key clients may send an idempotency key in.
+const idempotencyKeyHeader = "idempotency-key"
+
+// idempotencyKey returns key, or the idempotency key in the request metadata when key is empty.
+func idempotencyKey(ctx context.Context, key string) string {
+	if key != "" {
+		return key
+	}
+	if values := metadata.ValueFromIncomingContext(ctx, idempotencyKeyHeader); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
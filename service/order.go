@@ -6,8 +6,11 @@ import (
 	"log"
 
 	"github.com/flash_sale/flash_sale_order_service/genproto/order_service"
+	"github.com/flash_sale/flash_sale_order_service/orderstatus"
 	"github.com/flash_sale/flash_sale_order_service/storage"
 	"github.com/flash_sale/flash_sale_order_service/storage/redis"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // OrderService implements the order_service.OrderServiceServer interface.
@@ -25,11 +28,13 @@ func NewOrderService(storage storage.StorageI, redisClient *redis.Client) *Order
 	}
 }
 
-// CreateOrder creates a new order.
+// CreateOrder creates a new order. A retry with the same idempotency key gets the order
+// created the first time.
 func (s *OrderService) CreateOrder(ctx context.Context, req *order_service.CreateOrderRequest) (*order_service.CreateOrderResponse, error) {
+	req.IdempotencyKey = idempotencyKey(ctx, req.IdempotencyKey)
 	order, err := s.storage.Order().CreateOrder(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create order: %w", err)
+		return nil, wrapError(err, "failed to create order")
 	}
 
 	return &order_service.CreateOrderResponse{
@@ -51,9 +56,13 @@ func (s *OrderService) GetOrder(ctx context.Context, req *order_service.GetOrder
 
 // UpdateOrder updates an existing order.
 func (s *OrderService) UpdateOrder(ctx context.Context, req *order_service.UpdateOrderRequest) (*order_service.UpdateOrderResponse, error) {
+	if req.Order.Status != "" && !orderstatus.IsValid(req.Order.Status) {
+		return nil, wrapError(fmt.Errorf("%w: %q", orderstatus.ErrUnknownStatus, req.Order.Status), "failed to update order")
+	}
+
 	order, err := s.storage.Order().UpdateOrder(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update order: %w", err)
+		return nil, wrapError(err, "failed to update order")
 	}
 
 	return &order_service.UpdateOrderResponse{
@@ -83,9 +92,13 @@ func (s *OrderService) ListOrders(ctx context.Context, req *order_service.ListOr
 
 // UpdateOrderStatus updates the status of an order and sends a notification.
 func (s *OrderService) UpdateOrderStatus(ctx context.Context, req *order_service.UpdateOrderStatusRequest) (*order_service.UpdateOrderStatusResponse, error) {
+	if !orderstatus.IsValid(req.Status) {
+		return nil, wrapError(fmt.Errorf("%w: %q", orderstatus.ErrUnknownStatus, req.Status), "failed to update order status")
+	}
+
 	order, err := s.storage.Order().UpdateOrderStatus(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update order status: %w", err)
+		return nil, wrapError(err, "failed to update order status")
 	}
 
 	// Send notification to the user
@@ -99,3 +112,35 @@ func (s *OrderService) UpdateOrderStatus(ctx context.Context, req *order_service
 		Order: order,
 	}, nil
 }
+
+// GetOrderHistory retrieves the status changes of an order, oldest first.
+func (s *OrderService) GetOrderHistory(ctx context.Context, req *order_service.GetOrderHistoryRequest) (*order_service.GetOrderHistoryResponse, error) {
+	response, err := s.storage.Order().GetOrderHistory(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order history: %w", err)
+	}
+
+	return response, nil
+}
+
+// CancelOrder cancels an order, returns its stock and notifies the user.
+func (s *OrderService) CancelOrder(ctx context.Context, req *order_service.CancelOrderRequest) (*order_service.CancelOrderResponse, error) {
+	if req.Reason == "" {
+		return nil, status.Error(codes.InvalidArgument, "failed to cancel order: a cancellation reason is required")
+	}
+
+	order, err := s.storage.Order().CancelOrder(ctx, req)
+	if err != nil {
+		return nil, wrapError(err, "failed to cancel order")
+	}
+
+	// Send notification to the user
+	notificationMessage := fmt.Sprintf("Your order #%s has been cancelled: %s", order.Id, req.Reason)
+	if err := s.redisClient.AddNotification(ctx, order.ClientId, notificationMessage); err != nil {
+		log.Printf("failed to send notification: %v", err)
+	}
+
+	return &order_service.CancelOrderResponse{
+		Order: order,
+	}, nil
+}
@@ -6,8 +6,11 @@ import (
 	"log"
 
 	"github.com/flash_sale/flash_sale_order_service/genproto/order_service"
+	"github.com/flash_sale/flash_sale_order_service/money"
 	"github.com/flash_sale/flash_sale_order_service/storage"
 	"github.com/flash_sale/flash_sale_order_service/storage/redis"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // OrderItemService implements the order_service.OrderItemServiceServer interface.
@@ -49,9 +52,10 @@ func (s *OrderItemService) ListOrderItems(ctx context.Context, req *order_servic
 
 // ConvertBasketToOrderItems converts basket items to order items and sends a notification.
 func (s *OrderItemService) ConvertBasketToOrderItems(ctx context.Context, req *order_service.ConvertBasketToOrderItemsRequest) (*order_service.ConvertBasketToOrderItemsResponse, error) {
+	req.IdempotencyKey = idempotencyKey(ctx, req.IdempotencyKey)
 	orderID, err := s.storage.OrderItem().ConvertBasketToOrderItems(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to convert basket items to order items: %w", err)
+		return nil, wrapError(err, "failed to convert basket items to order items")
 	}
 
 	// Get order details for notification
@@ -70,14 +74,43 @@ func (s *OrderItemService) ConvertBasketToOrderItems(ctx context.Context, req *o
 	return orderID, nil
 }
 
-// DeleteOrderItem deletes an order item by its ID and updates the order total price.
+// DeleteOrderItem cancels and deletes an order item by its ID and updates the order total price.
 func (s *OrderItemService) DeleteOrderItem(ctx context.Context, req *order_service.DeleteOrderItemRequest) (*order_service.DeleteOrderItemResponse, error) {
 	_, err := s.storage.OrderItem().DeleteOrderItem(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to delete order item: %w", err)
+		return nil, wrapError(err, "failed to delete order item")
 	}
 
 	return &order_service.DeleteOrderItemResponse{
 		Message: "Order item deleted successfully",
 	}, nil
 }
+
+// CancelOrderItem cancels some or all units of an order item and notifies the user of the refund.
+func (s *OrderItemService) CancelOrderItem(ctx context.Context, req *order_service.CancelOrderItemRequest) (*order_service.CancelOrderItemResponse, error) {
+	if req.Quantity < 0 {
+		return nil, status.Error(codes.InvalidArgument, "failed to cancel order item: quantity must not be negative")
+	}
+
+	response, err := s.storage.OrderItem().CancelOrderItem(ctx, req)
+	if err != nil {
+		return nil, wrapError(err, "failed to cancel order item")
+	}
+
+	// Get order details for notification
+	order, err := s.storage.Order().GetOrder(ctx, &order_service.GetOrderRequest{Id: response.OrderItem.OrderId})
+	if err != nil {
+		log.Printf("failed to get order for notification: %v", err)
+		return response, nil
+	}
+
+	// Send notification to the user
+	refund := response.RefundAmount
+	notificationMessage := fmt.Sprintf("Part of your order #%s has been cancelled. You will be refunded %s %s.",
+		order.Id, money.Amount(refund.GetMinorUnits()), refund.GetCurrencyCode())
+	if err := s.redisClient.AddNotification(ctx, order.ClientId, notificationMessage); err != nil {
+		log.Printf("failed to send notification: %v", err)
+	}
+
+	return response, nil
+}
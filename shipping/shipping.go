@@ -0,0 +1,294 @@
+// Package shipping finds the delivery zone of an order and the fee charged for delivering it.
+//
+// Zones are GeoJSON polygons. Fees are kept in the base currency and converted at the
+// order's exchange rate, like product prices.
+package shipping
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/flash_sale/flash_sale_order_service/money"
+)
+
+// FeeType is how the fee of a zone is worked out.
+type FeeType string
+
+// Fee types.
+const (
+	// Flat charges Schedule.Amount.
+	Flat FeeType = "FLAT"
+	// Distance charges Schedule.Amount plus Schedule.PerKm for every started kilometre
+	// between the warehouse and the delivery point, as the crow flies.
+	Distance FeeType = "DISTANCE"
+	// FreeOver charges Schedule.Amount, or nothing once the items reach Schedule.Threshold.
+	FreeOver FeeType = "FREE_OVER"
+)
+
+// Point is a position on the globe, in degrees.
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// IsValid reports whether the point is on the globe. 0,0 is refused as well: it is in the
+// ocean and is what a client sends when it has no position at all.
+func (p Point) IsValid() bool {
+	return p.Latitude >= -90 && p.Latitude <= 90 &&
+		p.Longitude >= -180 && p.Longitude <= 180 &&
+		(p.Latitude != 0 || p.Longitude != 0)
+}
+
+// Schedule is the fee schedule of a zone.
+type Schedule struct {
+	Type      FeeType
+	Amount    money.Amount
+	PerKm     money.Amount
+	Threshold money.Amount
+	Warehouse Point
+}
+
+// Fee returns the fee for delivering items worth subtotal to destination, converted at rate.
+func (s Schedule) Fee(subtotal money.Amount, rate money.Rate, destination Point) money.Amount {
+	switch s.Type {
+	case Distance:
+		km := int32(math.Ceil(DistanceKm(s.Warehouse, destination)))
+		return s.Amount.Convert(rate) + s.PerKm.Convert(rate).Times(km)
+	case FreeOver:
+		if subtotal >= s.Threshold.Convert(rate) {
+			return 0
+		}
+		return s.Amount.Convert(rate)
+	default:
+		return s.Amount.Convert(rate)
+	}
+}
+
+// Polygon is a GeoJSON polygon: an outer ring followed by the rings of its holes.
+type Polygon [][]Point
+
+// Contains reports whether a point is inside the outer ring and outside every hole.
+func (p Polygon) Contains(point Point) bool {
+	if len(p) == 0 || !ringContains(p[0], point) {
+		return false
+	}
+	for _, hole := range p[1:] {
+		if ringContains(hole, point) {
+			return false
+		}
+	}
+	return true
+}
+
+// ringContains casts a ray from point along its latitude and counts the edges it crosses.
+func ringContains(ring []Point, point Point) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a.Latitude > point.Latitude) != (b.Latitude > point.Latitude) {
+			crossing := (b.Longitude-a.Longitude)*(point.Latitude-a.Latitude)/(b.Latitude-a.Latitude) + a.Longitude
+			if point.Longitude < crossing {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// Zone is an area orders are delivered to.
+type Zone struct {
+	Name     string
+	Polygons []Polygon
+	Fee      Schedule
+}
+
+// Contains reports whether a point is inside one of the zone's polygons.
+func (z Zone) Contains(point Point) bool {
+	for _, polygon := range z.Polygons {
+		if polygon.Contains(point) {
+			return true
+		}
+	}
+	return false
+}
+
+// Zones holds the delivery zones, checked in order.
+type Zones struct {
+	zones []Zone
+}
+
+// NewZones returns the Zones given. The first zone containing a point is the one used for it.
+func NewZones(zones []Zone) *Zones {
+	return &Zones{
+		zones: zones,
+	}
+}
+
+// Locate returns the zone a point is delivered in.
+func (z *Zones) Locate(point Point) (Zone, bool) {
+	if z == nil {
+		return Zone{}, false
+	}
+	for _, zone := range z.zones {
+		if zone.Contains(point) {
+			return zone, true
+		}
+	}
+	return Zone{}, false
+}
+
+// Get returns a zone by name.
+func (z *Zones) Get(name string) (Zone, bool) {
+	if z == nil {
+		return Zone{}, false
+	}
+	for _, zone := range z.zones {
+		if zone.Name == name {
+			return zone, true
+		}
+	}
+	return Zone{}, false
+}
+
+// DistanceKm returns the great-circle distance between two points in kilometres.
+func DistanceKm(a, b Point) float64 {
+	const earthRadiusKm = 6371.0
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// zonesFile is the layout of a zones file: a GeoJSON FeatureCollection of Polygon or
+// MultiPolygon features, e.g.
+//
+//	{"type": "FeatureCollection", "features": [{"type": "Feature",
+//		"geometry": {"type": "Polygon", "coordinates": [[[69.1, 41.2], [69.4, 41.2], [69.4, 41.4], [69.1, 41.2]]]},
+//		"properties": {"name": "city", "fee": {"type": "FREE_OVER", "amount": "3.00", "threshold": "50.00"}}}]}
+//
+// Positions are [longitude, latitude], as GeoJSON has them. A DISTANCE fee also needs
+// "per_km" and "warehouse": [longitude, latitude].
+type zonesFile struct {
+	Type     string `json:"type"`
+	Features []struct {
+		Geometry struct {
+			Type        string          `json:"type"`
+			Coordinates json.RawMessage `json:"coordinates"`
+		} `json:"geometry"`
+		Properties struct {
+			Name string `json:"name"`
+			Fee  struct {
+				Type      FeeType   `json:"type"`
+				Amount    string    `json:"amount"`
+				PerKm     string    `json:"per_km"`
+				Threshold string    `json:"threshold"`
+				Warehouse []float64 `json:"warehouse"`
+			} `json:"fee"`
+		} `json:"properties"`
+	} `json:"features"`
+}
+
+// LoadZones reads Zones from a GeoJSON file.
+func LoadZones(path string) (*Zones, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read delivery zones file: %w", err)
+	}
+
+	var file zonesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse delivery zones file: %w", err)
+	}
+	if file.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("delivery zones file is a %q, not a FeatureCollection", file.Type)
+	}
+
+	zones := make([]Zone, 0, len(file.Features))
+	for _, feature := range file.Features {
+		zone := Zone{Name: feature.Properties.Name}
+		if zone.Name == "" {
+			return nil, fmt.Errorf("delivery zones file has a zone without a name")
+		}
+
+		var polygons [][][][]float64
+		switch feature.Geometry.Type {
+		case "Polygon":
+			var polygon [][][]float64
+			err = json.Unmarshal(feature.Geometry.Coordinates, &polygon)
+			polygons = append(polygons, polygon)
+		case "MultiPolygon":
+			err = json.Unmarshal(feature.Geometry.Coordinates, &polygons)
+		default:
+			return nil, fmt.Errorf("delivery zone %s is a %q, not a Polygon or MultiPolygon", zone.Name, feature.Geometry.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the coordinates of delivery zone %s: %w", zone.Name, err)
+		}
+		for _, rings := range polygons {
+			polygon, err := makePolygon(rings)
+			if err != nil {
+				return nil, fmt.Errorf("delivery zone %s: %w", zone.Name, err)
+			}
+			zone.Polygons = append(zone.Polygons, polygon)
+		}
+
+		fee := feature.Properties.Fee
+		zone.Fee = Schedule{Type: fee.Type}
+		for _, field := range []struct {
+			value string
+			dst   *money.Amount
+		}{
+			{fee.Amount, &zone.Fee.Amount},
+			{fee.PerKm, &zone.Fee.PerKm},
+			{fee.Threshold, &zone.Fee.Threshold},
+		} {
+			if field.value == "" {
+				continue
+			}
+			amount, err := money.Parse(field.value)
+			if err != nil || amount < 0 {
+				return nil, fmt.Errorf("delivery zone %s has an invalid fee amount %q", zone.Name, field.value)
+			}
+			*field.dst = amount
+		}
+		switch fee.Type {
+		case Flat, FreeOver:
+		case Distance:
+			if len(fee.Warehouse) != 2 {
+				return nil, fmt.Errorf("delivery zone %s has a distance fee without a warehouse", zone.Name)
+			}
+			zone.Fee.Warehouse = Point{Latitude: fee.Warehouse[1], Longitude: fee.Warehouse[0]}
+		default:
+			return nil, fmt.Errorf("delivery zone %s has an unknown fee type %q", zone.Name, fee.Type)
+		}
+
+		zones = append(zones, zone)
+	}
+
+	return NewZones(zones), nil
+}
+
+// makePolygon turns GeoJSON rings of [longitude, latitude] positions into a Polygon.
+func makePolygon(rings [][][]float64) (Polygon, error) {
+	if len(rings) == 0 {
+		return nil, fmt.Errorf("polygon has no rings")
+	}
+	polygon := make(Polygon, 0, len(rings))
+	for _, ring := range rings {
+		if len(ring) < 4 {
+			return nil, fmt.Errorf("polygon ring has fewer than 4 positions")
+		}
+		points := make([]Point, 0, len(ring))
+		for _, position := range ring {
+			if len(position) < 2 {
+				return nil, fmt.Errorf("polygon has a position without a longitude and latitude")
+			}
+			points = append(points, Point{Latitude: position[1], Longitude: position[0]})
+		}
+		polygon = append(polygon, points)
+	}
+	return polygon, nil
+}
@@ -0,0 +1,128 @@
+package shipping
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/flash_sale/flash_sale_order_service/money"
+	"github.com/stretchr/testify/assert"
+)
+
+// square returns the ring of a square with corners at (lat, lon) and (lat+size, lon+size).
+func square(lat, lon, size float64) []Point {
+	return []Point{
+		{lat, lon}, {lat, lon + size}, {lat + size, lon + size}, {lat + size, lon}, {lat, lon},
+	}
+}
+
+func TestPointIsValid(t *testing.T) {
+	assert.True(t, Point{41.31, 69.24}.IsValid())
+	assert.True(t, Point{-90, 180}.IsValid())
+	assert.True(t, Point{0, 69.24}.IsValid())
+	assert.False(t, Point{0, 0}.IsValid())
+	assert.False(t, Point{90.1, 0}.IsValid())
+	assert.False(t, Point{0, -180.1}.IsValid())
+	assert.False(t, Point{math.NaN(), 69.24}.IsValid())
+	assert.False(t, Point{41.31, math.Inf(1)}.IsValid())
+}
+
+func TestPolygonContains(t *testing.T) {
+	donut := Polygon{square(0, 0, 10), square(4, 4, 2)}
+
+	assert.True(t, donut.Contains(Point{1, 1}))
+	assert.True(t, donut.Contains(Point{9, 5}))
+	assert.False(t, donut.Contains(Point{5, 5})) // in the hole
+	assert.False(t, donut.Contains(Point{11, 5}))
+	assert.False(t, donut.Contains(Point{5, -1}))
+}
+
+func TestZonesLocate(t *testing.T) {
+	zones := NewZones([]Zone{
+		{Name: "centre", Polygons: []Polygon{{square(4, 4, 2)}}},
+		{Name: "city", Polygons: []Polygon{{square(0, 0, 10)}}},
+	})
+
+	zone, ok := zones.Locate(Point{5, 5})
+	assert.True(t, ok)
+	assert.Equal(t, "centre", zone.Name)
+
+	zone, ok = zones.Locate(Point{1, 1})
+	assert.True(t, ok)
+	assert.Equal(t, "city", zone.Name)
+
+	_, ok = zones.Locate(Point{20, 20})
+	assert.False(t, ok)
+
+	zone, ok = zones.Get("city")
+	assert.True(t, ok)
+	assert.Equal(t, "city", zone.Name)
+
+	var none *Zones
+	_, ok = none.Locate(Point{5, 5})
+	assert.False(t, ok)
+}
+
+func TestScheduleFee(t *testing.T) {
+	halfPrice := money.Rate(50000000) // 0.5
+
+	flat := Schedule{Type: Flat, Amount: 499}
+	assert.Equal(t, money.Amount(499), flat.Fee(10000, money.OneToOne, Point{}))
+	assert.Equal(t, money.Amount(250), flat.Fee(10000, halfPrice, Point{}))
+
+	freeOver := Schedule{Type: FreeOver, Amount: 300, Threshold: 5000}
+	assert.Equal(t, money.Amount(300), freeOver.Fee(4999, money.OneToOne, Point{}))
+	assert.Equal(t, money.Amount(0), freeOver.Fee(5000, money.OneToOne, Point{}))
+	assert.Equal(t, money.Amount(0), freeOver.Fee(2500, halfPrice, Point{})) // 25.00 is the converted threshold
+
+	// One degree of latitude is about 111.2 km, so 112 started kilometres
+	distance := Schedule{Type: Distance, Amount: 200, PerKm: 10, Warehouse: Point{41, 69}}
+	assert.Equal(t, money.Amount(200+1120), distance.Fee(0, money.OneToOne, Point{42, 69}))
+	assert.Equal(t, money.Amount(200), distance.Fee(0, money.OneToOne, Point{41, 69}))
+}
+
+func TestLoadZones(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "zones.geojson")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"type": "FeatureCollection", "features": [
+		{"type": "Feature",
+			"geometry": {"type": "Polygon", "coordinates": [[[0, 0], [10, 0], [10, 10], [0, 10], [0, 0]]]},
+			"properties": {"name": "city", "fee": {"type": "FREE_OVER", "amount": "3.00", "threshold": "50"}}},
+		{"type": "Feature",
+			"geometry": {"type": "MultiPolygon", "coordinates": [
+				[[[20, 20], [30, 20], [30, 30], [20, 30], [20, 20]]],
+				[[[40, 40], [50, 40], [50, 50], [40, 50], [40, 40]]]
+			]},
+			"properties": {"name": "suburbs", "fee": {"type": "DISTANCE", "amount": "2", "per_km": "0.10", "warehouse": [5, 5]}}}
+	]}`), 0o600))
+
+	zones, err := LoadZones(path)
+	assert.NoError(t, err)
+
+	zone, ok := zones.Locate(Point{Latitude: 5, Longitude: 1})
+	assert.True(t, ok)
+	assert.Equal(t, Schedule{Type: FreeOver, Amount: 300, Threshold: 5000}, zone.Fee)
+
+	zone, ok = zones.Locate(Point{Latitude: 45, Longitude: 45})
+	assert.True(t, ok)
+	assert.Equal(t, "suburbs", zone.Name)
+	assert.Equal(t, Point{Latitude: 5, Longitude: 5}, zone.Fee.Warehouse)
+
+	for name, content := range map[string]string{
+		"not a collection":   `{"type": "Feature"}`,
+		"point":              `{"type": "FeatureCollection", "features": [{"geometry": {"type": "Point", "coordinates": [0, 0]}, "properties": {"name": "x", "fee": {"type": "FLAT"}}}]}`,
+		"open ring":          `{"type": "FeatureCollection", "features": [{"geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [0, 0]]]}, "properties": {"name": "x", "fee": {"type": "FLAT"}}}]}`,
+		"unknown fee":        `{"type": "FeatureCollection", "features": [{"geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 0]]]}, "properties": {"name": "x", "fee": {"type": "BY_WEIGHT"}}}]}`,
+		"distance no origin": `{"type": "FeatureCollection", "features": [{"geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 0]]]}, "properties": {"name": "x", "fee": {"type": "DISTANCE"}}}]}`,
+		"bad amount":         `{"type": "FeatureCollection", "features": [{"geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 0]]]}, "properties": {"name": "x", "fee": {"type": "FLAT", "amount": "free"}}}]}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, "bad.geojson")
+			assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+			_, err := LoadZones(path)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package storage
+
+import "errors"
+
+var (
+	// ErrSoldOut is returned when there is not enough stock left to fulfil a line.
+	ErrSoldOut = errors.New("sold out")
+
+	// ErrStockNotCached is returned by a StockCacheI that holds no counter for a product,
+	// in which case PostgreSQL has to be asked directly.
+	ErrStockNotCached = errors.New("stock not cached")
+
+	// ErrPurchaseLimitExceeded is returned when a user would buy more flash sale units than allowed.
+	ErrPurchaseLimitExceeded = errors.New("purchase limit exceeded")
+
+	// ErrInvalidQuantity is returned when a quantity is out of range for the line it applies to.
+	ErrInvalidQuantity = errors.New("invalid quantity")
+
+	// ErrUnsupportedCurrency is returned for an amount in a currency prices are not kept in.
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+
+	// ErrCouponNotFound is returned for a promo code that does not exist.
+	ErrCouponNotFound = errors.New("coupon not found")
+
+	// ErrCouponNotApplicable is returned when a coupon exists but cannot be used for a checkout.
+	ErrCouponNotApplicable = errors.New("coupon not applicable")
+
+	// ErrInvalidBasketStatus is returned when a basket is set to a status it cannot be put in
+	// directly, such as CHECKED_OUT, which only a checkout may set.
+	ErrInvalidBasketStatus = errors.New("invalid basket status")
+
+	// ErrInvalidCoupon is returned when a coupon being created or updated is malformed.
+	ErrInvalidCoupon = errors.New("invalid coupon")
+
+	// ErrQuoteNotFound is returned for a price quote that does not exist.
+	ErrQuoteNotFound = errors.New("quote not found")
+
+	// ErrQuoteExpired is returned when a price quote is used after it expired.
+	ErrQuoteExpired = errors.New("quote expired")
+
+	// ErrInvalidQuote is returned when a price quote was not issued for the basket being checked out.
+	ErrInvalidQuote = errors.New("invalid quote")
+
+	// ErrOutsideDeliveryZone is returned for delivery coordinates outside every delivery zone.
+	ErrOutsideDeliveryZone = errors.New("outside every delivery zone")
+
+	// ErrInvalidCoordinates is returned for a latitude or longitude out of range, or for 0,0.
+	ErrInvalidCoordinates = errors.New("invalid coordinates")
+
+	// ErrAddressNotFound is returned for a saved address that does not exist or belongs to another client.
+	ErrAddressNotFound = errors.New("address not found")
+
+	// ErrInvalidAddress is returned when an address is missing a field or has a malformed one.
+	ErrInvalidAddress = errors.New("invalid address")
+
+	// ErrInvalidIdempotencyKey is returned for an idempotency key that is too long.
+	ErrInvalidIdempotencyKey = errors.New("invalid idempotency key")
+
+	// ErrIdempotencyKeyReused is returned when an idempotency key is sent again with a different request.
+	ErrIdempotencyKeyReused = errors.New("idempotency key reused with a different request")
+)
@@ -0,0 +1,416 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/flash_sale/flash_sale_order_service/genproto/order_service"
+	"github.com/flash_sale/flash_sale_order_service/models"
+	"github.com/flash_sale/flash_sale_order_service/shipping"
+	"github.com/flash_sale/flash_sale_order_service/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type AddressRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewAddressRepo(db *pgxpool.Pool) *AddressRepo {
+	return &AddressRepo{
+		db: db,
+	}
+}
+
+const addressColumns = `
+	id,
+	client_id,
+	label,
+	street,
+	city,
+	postal_code,
+	notes,
+	recipient_phone,
+	latitude,
+	longitude,
+	created_at,
+	updated_at
+`
+
+func (r *AddressRepo) CreateAddress(ctx context.Context, req *order_service.CreateAddressRequest) (*order_service.Address, error) {
+	if req.Address == nil {
+		return nil, fmt.Errorf("%w: address is required", storage.ErrInvalidAddress)
+	}
+	if req.Address.Id == "" {
+		req.Address.Id = uuid.NewString()
+	}
+
+	addressModel, err := makeAddressModel(req.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO addresses (
+			id,
+			client_id,
+			label,
+			street,
+			city,
+			postal_code,
+			notes,
+			recipient_phone,
+			latitude,
+			longitude,
+			created_at,
+			updated_at,
+			deleted_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW(), 0
+		) RETURNING ` + addressColumns
+
+	row := r.db.QueryRow(ctx, query,
+		addressModel.Id,
+		addressModel.ClientId,
+		addressModel.Label,
+		addressModel.Street,
+		addressModel.City,
+		addressModel.PostalCode,
+		addressModel.Notes,
+		addressModel.RecipientPhone,
+		addressModel.Latitude,
+		addressModel.Longitude,
+	)
+	if err := scanAddress(row, &addressModel); err != nil {
+		return nil, err
+	}
+
+	return makeAddressProto(addressModel), nil
+}
+
+func (r *AddressRepo) GetAddress(ctx context.Context, req *order_service.GetAddressRequest) (*order_service.Address, error) {
+	var addressModel models.Address
+
+	query := `SELECT ` + addressColumns + ` FROM addresses WHERE id = $1 AND deleted_at = 0`
+
+	err := scanAddress(r.db.QueryRow(ctx, query, req.Id), &addressModel)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", storage.ErrAddressNotFound, req.Id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return makeAddressProto(addressModel), nil
+}
+
+// UpdateAddress updates a saved address. Orders already placed with it keep their copy.
+func (r *AddressRepo) UpdateAddress(ctx context.Context, req *order_service.UpdateAddressRequest) (*order_service.Address, error) {
+	addressModel, err := makeAddressModel(req.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE addresses
+		SET
+			label = $1,
+			street = $2,
+			city = $3,
+			postal_code = $4,
+			notes = $5,
+			recipient_phone = $6,
+			latitude = $7,
+			longitude = $8,
+			updated_at = NOW()
+		WHERE id = $9 AND client_id = $10 AND deleted_at = 0
+		RETURNING ` + addressColumns
+
+	row := r.db.QueryRow(ctx, query,
+		addressModel.Label,
+		addressModel.Street,
+		addressModel.City,
+		addressModel.PostalCode,
+		addressModel.Notes,
+		addressModel.RecipientPhone,
+		addressModel.Latitude,
+		addressModel.Longitude,
+		addressModel.Id,
+		addressModel.ClientId,
+	)
+	err = scanAddress(row, &addressModel)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", storage.ErrAddressNotFound, addressModel.Id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return makeAddressProto(addressModel), nil
+}
+
+func (r *AddressRepo) DeleteAddress(ctx context.Context, req *order_service.DeleteAddressRequest) (*order_service.DeleteAddressResponse, error) {
+	query := `
+		UPDATE addresses
+        SET deleted_at = $1
+        WHERE id = $2 AND deleted_at = 0
+	`
+
+	_, err := r.db.Exec(ctx, query, time.Now().Unix(), req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &order_service.DeleteAddressResponse{
+		Message: "Address deleted successfully",
+	}, nil
+}
+
+func (r *AddressRepo) ListAddresses(ctx context.Context, req *order_service.ListAddressesRequest) (*order_service.ListAddressesResponse, error) {
+	var args []interface{}
+	count := 1
+	query := `SELECT ` + addressColumns + ` FROM addresses WHERE 1=1 AND deleted_at = 0`
+
+	filter := ""
+
+	if req.ClientId != "" {
+		filter += fmt.Sprintf(" AND client_id = $%d", count)
+		args = append(args, req.ClientId)
+		count++
+	}
+
+	query += filter
+
+	// Handle invalid page or limit values
+	if req.Page <= 0 {
+		req.Page = 1 // Default to page 1
+	}
+	if req.Limit <= 0 {
+		req.Limit = 10 // Default to a limit of 10
+	}
+
+	totalCountQuery := "SELECT count(*) FROM addresses WHERE 1=1 AND deleted_at = 0" + filter
+	var totalCount int
+	err := r.db.QueryRow(ctx, totalCountQuery, args...).Scan(&totalCount)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add LIMIT and OFFSET for pagination using the proto fields
+	query += fmt.Sprintf(" ORDER BY created_at LIMIT $%d OFFSET $%d", count, count+1)
+	args = append(args, req.Limit, (req.Page-1)*req.Limit)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var addressList []*order_service.Address
+
+	for rows.Next() {
+		var addressModel models.Address
+		if err := scanAddress(rows, &addressModel); err != nil {
+			return nil, err
+		}
+		addressList = append(addressList, makeAddressProto(addressModel))
+	}
+
+	return &order_service.ListAddressesResponse{
+		Addresses: addressList,
+		Total:     int32(totalCount),
+	}, rows.Err()
+}
+
+// checkCoordinates makes sure a delivery point is somewhere on the globe.
+func checkCoordinates(latitude, longitude float64) error {
+	if !(shipping.Point{Latitude: latitude, Longitude: longitude}).IsValid() {
+		return fmt.Errorf("%w: %v, %v", storage.ErrInvalidCoordinates, latitude, longitude)
+	}
+	return nil
+}
+
+// phonePattern matches an E.164 phone number.
+var phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// checkDeliveryAddress trims the fields of an address and makes sure the required ones are set.
+func checkDeliveryAddress(address *order_service.DeliveryAddress) error {
+	address.Street = strings.TrimSpace(address.Street)
+	address.City = strings.TrimSpace(address.City)
+	address.PostalCode = strings.TrimSpace(address.PostalCode)
+	address.Notes = strings.TrimSpace(address.Notes)
+	address.RecipientPhone = strings.TrimSpace(address.RecipientPhone)
+
+	switch {
+	case address.Street == "":
+		return fmt.Errorf("%w: street is required", storage.ErrInvalidAddress)
+	case address.City == "":
+		return fmt.Errorf("%w: city is required", storage.ErrInvalidAddress)
+	case len(address.Street) > 255 || len(address.City) > 128 || len(address.PostalCode) > 20:
+		return fmt.Errorf("%w: street, city or postal code is too long", storage.ErrInvalidAddress)
+	case address.RecipientPhone != "" && !phonePattern.MatchString(address.RecipientPhone):
+		return fmt.Errorf("%w: recipient phone %q is not an E.164 number", storage.ErrInvalidAddress, address.RecipientPhone)
+	}
+	return nil
+}
+
+// getClientAddress reads a saved address of a client.
+func getClientAddress(ctx context.Context, db querier, addressID, clientID string) (models.Address, error) {
+	var addressModel models.Address
+	err := scanAddress(db.QueryRow(ctx, `
+		SELECT `+addressColumns+`
+		FROM addresses
+		WHERE id = $1 AND client_id = $2 AND deleted_at = 0
+	`, addressID, clientID), &addressModel)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return addressModel, fmt.Errorf("%w: %s", storage.ErrAddressNotFound, addressID)
+	}
+	if err != nil {
+		return addressModel, fmt.Errorf("failed to get address: %w", err)
+	}
+	return addressModel, nil
+}
+
+// saveOrderAddress stores the address an order is delivered to, replacing any earlier one.
+func saveOrderAddress(ctx context.Context, tx pgx.Tx, orderID, addressID string, address *order_service.DeliveryAddress) error {
+	// Use sql.NullString for nullable UUIDs
+	savedAddressID := sql.NullString{
+		String: addressID,
+		Valid:  addressID != "",
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO order_addresses (order_id, address_id, street, city, postal_code, notes, recipient_phone, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		ON CONFLICT (order_id) DO UPDATE
+		SET address_id = EXCLUDED.address_id,
+			street = EXCLUDED.street,
+			city = EXCLUDED.city,
+			postal_code = EXCLUDED.postal_code,
+			notes = EXCLUDED.notes,
+			recipient_phone = EXCLUDED.recipient_phone,
+			updated_at = NOW()
+	`, orderID, savedAddressID, address.Street, address.City, address.PostalCode, address.Notes, address.RecipientPhone)
+	if err != nil {
+		return fmt.Errorf("failed to save order address: %w", err)
+	}
+	return nil
+}
+
+// attachOrderAddresses fills in the delivery addresses of orders with one query.
+func attachOrderAddresses(ctx context.Context, db querier, orders ...*order_service.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*order_service.Order, len(orders))
+	ids := make([]string, 0, len(orders))
+	for _, order := range orders {
+		byID[order.Id] = order
+		ids = append(ids, order.Id)
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT order_id, address_id, street, city, postal_code, notes, recipient_phone
+		FROM order_addresses
+		WHERE order_id = ANY($1::uuid[])
+	`, ids)
+	if err != nil {
+		return fmt.Errorf("failed to get order addresses: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			orderID   string
+			addressID sql.NullString
+			address   order_service.DeliveryAddress
+		)
+		err := rows.Scan(&orderID, &addressID, &address.Street, &address.City, &address.PostalCode, &address.Notes, &address.RecipientPhone)
+		if err != nil {
+			return fmt.Errorf("failed to scan order address: %w", err)
+		}
+		if order, ok := byID[orderID]; ok {
+			order.AddressId = addressID.String
+			order.DeliveryAddress = &address
+		}
+	}
+
+	return rows.Err()
+}
+
+func scanAddress(row pgx.Row, address *models.Address) error {
+	return row.Scan(
+		&address.Id,
+		&address.ClientId,
+		&address.Label,
+		&address.Street,
+		&address.City,
+		&address.PostalCode,
+		&address.Notes,
+		&address.RecipientPhone,
+		&address.Latitude,
+		&address.Longitude,
+		&address.CreatedAt,
+		&address.UpdatedAt,
+	)
+}
+
+// Convert db model to proto model
+func makeAddressProto(address models.Address) *order_service.Address {
+	return &order_service.Address{
+		Id:       address.Id,
+		ClientId: address.ClientId,
+		Label:    address.Label,
+		Address: &order_service.DeliveryAddress{
+			Street:         address.Street,
+			City:           address.City,
+			PostalCode:     address.PostalCode,
+			Notes:          address.Notes,
+			RecipientPhone: address.RecipientPhone,
+		},
+		Latitude:  address.Latitude,
+		Longitude: address.Longitude,
+		CreatedAt: timestamppb.New(address.CreatedAt),
+		UpdatedAt: timestamppb.New(address.UpdatedAt),
+	}
+}
+
+// Convert proto model to db model, checking the address on the way
+func makeAddressModel(address *order_service.Address) (models.Address, error) {
+	if address == nil {
+		return models.Address{}, fmt.Errorf("%w: address is required", storage.ErrInvalidAddress)
+	}
+	if address.ClientId == "" {
+		return models.Address{}, fmt.Errorf("%w: client_id is required", storage.ErrInvalidAddress)
+	}
+	if address.Address == nil {
+		return models.Address{}, fmt.Errorf("%w: address is required", storage.ErrInvalidAddress)
+	}
+	if err := checkDeliveryAddress(address.Address); err != nil {
+		return models.Address{}, err
+	}
+	if err := checkCoordinates(address.Latitude, address.Longitude); err != nil {
+		return models.Address{}, err
+	}
+
+	return models.Address{
+		Id:             address.Id,
+		ClientId:       address.ClientId,
+		Label:          strings.TrimSpace(address.Label),
+		Street:         address.Address.Street,
+		City:           address.Address.City,
+		PostalCode:     address.Address.PostalCode,
+		Notes:          address.Address.Notes,
+		RecipientPhone: address.Address.RecipientPhone,
+		Latitude:       address.Latitude,
+		Longitude:      address.Longitude,
+	}, nil
+}
@@ -5,23 +5,37 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/flash_sale/flash_sale_order_service/currency"
 	"github.com/flash_sale/flash_sale_order_service/genproto/order_service"
 	"github.com/flash_sale/flash_sale_order_service/models"
+	"github.com/flash_sale/flash_sale_order_service/money"
+	"github.com/flash_sale/flash_sale_order_service/pricing"
+	"github.com/flash_sale/flash_sale_order_service/quote"
+	"github.com/flash_sale/flash_sale_order_service/storage"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 // ... (other code) ...
 
 type BasketRepo struct {
-	db *pgx.Conn
+	db         *pgxpool.Pool
+	stockCache storage.StockCacheI
+	rates      currency.ExchangeRateProvider
+	pricing    *pricing.Engine
+	quotes     *quote.Signer
 }
 
-func NewBasketRepo(db *pgx.Conn) *BasketRepo {
+func NewBasketRepo(db *pgxpool.Pool, stockCache storage.StockCacheI, rates currency.ExchangeRateProvider, engine *pricing.Engine, quotes *quote.Signer) *BasketRepo {
 	return &BasketRepo{
-		db: db,
+		db:         db,
+		stockCache: stockCache,
+		rates:      rates,
+		pricing:    engine,
+		quotes:     quotes,
 	}
 }
 
@@ -29,17 +43,27 @@ func (r *BasketRepo) CreateBasket(ctx context.Context, req *order_service.Create
 	if req.Basket.Id == "" {
 		req.Basket.Id = uuid.NewString()
 	}
+	if req.Basket.CurrencyCode == "" {
+		req.Basket.CurrencyCode = money.DefaultCurrency
+	}
+	if !currency.IsValidCode(req.Basket.CurrencyCode) {
+		return nil, fmt.Errorf("%w: %q", storage.ErrUnsupportedCurrency, req.Basket.CurrencyCode)
+	}
+	if err := checkBasketStatus(req.Basket.Status); err != nil {
+		return nil, err
+	}
 
 	query := `
 		INSERT INTO baskets (
 			id,
 			user_id,
 			status,
+			currency_code,
 			created_at,
 			updated_at,
 			deleted_at
 		) VALUES (
-			$1, $2, $3, NOW(), NOW(), 0
+			$1, $2, $3, $4, NOW(), NOW(), 0
 		) RETURNING id, created_at, updated_at
 	`
 
@@ -49,6 +73,7 @@ func (r *BasketRepo) CreateBasket(ctx context.Context, req *order_service.Create
 		basketModel.Id,
 		basketModel.UserId,
 		basketModel.Status,
+		basketModel.CurrencyCode,
 	).Scan(&basketModel.Id, &basketModel.CreatedAt, &basketModel.UpdatedAt)
 
 	if err != nil {
@@ -66,6 +91,7 @@ func (r *BasketRepo) GetBasket(ctx context.Context, req *order_service.GetBasket
 			id,
 			user_id,
 			status,
+			currency_code,
 			created_at,
 			updated_at,
 			deleted_at
@@ -77,6 +103,7 @@ func (r *BasketRepo) GetBasket(ctx context.Context, req *order_service.GetBasket
 		&basketModel.Id,
 		&basketModel.UserId,
 		&basketModel.Status,
+		&basketModel.CurrencyCode,
 		&basketModel.CreatedAt,
 		&basketModel.UpdatedAt,
 		&basketModel.DeletedAt,
@@ -93,6 +120,10 @@ func (r *BasketRepo) GetBasket(ctx context.Context, req *order_service.GetBasket
 }
 
 func (r *BasketRepo) UpdateBasket(ctx context.Context, req *order_service.UpdateBasketRequest) (*order_service.Basket, error) {
+	if err := checkBasketStatus(req.Basket.GetStatus()); err != nil {
+		return nil, err
+	}
+
 	query := `
 		UPDATE baskets
 		SET 
@@ -100,7 +131,7 @@ func (r *BasketRepo) UpdateBasket(ctx context.Context, req *order_service.Update
 			status = $2,
 			updated_at = NOW()
 		WHERE id = $3 AND deleted_at = 0
-		RETURNING id, user_id, status, created_at, updated_at
+		RETURNING id, user_id, status, currency_code, created_at, updated_at
 	`
 
 	basketModel := makeBasketModel(req.Basket)
@@ -113,6 +144,7 @@ func (r *BasketRepo) UpdateBasket(ctx context.Context, req *order_service.Update
 		&basketModel.Id,
 		&basketModel.UserId,
 		&basketModel.Status,
+		&basketModel.CurrencyCode,
 		&basketModel.CreatedAt,
 		&basketModel.UpdatedAt,
 	)
@@ -127,18 +159,35 @@ func (r *BasketRepo) UpdateBasket(ctx context.Context, req *order_service.Update
 	return makeBasketProto(basketModel), nil
 }
 
+// DeleteBasket deletes a basket and gives back the stock its items were holding.
 func (r *BasketRepo) DeleteBasket(ctx context.Context, req *order_service.DeleteBasketRequest) (*order_service.DeleteBasketResponse, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	releasedHolds, err := releaseBasketStockHolds(ctx, tx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
 	query := `
 		UPDATE baskets
         SET deleted_at = $1
         WHERE id = $2 AND deleted_at = 0
 	`
 
-	_, err := r.db.Exec(ctx, query, time.Now().Unix(), req.Id)
+	_, err = tx.Exec(ctx, query, time.Now().Unix(), req.Id)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	releaseCachedStockHolds(ctx, r.stockCache, releasedHolds)
+
 	return &order_service.DeleteBasketResponse{
 		Message: "Basket deleted successfully",
 	}, nil
@@ -152,6 +201,7 @@ func (r *BasketRepo) ListBaskets(ctx context.Context, req *order_service.ListBas
 			id,
 			user_id,
 			status,
+			currency_code,
 			created_at,
 			updated_at,
 			deleted_at
@@ -203,6 +253,7 @@ func (r *BasketRepo) ListBaskets(ctx context.Context, req *order_service.ListBas
 			&basketModel.Id,
 			&basketModel.UserId,
 			&basketModel.Status,
+			&basketModel.CurrencyCode,
 			&basketModel.CreatedAt,
 			&basketModel.UpdatedAt,
 			&basketModel.DeletedAt,
@@ -220,13 +271,17 @@ func (r *BasketRepo) ListBaskets(ctx context.Context, req *order_service.ListBas
 }
 
 func (r *BasketRepo) UpdateBasketStatus(ctx context.Context, req *order_service.UpdateBasketStatusRequest) (*order_service.Basket, error) {
+	if err := checkBasketStatus(req.Status); err != nil {
+		return nil, err
+	}
+
 	query := `
 		UPDATE baskets
 		SET 
 			status = $1,
 			updated_at = NOW()
 		WHERE id = $2 AND deleted_at = 0
-		RETURNING id, user_id, status, created_at, updated_at
+		RETURNING id, user_id, status, currency_code, created_at, updated_at
 	`
 
 	var basketModel models.Basket
@@ -238,6 +293,7 @@ func (r *BasketRepo) UpdateBasketStatus(ctx context.Context, req *order_service.
 		&basketModel.Id,
 		&basketModel.UserId,
 		&basketModel.Status,
+		&basketModel.CurrencyCode,
 		&basketModel.CreatedAt,
 		&basketModel.UpdatedAt,
 	)
@@ -252,24 +308,106 @@ func (r *BasketRepo) UpdateBasketStatus(ctx context.Context, req *order_service.
 	return makeBasketProto(basketModel), nil
 }
 
+// checkBasketStatus rejects putting a basket in the CHECKED_OUT status directly. A basket is
+// only checked out by ConvertBasketToOrderItems, which also publishes basket.checked_out.
+func checkBasketStatus(status string) error {
+	if status == "CHECKED_OUT" {
+		return fmt.Errorf("%w: baskets are checked out by converting them to an order", storage.ErrInvalidBasketStatus)
+	}
+	return nil
+}
+
+// GetBasketSummary prices a basket at the current prices of its products and promotions,
+// the same way it would be priced if it were checked out now.
+func (r *BasketRepo) GetBasketSummary(ctx context.Context, req *order_service.GetBasketSummaryRequest) (*order_service.GetBasketSummaryResponse, error) {
+	var currencyCode string
+	err := r.db.QueryRow(ctx, `
+		SELECT currency_code
+		FROM baskets
+		WHERE id = $1 AND deleted_at = 0
+	`, req.Id).Scan(&currencyCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get basket: %w", err)
+	}
+
+	rate, err := r.rates.Rate(ctx, money.DefaultCurrency, currencyCode)
+	if err != nil {
+		return nil, err
+	}
+
+	basketItems, err := listAllBasketItems(ctx, r.db, req.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get basket items: %w", err)
+	}
+
+	summary := &order_service.GetBasketSummaryResponse{
+		BasketId:     req.Id,
+		CurrencyCode: currencyCode,
+	}
+	var subtotal, savings, total money.Amount
+	validity := newPromotionValidity()
+	for _, basketItem := range basketItems {
+		product, err := getProduct(ctx, r.db, basketItem.ProductId)
+		if err != nil {
+			return nil, err
+		}
+		line, err := basketItemLine(ctx, r.db, basketItem, product, rate, validity)
+		if err != nil {
+			return nil, err
+		}
+		priced := r.pricing.PriceLine(line)
+
+		for _, promotion := range line.Promotions {
+			if promotion.Ineligible != "" {
+				summary.Warnings = append(summary.Warnings, fmt.Sprintf("%s: %s", product.Name, promotion.Ineligible))
+			}
+		}
+		if added := amountFromProto(basketItem.UnitPrice); added != priced.UnitPrice {
+			summary.Warnings = append(summary.Warnings, fmt.Sprintf("%s: price changed from %s to %s %s",
+				product.Name, added, priced.UnitPrice, currencyCode))
+		}
+
+		subtotal += priced.BasePrice.Times(priced.Quantity)
+		savings += priced.Savings()
+		total += priced.Total
+		summary.Lines = append(summary.Lines, &order_service.BasketLineSummary{
+			BasketItemId:  basketItem.Id,
+			ProductId:     basketItem.ProductId,
+			Quantity:      basketItem.Quantity,
+			BaseUnitPrice: makeMoneyProto(priced.BasePrice, currencyCode),
+			UnitPrice:     makeMoneyProto(priced.UnitPrice, currencyCode),
+			TotalPrice:    makeMoneyProto(priced.Total, currencyCode),
+			Savings:       makeMoneyProto(priced.Savings(), currencyCode),
+			Promotions:    makePromotionStepProtos(priced.Steps, currencyCode),
+		})
+	}
+
+	summary.Subtotal = makeMoneyProto(subtotal, currencyCode)
+	summary.Savings = makeMoneyProto(savings, currencyCode)
+	summary.Total = makeMoneyProto(total, currencyCode)
+	return summary, nil
+}
+
 // Convert db model to proto model
 func makeBasketProto(basket models.Basket) *order_service.Basket {
 	return &order_service.Basket{
-		Id:        basket.Id,
-		UserId:    basket.UserId,
-		Status:    basket.Status,
-		CreatedAt: timestamppb.New(basket.CreatedAt),
-		UpdatedAt: timestamppb.New(basket.UpdatedAt),
+		Id:           basket.Id,
+		UserId:       basket.UserId,
+		Status:       basket.Status,
+		CurrencyCode: basket.CurrencyCode,
+		CreatedAt:    timestamppb.New(basket.CreatedAt),
+		UpdatedAt:    timestamppb.New(basket.UpdatedAt),
 	}
 }
 
 // Convert proto model to db model
 func makeBasketModel(basket *order_service.Basket) models.Basket {
 	return models.Basket{
-		Id:        basket.Id,
-		UserId:    basket.UserId,
-		Status:    basket.Status,
-		CreatedAt: basket.CreatedAt.AsTime(),
-		UpdatedAt: basket.UpdatedAt.AsTime(),
+		Id:           basket.Id,
+		UserId:       basket.UserId,
+		Status:       basket.Status,
+		CurrencyCode: basket.CurrencyCode,
+		CreatedAt:    basket.CreatedAt.AsTime(),
+		UpdatedAt:    basket.UpdatedAt.AsTime(),
 	}
 }
@@ -3,23 +3,39 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"log"
 	"time"
 
+	"github.com/flash_sale/flash_sale_order_service/currency"
 	"github.com/flash_sale/flash_sale_order_service/genproto/order_service"
 	"github.com/flash_sale/flash_sale_order_service/models"
+	"github.com/flash_sale/flash_sale_order_service/money"
+	"github.com/flash_sale/flash_sale_order_service/pricing"
+	"github.com/flash_sale/flash_sale_order_service/storage"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type BasketItemRepo struct {
-	db *pgx.Conn
+	db         *pgxpool.Pool
+	stockCache storage.StockCacheI
+	rules      FlashSaleRules
+	rates      currency.ExchangeRateProvider
+	pricing    *pricing.Engine
 }
 
-func NewBasketItemRepo(db *pgx.Conn) *BasketItemRepo {
+func NewBasketItemRepo(db *pgxpool.Pool, stockCache storage.StockCacheI, rules FlashSaleRules, rates currency.ExchangeRateProvider, engine *pricing.Engine) *BasketItemRepo {
 	return &BasketItemRepo{
-		db: db,
+		db:         db,
+		stockCache: stockCache,
+		rules:      rules,
+		rates:      rates,
+		pricing:    engine,
 	}
 }
 
@@ -28,6 +44,118 @@ func (r *BasketItemRepo) CreateBasketItem(ctx context.Context, req *order_servic
 		req.BasketItem.Id = uuid.NewString()
 	}
 
+	var userID, currencyCode string
+	err := r.db.QueryRow(ctx, `
+		SELECT user_id, currency_code
+		FROM baskets
+		WHERE id = $1 AND deleted_at = 0
+	`, req.BasketItem.BasketId).Scan(&userID, &currencyCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get basket: %w", err)
+	}
+	if req.BasketItem.Quantity <= 0 {
+		return nil, fmt.Errorf("%w: quantity must be positive", storage.ErrInvalidQuantity)
+	}
+
+	// Prices are worked out here, the same way the order will be priced; the client's are ignored
+	rate, err := r.rates.Rate(ctx, money.DefaultCurrency, currencyCode)
+	if err != nil {
+		return nil, err
+	}
+	product, err := getProduct(ctx, r.db, req.BasketItem.ProductId)
+	if err != nil {
+		return nil, err
+	}
+	line, err := basketItemLine(ctx, r.db, req.BasketItem, product, rate, newPromotionValidity())
+	if err != nil {
+		return nil, err
+	}
+	priced := r.pricing.PriceLine(line)
+	req.BasketItem.UnitPrice = makeMoneyProto(priced.UnitPrice, currencyCode)
+	req.BasketItem.TotalPrice = makeMoneyProto(priced.Total, currencyCode)
+
+	if hasFlashSaleLines(req.BasketItem) {
+		err = checkPurchaseLimits(ctx, r.db, r.rules, userID, []*order_service.BasketItem{req.BasketItem}, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.rules.HoldTTL > 0 &&
+		req.BasketItem.ProductType == "FLASH_SALE" &&
+		priced.Has(pricing.FlashSale) {
+		return r.createBasketItemWithStockHold(ctx, req.BasketItem, currencyCode)
+	}
+
+	basketItemModel, err := insertBasketItem(ctx, r.db, req.BasketItem, currencyCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return makeBasketItemProto(basketItemModel), nil
+}
+
+// createBasketItemWithStockHold inserts a flash sale basket item and holds its units
+// in the same transaction, so the item is either added with its stock or not at all.
+func (r *BasketItemRepo) createBasketItemWithStockHold(ctx context.Context, item *order_service.BasketItem, currencyCode string) (*order_service.BasketItem, error) {
+	undoCache, err := r.reserveCachedStock(ctx, item.FlashSaleEventProductId, item.Quantity)
+	if err != nil {
+		return nil, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			undoCache()
+		}
+	}()
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	basketItemModel, err := insertBasketItem(ctx, tx, item, currencyCode)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := placeStockHold(ctx, tx, basketItemModel.Id, item.FlashSaleEventProductId, item.Quantity, r.rules.HoldTTL); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	committed = true
+
+	return makeBasketItemProto(basketItemModel), nil
+}
+
+// reserveCachedStock takes quantity units of a flash sale product off the stock cache ahead of
+// holding them in PostgreSQL. The returned func gives them back, for when the hold is not committed.
+func (r *BasketItemRepo) reserveCachedStock(ctx context.Context, flashSaleEventProductID string, quantity int32) (func(), error) {
+	undo := func() {}
+	if r.stockCache == nil {
+		return undo, nil
+	}
+	err := r.stockCache.ReserveFlashSaleStock(ctx, flashSaleEventProductID, quantity)
+	if errors.Is(err, storage.ErrStockNotCached) {
+		return undo, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		if err := r.stockCache.ReleaseFlashSaleStock(ctx, flashSaleEventProductID, quantity); err != nil {
+			log.Printf("failed to release cached stock of flash sale product %s: %v", flashSaleEventProductID, err)
+		}
+	}, nil
+}
+
+// insertBasketItem adds an item to a basket whose prices are in currencyCode.
+func insertBasketItem(ctx context.Context, db querier, item *order_service.BasketItem, currencyCode string) (models.BasketItem, error) {
 	query := `
 		INSERT INTO basket_items (
 			id,
@@ -38,25 +166,27 @@ func (r *BasketItemRepo) CreateBasketItem(ctx context.Context, req *order_servic
 			quantity,
 			unit_price,
 			total_price,
+			currency_code,
 			product_type,
 			created_at,
 			updated_at,
 			deleted_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW(), 0
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW(), 0
 		) RETURNING id, created_at, updated_at
 	`
 
 	flashSaleEventProductID := sql.NullString{
-		String: req.BasketItem.FlashSaleEventProductId,
-		Valid:  req.BasketItem.FlashSaleEventProductId != "",
+		String: item.FlashSaleEventProductId,
+		Valid:  item.FlashSaleEventProductId != "",
 	}
 	discountProductID := sql.NullString{
-		String: req.BasketItem.DiscountProductId,
-		Valid:  req.BasketItem.DiscountProductId != "",
+		String: item.DiscountProductId,
+		Valid:  item.DiscountProductId != "",
 	}
-	basketItemModel := makeBasketItemModel(req.BasketItem)
-	err := r.db.QueryRow(ctx, query,
+	basketItemModel := makeBasketItemModel(item)
+	basketItemModel.CurrencyCode = currencyCode
+	err := db.QueryRow(ctx, query,
 		basketItemModel.Id,
 		basketItemModel.BasketId,
 		basketItemModel.ProductId,
@@ -65,14 +195,11 @@ func (r *BasketItemRepo) CreateBasketItem(ctx context.Context, req *order_servic
 		basketItemModel.Quantity,
 		basketItemModel.UnitPrice,
 		basketItemModel.TotalPrice,
+		basketItemModel.CurrencyCode,
 		basketItemModel.ProductType,
 	).Scan(&basketItemModel.Id, &basketItemModel.CreatedAt, &basketItemModel.UpdatedAt)
 
-	if err != nil {
-		return nil, err
-	}
-
-	return makeBasketItemProto(basketItemModel), nil
+	return basketItemModel, err
 }
 func (r *BasketItemRepo) GetBasketItem(ctx context.Context, req *order_service.GetBasketItemRequest) (*order_service.BasketItem, error) {
 	var (
@@ -92,6 +219,7 @@ func (r *BasketItemRepo) GetBasketItem(ctx context.Context, req *order_service.G
 			quantity,
 			unit_price,
 			total_price,
+			currency_code,
 			product_type,
 			created_at,
 			updated_at,
@@ -109,6 +237,7 @@ func (r *BasketItemRepo) GetBasketItem(ctx context.Context, req *order_service.G
 		&basketItemModel.Quantity,
 		&basketItemModel.UnitPrice,
 		&basketItemModel.TotalPrice,
+		&basketItemModel.CurrencyCode,
 		&basketItemModel.ProductType,
 		&basketItemModel.CreatedAt,
 		&basketItemModel.UpdatedAt,
@@ -133,21 +262,198 @@ func (r *BasketItemRepo) GetBasketItem(ctx context.Context, req *order_service.G
 	return makeBasketItemProto(basketItemModel), nil
 }
 func (r *BasketItemRepo) DeleteBasketItem(ctx context.Context, req *order_service.DeleteBasketItemRequest) (*order_service.DeleteBasketItemResponse, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	query := `
 		UPDATE basket_items
         SET deleted_at = $1
         WHERE id = $2 AND deleted_at = 0
 	`
 
-	_, err := r.db.Exec(ctx, query, time.Now().Unix(), req.Id)
+	_, err = tx.Exec(ctx, query, time.Now().Unix(), req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Give back any stock the item was holding
+	releasedHolds, err := releaseBasketItemStockHold(ctx, tx, req.Id)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	releaseCachedStockHolds(ctx, r.stockCache, releasedHolds)
+
 	return &order_service.DeleteBasketItemResponse{
 		Message: "Basket item deleted successfully",
 	}, nil
 }
+
+// UpdateBasketItemQuantity changes how many units a basket item is for and prices it again. The
+// stock hold of a flash sale item is replaced by one for the new quantity, so it never holds more
+// or fewer units than the item is for.
+func (r *BasketItemRepo) UpdateBasketItemQuantity(ctx context.Context, req *order_service.UpdateBasketItemQuantityRequest) (*order_service.BasketItem, error) {
+	if req.Quantity <= 0 {
+		return nil, fmt.Errorf("%w: quantity must be positive", storage.ErrInvalidQuantity)
+	}
+
+	current, err := r.GetBasketItem(ctx, &order_service.GetBasketItemRequest{Id: req.Id})
+	if err != nil {
+		return nil, err
+	}
+
+	var userID, currencyCode string
+	err = r.db.QueryRow(ctx, `
+		SELECT user_id, currency_code
+		FROM baskets
+		WHERE id = $1 AND deleted_at = 0
+	`, current.BasketId).Scan(&userID, &currencyCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get basket: %w", err)
+	}
+
+	item := proto.Clone(current).(*order_service.BasketItem)
+	item.Quantity = req.Quantity
+
+	// Priced again, since promotions can depend on the quantity
+	rate, err := r.rates.Rate(ctx, money.DefaultCurrency, currencyCode)
+	if err != nil {
+		return nil, err
+	}
+	product, err := getProduct(ctx, r.db, item.ProductId)
+	if err != nil {
+		return nil, err
+	}
+	line, err := basketItemLine(ctx, r.db, item, product, rate, newPromotionValidity())
+	if err != nil {
+		return nil, err
+	}
+	priced := r.pricing.PriceLine(line)
+	item.UnitPrice = makeMoneyProto(priced.UnitPrice, currencyCode)
+	item.TotalPrice = makeMoneyProto(priced.Total, currencyCode)
+
+	// The current quantity already counts towards the limits, so only the units added are checked
+	if hasFlashSaleLines(item) && item.Quantity > current.Quantity {
+		added := proto.Clone(item).(*order_service.BasketItem)
+		added.Quantity = item.Quantity - current.Quantity
+		err = checkPurchaseLimits(ctx, r.db, r.rules, userID, []*order_service.BasketItem{added}, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hold := r.rules.HoldTTL > 0 &&
+		item.ProductType == "FLASH_SALE" &&
+		priced.Has(pricing.FlashSale)
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	basketItemModel := makeBasketItemModel(item)
+	err = tx.QueryRow(ctx, `
+		UPDATE basket_items
+		SET quantity = $1,
+			unit_price = $2,
+			total_price = $3,
+			updated_at = NOW()
+		WHERE id = $4 AND deleted_at = 0
+		RETURNING updated_at
+	`, basketItemModel.Quantity, basketItemModel.UnitPrice, basketItemModel.TotalPrice, basketItemModel.Id).Scan(&basketItemModel.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	basketItemModel.CurrencyCode = currencyCode
+
+	// The old hold goes back to the stock first, so the new one can use its units
+	releasedHolds, err := releaseBasketItemStockHold(ctx, tx, item.Id)
+	if err != nil {
+		return nil, err
+	}
+	// The new hold runs out when the old one would have, so changing the quantity never extends it
+	ttl := r.rules.HoldTTL
+	released := make(map[string]int32)
+	for _, releasedHold := range releasedHolds {
+		released[releasedHold.FlashSaleEventProductId] += releasedHold.Quantity
+		ttl = min(ttl, time.Until(releasedHold.ExpiresAt))
+	}
+
+	committed := false
+	if hold && ttl > 0 {
+		// The units of the old hold are still taken from the stock cache, so the new hold
+		// keeps them and only the units added are taken from it
+		kept := min(released[item.FlashSaleEventProductId], item.Quantity)
+		if released[item.FlashSaleEventProductId] -= kept; released[item.FlashSaleEventProductId] == 0 {
+			delete(released, item.FlashSaleEventProductId)
+		}
+		if added := item.Quantity - kept; added > 0 {
+			undoCache, err := r.reserveCachedStock(ctx, item.FlashSaleEventProductId, added)
+			if err != nil {
+				return nil, err
+			}
+			defer func() {
+				if !committed {
+					undoCache()
+				}
+			}()
+		}
+
+		err = placeStockHold(ctx, tx, item.Id, item.FlashSaleEventProductId, item.Quantity, ttl)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	committed = true
+	releaseCachedFlashSaleStock(ctx, r.stockCache, released)
+
+	return makeBasketItemProto(basketItemModel), nil
+}
+
+// ClearBasketItems deletes every item of a basket and gives back the stock they were holding.
+func (r *BasketItemRepo) ClearBasketItems(ctx context.Context, req *order_service.ClearBasketItemsRequest) (*order_service.ClearBasketItemsResponse, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	releasedHolds, err := releaseBasketStockHolds(ctx, tx, req.BasketId)
+	if err != nil {
+		return nil, err
+	}
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE basket_items
+		SET deleted_at = $1
+		WHERE basket_id = $2 AND deleted_at = 0
+	`, time.Now().Unix(), req.BasketId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	releaseCachedStockHolds(ctx, r.stockCache, releasedHolds)
+
+	return &order_service.ClearBasketItemsResponse{
+		Message: "Basket items deleted successfully",
+		Deleted: int32(tag.RowsAffected()),
+	}, nil
+}
+
 func (r *BasketItemRepo) ListBasketItems(ctx context.Context, req *order_service.ListBasketItemsRequest) (*order_service.ListBasketItemsResponse, error) {
 	var args []interface{}
 	count := 1
@@ -161,6 +467,7 @@ func (r *BasketItemRepo) ListBasketItems(ctx context.Context, req *order_service
 			quantity,
 			unit_price,
 			total_price,
+			currency_code,
 			product_type,
 			created_at,
 			updated_at,
@@ -222,6 +529,7 @@ func (r *BasketItemRepo) ListBasketItems(ctx context.Context, req *order_service
 			&basketItemModel.Quantity,
 			&basketItemModel.UnitPrice,
 			&basketItemModel.TotalPrice,
+			&basketItemModel.CurrencyCode,
 			&basketItemModel.ProductType,
 			&basketItemModel.CreatedAt,
 			&basketItemModel.UpdatedAt,
@@ -248,6 +556,74 @@ func (r *BasketItemRepo) ListBasketItems(ctx context.Context, req *order_service
 	}, nil
 }
 
+// listAllBasketItems returns every live item of a basket, without pagination.
+func listAllBasketItems(ctx context.Context, db querier, basketID string) ([]*order_service.BasketItem, error) {
+	query := `
+		SELECT 
+			id,
+			basket_id,
+			product_id,
+			flash_sale_event_product_id,
+			discount_product_id,
+			quantity,
+			unit_price,
+			total_price,
+			currency_code,
+			product_type,
+			created_at,
+			updated_at,
+			deleted_at
+		FROM basket_items
+		WHERE basket_id = $1 AND deleted_at = 0
+		ORDER BY created_at
+	`
+
+	rows, err := db.Query(ctx, query, basketID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var basketItemList []*order_service.BasketItem
+
+	for rows.Next() {
+		var (
+			basketItemModel         models.BasketItem
+			flashSaleEventProductID sql.NullString
+			discountProductID       sql.NullString
+		)
+		err = rows.Scan(
+			&basketItemModel.Id,
+			&basketItemModel.BasketId,
+			&basketItemModel.ProductId,
+			&flashSaleEventProductID,
+			&discountProductID,
+			&basketItemModel.Quantity,
+			&basketItemModel.UnitPrice,
+			&basketItemModel.TotalPrice,
+			&basketItemModel.CurrencyCode,
+			&basketItemModel.ProductType,
+			&basketItemModel.CreatedAt,
+			&basketItemModel.UpdatedAt,
+			&basketItemModel.DeletedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if flashSaleEventProductID.Valid {
+			basketItemModel.FlashSaleEventProductId = flashSaleEventProductID.String
+		}
+		if discountProductID.Valid {
+			basketItemModel.DiscountProductId = discountProductID.String
+		}
+
+		basketItemList = append(basketItemList, makeBasketItemProto(basketItemModel))
+	}
+
+	return basketItemList, rows.Err()
+}
+
 // Convert db model to proto model
 func makeBasketItemProto(item models.BasketItem) *order_service.BasketItem {
 	return &order_service.BasketItem{
@@ -257,8 +633,8 @@ func makeBasketItemProto(item models.BasketItem) *order_service.BasketItem {
 		FlashSaleEventProductId: item.FlashSaleEventProductId,
 		DiscountProductId:       item.DiscountProductId,
 		Quantity:                item.Quantity,
-		UnitPrice:               item.UnitPrice,
-		TotalPrice:              item.TotalPrice,
+		UnitPrice:               makeMoneyProto(item.UnitPrice, item.CurrencyCode),
+		TotalPrice:              makeMoneyProto(item.TotalPrice, item.CurrencyCode),
 		ProductType:             item.ProductType,
 		CreatedAt:               timestamppb.New(item.CreatedAt),
 		UpdatedAt:               timestamppb.New(item.UpdatedAt),
@@ -274,8 +650,8 @@ func makeBasketItemModel(item *order_service.BasketItem) models.BasketItem {
 		FlashSaleEventProductId: item.FlashSaleEventProductId,
 		DiscountProductId:       item.DiscountProductId,
 		Quantity:                item.Quantity,
-		UnitPrice:               item.UnitPrice,
-		TotalPrice:              item.TotalPrice,
+		UnitPrice:               amountFromProto(item.UnitPrice),
+		TotalPrice:              amountFromProto(item.TotalPrice),
 		ProductType:             item.ProductType,
 		CreatedAt:               item.CreatedAt.AsTime(),
 		UpdatedAt:               item.UpdatedAt.AsTime(),
@@ -0,0 +1,639 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/flash_sale/flash_sale_order_service/currency"
+	"github.com/flash_sale/flash_sale_order_service/genproto/order_service"
+	"github.com/flash_sale/flash_sale_order_service/models"
+	"github.com/flash_sale/flash_sale_order_service/money"
+	"github.com/flash_sale/flash_sale_order_service/pricing"
+	"github.com/flash_sale/flash_sale_order_service/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type CouponRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewCouponRepo(db *pgxpool.Pool) *CouponRepo {
+	return &CouponRepo{
+		db: db,
+	}
+}
+
+const couponColumns = `
+	id,
+	code,
+	effect,
+	percentage,
+	amount,
+	min_basket_value,
+	currency_code,
+	max_uses,
+	max_uses_per_user,
+	times_used,
+	starts_at,
+	ends_at,
+	is_active,
+	created_at,
+	updated_at
+`
+
+func (r *CouponRepo) CreateCoupon(ctx context.Context, req *order_service.CreateCouponRequest) (*order_service.Coupon, error) {
+	if req.Coupon.Id == "" {
+		req.Coupon.Id = uuid.NewString()
+	}
+
+	couponModel, err := makeCouponModel(req.Coupon)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO coupons (
+			id,
+			code,
+			effect,
+			percentage,
+			amount,
+			min_basket_value,
+			currency_code,
+			max_uses,
+			max_uses_per_user,
+			times_used,
+			starts_at,
+			ends_at,
+			is_active,
+			created_at,
+			updated_at,
+			deleted_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, 0, $10, $11, $12, NOW(), NOW(), 0
+		) RETURNING ` + couponColumns
+
+	row := r.db.QueryRow(ctx, query,
+		couponModel.Id,
+		couponModel.Code,
+		couponModel.Effect,
+		couponModel.Percentage,
+		couponModel.Amount,
+		couponModel.MinBasketValue,
+		couponModel.CurrencyCode,
+		couponModel.MaxUses,
+		couponModel.MaxUsesPerUser,
+		couponModel.StartsAt,
+		couponModel.EndsAt,
+		couponModel.IsActive,
+	)
+	if err := scanCoupon(row, &couponModel); err != nil {
+		return nil, err
+	}
+
+	return makeCouponProto(couponModel), nil
+}
+
+func (r *CouponRepo) GetCoupon(ctx context.Context, req *order_service.GetCouponRequest) (*order_service.Coupon, error) {
+	var couponModel models.Coupon
+
+	query := `SELECT ` + couponColumns + ` FROM coupons WHERE id = $1 AND deleted_at = 0`
+
+	err := scanCoupon(r.db.QueryRow(ctx, query, req.Id), &couponModel)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", storage.ErrCouponNotFound, req.Id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return makeCouponProto(couponModel), nil
+}
+
+// UpdateCoupon updates everything about a coupon except how many times it was used.
+func (r *CouponRepo) UpdateCoupon(ctx context.Context, req *order_service.UpdateCouponRequest) (*order_service.Coupon, error) {
+	couponModel, err := makeCouponModel(req.Coupon)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE coupons
+		SET
+			code = $1,
+			effect = $2,
+			percentage = $3,
+			amount = $4,
+			min_basket_value = $5,
+			currency_code = $6,
+			max_uses = $7,
+			max_uses_per_user = $8,
+			starts_at = $9,
+			ends_at = $10,
+			is_active = $11,
+			updated_at = NOW()
+		WHERE id = $12 AND deleted_at = 0
+		RETURNING ` + couponColumns
+
+	row := r.db.QueryRow(ctx, query,
+		couponModel.Code,
+		couponModel.Effect,
+		couponModel.Percentage,
+		couponModel.Amount,
+		couponModel.MinBasketValue,
+		couponModel.CurrencyCode,
+		couponModel.MaxUses,
+		couponModel.MaxUsesPerUser,
+		couponModel.StartsAt,
+		couponModel.EndsAt,
+		couponModel.IsActive,
+		couponModel.Id,
+	)
+	err = scanCoupon(row, &couponModel)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", storage.ErrCouponNotFound, couponModel.Id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return makeCouponProto(couponModel), nil
+}
+
+func (r *CouponRepo) DeleteCoupon(ctx context.Context, req *order_service.DeleteCouponRequest) (*order_service.DeleteCouponResponse, error) {
+	query := `
+		UPDATE coupons
+        SET deleted_at = $1
+        WHERE id = $2 AND deleted_at = 0
+	`
+
+	_, err := r.db.Exec(ctx, query, time.Now().Unix(), req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &order_service.DeleteCouponResponse{
+		Message: "Coupon deleted successfully",
+	}, nil
+}
+
+func (r *CouponRepo) ListCoupons(ctx context.Context, req *order_service.ListCouponsRequest) (*order_service.ListCouponsResponse, error) {
+	var args []interface{}
+	count := 1
+	query := `SELECT ` + couponColumns + ` FROM coupons WHERE 1=1 AND deleted_at = 0`
+
+	filter := ""
+
+	if req.ActiveOnly {
+		filter += " AND is_active AND starts_at <= NOW() AND ends_at > NOW()"
+	}
+
+	query += filter
+
+	// Handle invalid page or limit values
+	if req.Page <= 0 {
+		req.Page = 1 // Default to page 1
+	}
+	if req.Limit <= 0 {
+		req.Limit = 10 // Default to a limit of 10
+	}
+
+	totalCountQuery := "SELECT count(*) FROM coupons WHERE 1=1 AND deleted_at = 0" + filter
+	var totalCount int
+	err := r.db.QueryRow(ctx, totalCountQuery, args...).Scan(&totalCount)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add LIMIT and OFFSET for pagination using the proto fields
+	query += fmt.Sprintf(" ORDER BY created_at LIMIT $%d OFFSET $%d", count, count+1)
+	args = append(args, req.Limit, (req.Page-1)*req.Limit)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var couponList []*order_service.Coupon
+
+	for rows.Next() {
+		var couponModel models.Coupon
+		if err := scanCoupon(rows, &couponModel); err != nil {
+			return nil, err
+		}
+		couponList = append(couponList, makeCouponProto(couponModel))
+	}
+
+	return &order_service.ListCouponsResponse{
+		Coupons: couponList,
+		Total:   int32(totalCount),
+	}, rows.Err()
+}
+
+// applyCoupons redeems promo codes against the lines just added to an order and records
+// them as the order's applied promotions. The pricing engine decides which lines each
+// coupon may be taken off; a coupon it refuses is reported as not applicable.
+func applyCoupons(ctx context.Context, tx pgx.Tx, engine *pricing.Engine, rates currency.ExchangeRateProvider, orderID, userID, currencyCode string, codes []string, lines []pricing.LineResult) error {
+	if len(codes) == 0 {
+		return nil
+	}
+
+	var subtotal money.Amount
+	for _, line := range lines {
+		subtotal += line.Total
+	}
+
+	coupons := make(map[string]models.Coupon, len(codes))
+	var promotions []pricing.Promotion
+	for _, code := range codes {
+		code = normalizeCouponCode(code)
+
+		// Locking the coupon serializes its redemptions, so its limits hold under concurrent checkouts
+		var coupon models.Coupon
+		err := scanCoupon(tx.QueryRow(ctx, `
+			SELECT `+couponColumns+`
+			FROM coupons
+			WHERE code = $1 AND deleted_at = 0
+			FOR UPDATE
+		`, code), &coupon)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%w: %s", storage.ErrCouponNotFound, code)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get coupon: %w", err)
+		}
+		if _, ok := coupons[coupon.Id]; ok {
+			return fmt.Errorf("%w: %s is given more than once", storage.ErrCouponNotApplicable, code)
+		}
+		coupons[coupon.Id] = coupon
+
+		if err := checkCouponUsable(ctx, tx, coupon, userID, time.Now()); err != nil {
+			return err
+		}
+
+		rate, err := rates.Rate(ctx, coupon.CurrencyCode, currencyCode)
+		if err != nil {
+			return err
+		}
+		if minimum := coupon.MinBasketValue.Convert(rate); subtotal < minimum {
+			return fmt.Errorf("%w: %s needs a basket of at least %s %s", storage.ErrCouponNotApplicable, code, minimum, currencyCode)
+		}
+
+		promotions = append(promotions, pricing.Promotion{
+			ID:      coupon.Id,
+			Kind:    pricing.Coupon,
+			Effect:  pricing.Effect(coupon.Effect),
+			Percent: coupon.Percentage,
+			Amount:  coupon.Amount.Convert(rate),
+		})
+	}
+
+	priced := engine.PriceBasket(lines, promotions)
+	for _, step := range priced.Steps {
+		coupon := coupons[step.PromotionID]
+		if !step.Applied {
+			return fmt.Errorf("%w: %s: %s", storage.ErrCouponNotApplicable, coupon.Code, step.Reason)
+		}
+
+		_, err := tx.Exec(ctx, `
+			UPDATE coupons
+			SET times_used = times_used + 1,
+				updated_at = NOW()
+			WHERE id = $1
+		`, coupon.Id)
+		if err != nil {
+			return fmt.Errorf("failed to redeem coupon: %w", err)
+		}
+
+		_, err = tx.Exec(ctx, `
+			INSERT INTO order_promotions (
+				id,
+				order_id,
+				coupon_id,
+				user_id,
+				code,
+				effect,
+				discount,
+				currency_code,
+				created_at
+			) VALUES (
+				$1, $2, $3, $4, $5, $6, $7, $8, NOW()
+			)
+		`, uuid.NewString(), orderID, coupon.Id, userID, coupon.Code, coupon.Effect, step.Discount, currencyCode)
+		if err != nil {
+			return fmt.Errorf("failed to record applied promotion: %w", err)
+		}
+	}
+
+	_, err := tx.Exec(ctx, `
+		UPDATE orders
+		SET discount_total = (
+				SELECT COALESCE(SUM(discount), 0)
+				FROM order_promotions
+				WHERE order_id = $1
+			),
+			updated_at = NOW()
+		WHERE id = $1
+	`, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to update order discount: %w", err)
+	}
+
+	return nil
+}
+
+// checkCouponUsable checks a coupon's validity window and usage limits for a user.
+func checkCouponUsable(ctx context.Context, tx pgx.Tx, coupon models.Coupon, userID string, now time.Time) error {
+	if !coupon.IsActive || now.Before(coupon.StartsAt) || !now.Before(coupon.EndsAt) {
+		return fmt.Errorf("%w: %s is not valid at this time", storage.ErrCouponNotApplicable, coupon.Code)
+	}
+	if coupon.MaxUses > 0 && coupon.TimesUsed >= coupon.MaxUses {
+		return fmt.Errorf("%w: %s has been used up", storage.ErrCouponNotApplicable, coupon.Code)
+	}
+	if coupon.MaxUsesPerUser == 0 {
+		return nil
+	}
+
+	// Redemptions on cancelled orders were given back and do not count
+	var used int32
+	err := tx.QueryRow(ctx, `
+		SELECT count(*)
+		FROM order_promotions op
+		JOIN orders o ON o.id = op.order_id
+		WHERE op.coupon_id = $1 AND op.user_id = $2 AND o.status <> 'CANCELLED'
+	`, coupon.Id, userID).Scan(&used)
+	if err != nil {
+		return fmt.Errorf("failed to count coupon redemptions: %w", err)
+	}
+	if used >= coupon.MaxUsesPerUser {
+		return fmt.Errorf("%w: %s can be used %d times per user", storage.ErrCouponNotApplicable, coupon.Code, coupon.MaxUsesPerUser)
+	}
+
+	return nil
+}
+
+// reverseCouponRedemptions gives the coupons redeemed against a cancelled order their uses back.
+func reverseCouponRedemptions(ctx context.Context, tx pgx.Tx, orderID string) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE coupons c
+		SET times_used = GREATEST(c.times_used - redeemed.uses, 0),
+			updated_at = NOW()
+		FROM (
+			SELECT coupon_id, count(*) AS uses
+			FROM order_promotions
+			WHERE order_id = $1
+			GROUP BY coupon_id
+		) redeemed
+		WHERE c.id = redeemed.coupon_id
+	`, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to reverse coupon redemptions: %w", err)
+	}
+	return nil
+}
+
+// reduceCouponDiscounts takes amount off the discounts of the coupons applied to an order, in
+// proportion to each, when some of what they were taken off is cancelled.
+func reduceCouponDiscounts(ctx context.Context, tx pgx.Tx, orderID string, amount money.Amount) error {
+	if amount <= 0 {
+		return nil
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, discount
+		FROM order_promotions
+		WHERE order_id = $1
+		ORDER BY created_at, id
+		FOR UPDATE
+	`, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to get applied promotions: %w", err)
+	}
+	var (
+		ids       []string
+		discounts []money.Amount
+		total     money.Amount
+	)
+	for rows.Next() {
+		var (
+			id       string
+			discount money.Amount
+		)
+		if err := rows.Scan(&id, &discount); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to get applied promotions: %w", err)
+		}
+		ids = append(ids, id)
+		discounts = append(discounts, discount)
+		total += discount
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get applied promotions: %w", err)
+	}
+	if total <= 0 {
+		return nil
+	}
+
+	// The last promotion takes what rounding leaves over
+	left := amount
+	for i, id := range ids {
+		share := left
+		if i < len(ids)-1 {
+			share = amount.Share(int64(discounts[i]), int64(total))
+		}
+		if share > discounts[i] {
+			share = discounts[i]
+		}
+		left -= share
+
+		_, err := tx.Exec(ctx, `
+			UPDATE order_promotions
+			SET discount = discount - $1
+			WHERE id = $2
+		`, share, id)
+		if err != nil {
+			return fmt.Errorf("failed to reduce applied promotion: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE orders
+		SET discount_total = (
+				SELECT COALESCE(SUM(discount), 0)
+				FROM order_promotions
+				WHERE order_id = $1
+			),
+			updated_at = NOW()
+		WHERE id = $1
+	`, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to update order discount: %w", err)
+	}
+
+	return nil
+}
+
+// attachOrderPromotions fills in the applied promotions of orders with one query.
+func attachOrderPromotions(ctx context.Context, db querier, orders ...*order_service.Order) error {
+	if len(orders) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*order_service.Order, len(orders))
+	ids := make([]string, 0, len(orders))
+	for _, order := range orders {
+		byID[order.Id] = order
+		ids = append(ids, order.Id)
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT order_id, coupon_id, code, effect, discount, currency_code
+		FROM order_promotions
+		WHERE order_id = ANY($1::uuid[])
+		ORDER BY created_at, id
+	`, ids)
+	if err != nil {
+		return fmt.Errorf("failed to get applied promotions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var promotion models.OrderPromotion
+		err := rows.Scan(
+			&promotion.OrderId,
+			&promotion.CouponId,
+			&promotion.Code,
+			&promotion.Effect,
+			&promotion.Discount,
+			&promotion.CurrencyCode,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to get applied promotions: %w", err)
+		}
+		order := byID[promotion.OrderId]
+		order.AppliedPromotions = append(order.AppliedPromotions, &order_service.AppliedPromotion{
+			CouponId: promotion.CouponId,
+			Code:     promotion.Code,
+			Effect:   promotion.Effect,
+			Discount: makeMoneyProto(promotion.Discount, promotion.CurrencyCode),
+		})
+	}
+
+	return rows.Err()
+}
+
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func scanCoupon(row pgx.Row, coupon *models.Coupon) error {
+	return row.Scan(
+		&coupon.Id,
+		&coupon.Code,
+		&coupon.Effect,
+		&coupon.Percentage,
+		&coupon.Amount,
+		&coupon.MinBasketValue,
+		&coupon.CurrencyCode,
+		&coupon.MaxUses,
+		&coupon.MaxUsesPerUser,
+		&coupon.TimesUsed,
+		&coupon.StartsAt,
+		&coupon.EndsAt,
+		&coupon.IsActive,
+		&coupon.CreatedAt,
+		&coupon.UpdatedAt,
+	)
+}
+
+// Convert db model to proto model
+func makeCouponProto(coupon models.Coupon) *order_service.Coupon {
+	proto := &order_service.Coupon{
+		Id:             coupon.Id,
+		Code:           coupon.Code,
+		Effect:         coupon.Effect,
+		MinBasketValue: makeMoneyProto(coupon.MinBasketValue, coupon.CurrencyCode),
+		MaxUses:        coupon.MaxUses,
+		MaxUsesPerUser: coupon.MaxUsesPerUser,
+		TimesUsed:      coupon.TimesUsed,
+		StartsAt:       timestamppb.New(coupon.StartsAt),
+		EndsAt:         timestamppb.New(coupon.EndsAt),
+		IsActive:       coupon.IsActive,
+		CreatedAt:      timestamppb.New(coupon.CreatedAt),
+		UpdatedAt:      timestamppb.New(coupon.UpdatedAt),
+	}
+	switch coupon.Effect {
+	case "PERCENTAGE":
+		proto.Percentage = coupon.Percentage.String()
+	case "FIXED_AMOUNT":
+		proto.Amount = makeMoneyProto(coupon.Amount, coupon.CurrencyCode)
+	}
+	return proto
+}
+
+// Convert proto model to db model, rejecting coupons that could never be applied
+func makeCouponModel(coupon *order_service.Coupon) (models.Coupon, error) {
+	couponModel := models.Coupon{
+		Id:             coupon.Id,
+		Code:           normalizeCouponCode(coupon.Code),
+		Effect:         coupon.Effect,
+		MinBasketValue: amountFromProto(coupon.MinBasketValue),
+		CurrencyCode:   money.DefaultCurrency,
+		MaxUses:        coupon.MaxUses,
+		MaxUsesPerUser: coupon.MaxUsesPerUser,
+		StartsAt:       coupon.StartsAt.AsTime(),
+		EndsAt:         coupon.EndsAt.AsTime(),
+		IsActive:       coupon.IsActive,
+	}
+
+	// The amounts of a coupon share one currency
+	for _, m := range []*order_service.Money{coupon.Amount, coupon.MinBasketValue} {
+		if m.GetCurrencyCode() != "" {
+			couponModel.CurrencyCode = m.GetCurrencyCode()
+			break
+		}
+	}
+	if !currency.IsValidCode(couponModel.CurrencyCode) {
+		return couponModel, fmt.Errorf("%w: %q", storage.ErrUnsupportedCurrency, couponModel.CurrencyCode)
+	}
+	if err := checkCurrency(couponModel.CurrencyCode, coupon.Amount, coupon.MinBasketValue); err != nil {
+		return couponModel, err
+	}
+
+	if couponModel.Code == "" {
+		return couponModel, fmt.Errorf("%w: code is required", storage.ErrInvalidCoupon)
+	}
+	if couponModel.MaxUses < 0 || couponModel.MaxUsesPerUser < 0 || couponModel.MinBasketValue < 0 {
+		return couponModel, fmt.Errorf("%w: limits must not be negative", storage.ErrInvalidCoupon)
+	}
+	if coupon.StartsAt == nil || coupon.EndsAt == nil || !couponModel.EndsAt.After(couponModel.StartsAt) {
+		return couponModel, fmt.Errorf("%w: ends_at must be after starts_at", storage.ErrInvalidCoupon)
+	}
+
+	switch couponModel.Effect {
+	case "PERCENTAGE":
+		percentage, err := money.ParsePercent(coupon.Percentage)
+		if err != nil || percentage <= 0 || percentage > 100*100 {
+			return couponModel, fmt.Errorf("%w: percentage must be above 0 and at most 100", storage.ErrInvalidCoupon)
+		}
+		couponModel.Percentage = percentage
+	case "FIXED_AMOUNT":
+		couponModel.Amount = amountFromProto(coupon.Amount)
+		if couponModel.Amount <= 0 {
+			return couponModel, fmt.Errorf("%w: amount must be above 0", storage.ErrInvalidCoupon)
+		}
+	case "FREE_SHIPPING":
+	default:
+		return couponModel, fmt.Errorf("%w: unknown effect %q", storage.ErrInvalidCoupon, couponModel.Effect)
+	}
+
+	return couponModel, nil
+}
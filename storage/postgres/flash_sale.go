@@ -0,0 +1,131 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flash_sale/flash_sale_order_service/genproto/order_service"
+	"github.com/flash_sale/flash_sale_order_service/models"
+	"github.com/flash_sale/flash_sale_order_service/storage"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// expiredStockHoldBatchSize caps how many holds one ReleaseExpiredStockHolds call releases.
+const expiredStockHoldBatchSize = 500
+
+type FlashSaleRepo struct {
+	db         *pgxpool.Pool
+	stockCache storage.StockCacheI
+}
+
+func NewFlashSaleRepo(db *pgxpool.Pool, stockCache storage.StockCacheI) *FlashSaleRepo {
+	return &FlashSaleRepo{
+		db:         db,
+		stockCache: stockCache,
+	}
+}
+
+// ListActiveFlashSaleStock returns the available quantity of every product in a running flash sale event.
+func (r *FlashSaleRepo) ListActiveFlashSaleStock(ctx context.Context) ([]*models.FlashSaleStock, error) {
+	query := `
+		SELECT
+			fsep.id,
+			fsep.available_quantity,
+			fse.end_time
+		FROM flash_sale_event_products fsep
+		JOIN flash_sale_events fse ON fsep.event_id = fse.id
+		WHERE fsep.deleted_at = 0
+			AND fse.deleted_at = 0
+			AND fse.status = 'ACTIVE'
+			AND fse.end_time > NOW()
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stockList []*models.FlashSaleStock
+
+	for rows.Next() {
+		var stock models.FlashSaleStock
+		err = rows.Scan(
+			&stock.FlashSaleEventProductId,
+			&stock.AvailableQuantity,
+			&stock.EndTime,
+		)
+		if err != nil {
+			return nil, err
+		}
+		stockList = append(stockList, &stock)
+	}
+
+	return stockList, rows.Err()
+}
+
+// GetFlashSaleStock returns the available and held quantity of a flash sale event product.
+// Held units are already taken out of the available quantity.
+func (r *FlashSaleRepo) GetFlashSaleStock(ctx context.Context, req *order_service.GetFlashSaleStockRequest) (*order_service.GetFlashSaleStockResponse, error) {
+	var (
+		flashSaleEventProduct models.FlashSaleEventProduct
+		reservedQuantity      int32
+	)
+
+	query := `
+		SELECT
+			fsep.id,
+			fsep.available_quantity,
+			fsep.original_stock,
+			COALESCE((
+				SELECT SUM(sh.quantity)
+				FROM stock_holds sh
+				WHERE sh.flash_sale_event_product_id = fsep.id AND sh.status = 'ACTIVE'
+			), 0)
+		FROM flash_sale_event_products fsep
+		WHERE fsep.id = $1 AND fsep.deleted_at = 0
+	`
+
+	err := r.db.QueryRow(ctx, query, req.FlashSaleEventProductId).Scan(
+		&flashSaleEventProduct.Id,
+		&flashSaleEventProduct.AvailableQuantity,
+		&flashSaleEventProduct.OriginalStock,
+		&reservedQuantity,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, pgx.ErrNoRows
+		}
+		return nil, err
+	}
+
+	return &order_service.GetFlashSaleStockResponse{
+		FlashSaleEventProductId: flashSaleEventProduct.Id,
+		AvailableQuantity:       flashSaleEventProduct.AvailableQuantity,
+		ReservedQuantity:        reservedQuantity,
+		OriginalStock:           flashSaleEventProduct.OriginalStock,
+	}, nil
+}
+
+// ReleaseExpiredStockHolds gives the units of expired stock holds back to their flash sale
+// products and returns how many holds were released.
+func (r *FlashSaleRepo) ReleaseExpiredStockHolds(ctx context.Context) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	releasedHolds, err := releaseExpiredStockHolds(ctx, tx, expiredStockHoldBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	releaseCachedStockHolds(ctx, r.stockCache, releasedHolds)
+
+	return len(releasedHolds), nil
+}
@@ -0,0 +1,116 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/flash_sale/flash_sale_order_service/storage"
+	"github.com/jackc/pgx/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// maxIdempotencyKeyLength is the longest idempotency key accepted.
+const maxIdempotencyKeyLength = 255
+
+// Operations idempotency keys are scoped to, so one key can be used for each of them.
+const (
+	createOrderOperation               = "CreateOrder"
+	convertBasketToOrderItemsOperation = "ConvertBasketToOrderItems"
+)
+
+// hashRequest returns the SHA-256 of the deterministic protobuf encoding of a request.
+func hashRequest(req proto.Message) ([]byte, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return sum[:], nil
+}
+
+// claimIdempotencyKey reserves an idempotency key for the request handled in tx. If the key
+// already holds the result of the same request, the result is read into replay and true is
+// returned, and the caller hands it back instead of running the request again.
+//
+// A request sent again while the first one is still running waits here until the first
+// transaction ends. An empty key, or a ttl of 0, claims nothing.
+func claimIdempotencyKey(ctx context.Context, tx pgx.Tx, operation, key string, requestHash []byte, ttl time.Duration, replay proto.Message) (bool, error) {
+	if key == "" || ttl <= 0 {
+		return false, nil
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return false, fmt.Errorf("%w: longer than %d characters", storage.ErrInvalidIdempotencyKey, maxIdempotencyKeyLength)
+	}
+
+	// An expired key is taken over as if it had never been used
+	var claimed string
+	err := tx.QueryRow(ctx, `
+		INSERT INTO idempotency_keys (operation, key, request_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, NOW(), NOW() + make_interval(secs => $4))
+		ON CONFLICT (operation, key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash,
+			response = NULL,
+			created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= NOW()
+		RETURNING key
+	`, operation, key, requestHash, ttl.Seconds()).Scan(&claimed)
+	if err == nil {
+		return false, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return false, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+
+	var storedHash, response []byte
+	err = tx.QueryRow(ctx, `
+		SELECT request_hash, response
+		FROM idempotency_keys
+		WHERE operation = $1 AND key = $2
+	`, operation, key).Scan(&storedHash, &response)
+	if err != nil {
+		return false, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+	if !bytes.Equal(storedHash, requestHash) {
+		return false, fmt.Errorf("%w: %q", storage.ErrIdempotencyKeyReused, key)
+	}
+	if err := proto.Unmarshal(response, replay); err != nil {
+		return false, fmt.Errorf("failed to decode idempotent response: %w", err)
+	}
+	return true, nil
+}
+
+// saveIdempotentResponse stores the result of a request under the idempotency key it claimed.
+// It is saved in the same transaction, so a request that fails leaves the key free for a retry.
+func saveIdempotentResponse(ctx context.Context, tx pgx.Tx, operation, key string, ttl time.Duration, response proto.Message) error {
+	if key == "" || ttl <= 0 {
+		return nil
+	}
+
+	data, err := proto.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("failed to encode idempotent response: %w", err)
+	}
+	_, err = tx.Exec(ctx, `
+		UPDATE idempotency_keys
+		SET response = $3
+		WHERE operation = $1 AND key = $2
+	`, operation, key, data)
+	if err != nil {
+		return fmt.Errorf("failed to save idempotent response: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys removes the idempotency keys whose replay window has passed.
+func (r *OrderRepo) DeleteExpiredIdempotencyKeys(ctx context.Context) (int, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/flash_sale/flash_sale_order_service/genproto/order_service"
+	"github.com/flash_sale/flash_sale_order_service/money"
+	"github.com/flash_sale/flash_sale_order_service/storage"
+)
+
+// makeMoneyProto wraps an amount in its currency.
+func makeMoneyProto(amount money.Amount, currencyCode string) *order_service.Money {
+	return &order_service.Money{
+		MinorUnits:   int64(amount),
+		CurrencyCode: currencyCode,
+	}
+}
+
+// makeExchangeRateProto formats a rate for clients. A rate that has not been fixed yet is empty.
+func makeExchangeRateProto(rate money.Rate) string {
+	if rate == 0 {
+		return ""
+	}
+	return rate.String()
+}
+
+// amountFromProto reads an amount sent by a client. A missing amount is zero.
+func amountFromProto(m *order_service.Money) money.Amount {
+	return money.Amount(m.GetMinorUnits())
+}
+
+// checkCurrency makes sure amounts sent by a client are in the currency of the basket or
+// order they belong to. Amounts without a currency are taken to be in it.
+func checkCurrency(currencyCode string, amounts ...*order_service.Money) error {
+	for _, m := range amounts {
+		if m == nil || m.CurrencyCode == "" || m.CurrencyCode == currencyCode {
+			continue
+		}
+		return fmt.Errorf("%w: %s, expected %s", storage.ErrUnsupportedCurrency, m.CurrencyCode, currencyCode)
+	}
+	return nil
+}
@@ -2,30 +2,72 @@ package postgres
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"time"
 
+	"github.com/flash_sale/flash_sale_order_service/currency"
 	"github.com/flash_sale/flash_sale_order_service/genproto/order_service"
 	"github.com/flash_sale/flash_sale_order_service/models"
+	"github.com/flash_sale/flash_sale_order_service/money"
+	"github.com/flash_sale/flash_sale_order_service/orderstatus"
+	"github.com/flash_sale/flash_sale_order_service/outbox"
+	"github.com/flash_sale/flash_sale_order_service/shipping"
+	"github.com/flash_sale/flash_sale_order_service/storage"
+	"github.com/flash_sale/flash_sale_order_service/tax"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type OrderRepo struct {
-	db *pgx.Conn
+	db             *pgxpool.Pool
+	stockCache     storage.StockCacheI
+	taxes          *tax.Table
+	zones          *shipping.Zones
+	idempotencyTTL time.Duration
 }
 
-func NewOrderRepo(db *pgx.Conn) *OrderRepo {
+func NewOrderRepo(db *pgxpool.Pool, stockCache storage.StockCacheI, taxes *tax.Table, zones *shipping.Zones, idempotencyTTL time.Duration) *OrderRepo {
 	return &OrderRepo{
-		db: db,
+		db:             db,
+		stockCache:     stockCache,
+		taxes:          taxes,
+		zones:          zones,
+		idempotencyTTL: idempotencyTTL,
 	}
 }
 
 func (r *OrderRepo) CreateOrder(ctx context.Context, req *order_service.CreateOrderRequest) (*order_service.Order, error) {
+	// Hash the request as the client sent it, before any defaults are filled in
+	requestHash, err := hashRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
 	if req.Order.Id == "" {
 		req.Order.Id = uuid.NewString()
 	}
+	// Every order starts out PENDING; later statuses are only reached through the allowed transitions
+	if req.Order.Status == "" {
+		req.Order.Status = orderstatus.Pending
+	}
+	if req.Order.Status != orderstatus.Pending {
+		return nil, fmt.Errorf("%w: orders are created as %s, not %q", orderstatus.ErrInvalidInitialStatus, orderstatus.Pending, req.Order.Status)
+	}
+	if req.Order.CurrencyCode == "" {
+		req.Order.CurrencyCode = money.DefaultCurrency
+	}
+	if !currency.IsValidCode(req.Order.CurrencyCode) {
+		return nil, fmt.Errorf("%w: %q", storage.ErrUnsupportedCurrency, req.Order.CurrencyCode)
+	}
+	if err := checkCurrency(req.Order.CurrencyCode, req.Order.TotalPrice); err != nil {
+		return nil, err
+	}
+	if err := resolveDeliveryAddress(ctx, r.db, req.Order); err != nil {
+		return nil, err
+	}
 
 	query := `
 		INSERT INTO orders (
@@ -35,30 +77,79 @@ func (r *OrderRepo) CreateOrder(ctx context.Context, req *order_service.CreateOr
 			delivery_longitude,
 			total_price,
 			status,
+			currency_code,
+			shipping_zone,
 			created_at,
 			updated_at,
 			deleted_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, NOW(), NOW(), 0
+			$1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW(), 0
 		) RETURNING id, created_at, updated_at
 	`
 
 	orderModel := makeOrderModel(req.Order)
+	zone, err := r.deliveryZone(orderModel.DeliveryLatitude, orderModel.DeliveryLongitude)
+	if err != nil {
+		return nil, err
+	}
+	orderModel.ShippingZone = zone
 
-	err := r.db.QueryRow(ctx, query,
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// A retried request gets the order created the first time
+	var replay order_service.Order
+	replayed, err := claimIdempotencyKey(ctx, tx, createOrderOperation, req.IdempotencyKey, requestHash, r.idempotencyTTL, &replay)
+	if err != nil {
+		return nil, err
+	}
+	if replayed {
+		return &replay, nil
+	}
+
+	err = tx.QueryRow(ctx, query,
 		orderModel.Id,
 		orderModel.ClientId,
 		orderModel.DeliveryLatitude,
 		orderModel.DeliveryLongitude,
 		orderModel.TotalPrice,
 		orderModel.Status,
+		orderModel.CurrencyCode,
+		orderModel.ShippingZone,
 	).Scan(&orderModel.Id, &orderModel.CreatedAt, &orderModel.UpdatedAt)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return makeOrderProto(orderModel), nil
+	if err := recordStatusChange(ctx, tx, &orderModel, "", orderModel.ClientId, "order created"); err != nil {
+		return nil, err
+	}
+
+	if req.Order.DeliveryAddress != nil {
+		if err := saveOrderAddress(ctx, tx, orderModel.Id, req.Order.AddressId, req.Order.DeliveryAddress); err != nil {
+			return nil, err
+		}
+	}
+
+	order := makeOrderProto(orderModel)
+	order.AddressId = req.Order.AddressId
+	order.DeliveryAddress = req.Order.DeliveryAddress
+	if err := enqueueEvent(ctx, tx, outbox.TopicOrderCreated, outbox.AggregateOrder, order.Id, &order_service.OrderCreated{Order: order}); err != nil {
+		return nil, err
+	}
+	if err := saveIdempotentResponse(ctx, tx, createOrderOperation, req.IdempotencyKey, r.idempotencyTTL, order); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return order, nil
 }
 
 func (r *OrderRepo) GetOrder(ctx context.Context, req *order_service.GetOrderRequest) (*order_service.Order, error) {
@@ -72,6 +163,13 @@ func (r *OrderRepo) GetOrder(ctx context.Context, req *order_service.GetOrderReq
 			delivery_longitude,
 			total_price,
 			status,
+			currency_code,
+			exchange_rate,
+			discount_total,
+			tax_total,
+			tax_inclusive,
+			shipping_zone,
+			shipping_fee,
 			created_at,
 			updated_at,
 			deleted_at
@@ -86,6 +184,13 @@ func (r *OrderRepo) GetOrder(ctx context.Context, req *order_service.GetOrderReq
 		&orderModel.DeliveryLongitude,
 		&orderModel.TotalPrice,
 		&orderModel.Status,
+		&orderModel.CurrencyCode,
+		&orderModel.ExchangeRate,
+		&orderModel.DiscountTotal,
+		&orderModel.TaxTotal,
+		&orderModel.TaxInclusive,
+		&orderModel.ShippingZone,
+		&orderModel.ShippingFee,
 		&orderModel.CreatedAt,
 		&orderModel.UpdatedAt,
 		&orderModel.DeletedAt,
@@ -98,51 +203,136 @@ func (r *OrderRepo) GetOrder(ctx context.Context, req *order_service.GetOrderReq
 		return nil, err
 	}
 
-	return makeOrderProto(orderModel), nil
+	order := makeOrderProto(orderModel)
+	if err := attachOrderPromotions(ctx, r.db, order); err != nil {
+		return nil, err
+	}
+	if err := attachOrderAddresses(ctx, r.db, order); err != nil {
+		return nil, err
+	}
+
+	return order, nil
 }
 
 func (r *OrderRepo) UpdateOrder(ctx context.Context, req *order_service.UpdateOrderRequest) (*order_service.Order, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := resolveDeliveryAddress(ctx, tx, req.Order); err != nil {
+		return nil, err
+	}
+
+	orderModel := makeOrderModel(req.Order)
+
+	// A full update may not be used to skip the status lifecycle
+	currentStatus, err := lockOrderStatus(ctx, tx, orderModel.Id)
+	if err != nil {
+		return nil, err
+	}
+	if orderModel.Status == "" {
+		orderModel.Status = currentStatus
+	}
+	if err := statusUpdateTransition(currentStatus, orderModel.Status); err != nil {
+		return nil, err
+	}
+
+	var latitude, longitude float64
+	err = tx.QueryRow(ctx, `
+		SELECT delivery_latitude, delivery_longitude
+		FROM orders
+		WHERE id = $1
+	`, orderModel.Id).Scan(&latitude, &longitude)
+	if err != nil {
+		return nil, err
+	}
+	orderModel.ShippingZone, err = r.deliveryZone(orderModel.DeliveryLatitude, orderModel.DeliveryLongitude)
+	if err != nil {
+		return nil, err
+	}
+
+	// The totals are worked out from the items, so the ones sent by the client are ignored
 	query := `
 		UPDATE orders
 		SET 
 			client_id = $1,
 			delivery_latitude = $2,
 			delivery_longitude = $3,
-			total_price = $4,
-			status = $5,
+			status = $4,
+			shipping_zone = $6,
 			updated_at = NOW()
-		WHERE id = $6 AND deleted_at = 0
-		RETURNING id, client_id, delivery_latitude, delivery_longitude, total_price, status, created_at, updated_at
+		WHERE id = $5 AND deleted_at = 0
 	`
 
-	orderModel := makeOrderModel(req.Order)
-
-	err := r.db.QueryRow(ctx, query,
+	tag, err := tx.Exec(ctx, query,
 		orderModel.ClientId,
 		orderModel.DeliveryLatitude,
 		orderModel.DeliveryLongitude,
-		orderModel.TotalPrice,
 		orderModel.Status,
 		orderModel.Id,
-	).Scan(
+		orderModel.ShippingZone,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, pgx.ErrNoRows
+	}
+
+	// The shipping fee and the tax depend on where the order is delivered
+	if orderModel.DeliveryLatitude != latitude || orderModel.DeliveryLongitude != longitude {
+		if err := updateOrderTotalPrice(ctx, tx, r.taxes, r.zones, orderModel.Id); err != nil {
+			return nil, fmt.Errorf("failed to update order total price: %w", err)
+		}
+	}
+
+	err = tx.QueryRow(ctx, `
+		SELECT id, client_id, delivery_latitude, delivery_longitude, total_price, status, currency_code, exchange_rate, discount_total, tax_total, tax_inclusive, shipping_zone, shipping_fee, created_at, updated_at
+		FROM orders
+		WHERE id = $1
+	`, orderModel.Id).Scan(
 		&orderModel.Id,
 		&orderModel.ClientId,
 		&orderModel.DeliveryLatitude,
 		&orderModel.DeliveryLongitude,
 		&orderModel.TotalPrice,
 		&orderModel.Status,
+		&orderModel.CurrencyCode,
+		&orderModel.ExchangeRate,
+		&orderModel.DiscountTotal,
+		&orderModel.TaxTotal,
+		&orderModel.TaxInclusive,
+		&orderModel.ShippingZone,
+		&orderModel.ShippingFee,
 		&orderModel.CreatedAt,
 		&orderModel.UpdatedAt,
 	)
-
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return nil, pgx.ErrNoRows
-		}
 		return nil, err
 	}
 
-	return makeOrderProto(orderModel), nil
+	if currentStatus != orderModel.Status {
+		if err := recordStatusChange(ctx, tx, &orderModel, currentStatus, req.Actor, req.Reason); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Order.DeliveryAddress != nil {
+		if err := saveOrderAddress(ctx, tx, orderModel.Id, req.Order.AddressId, req.Order.DeliveryAddress); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	order := makeOrderProto(orderModel)
+	order.AddressId = req.Order.AddressId
+	order.DeliveryAddress = req.Order.DeliveryAddress
+	return order, nil
 }
 
 func (r *OrderRepo) DeleteOrder(ctx context.Context, req *order_service.DeleteOrderRequest) (*order_service.DeleteOrderResponse, error) {
@@ -173,6 +363,13 @@ func (r *OrderRepo) ListOrders(ctx context.Context, req *order_service.ListOrder
 			delivery_longitude,
 			total_price,
 			status,
+			currency_code,
+			exchange_rate,
+			discount_total,
+			tax_total,
+			tax_inclusive,
+			shipping_zone,
+			shipping_fee,
 			created_at,
 			updated_at,
 			deleted_at
@@ -233,6 +430,13 @@ func (r *OrderRepo) ListOrders(ctx context.Context, req *order_service.ListOrder
 			&orderModel.DeliveryLongitude,
 			&orderModel.TotalPrice,
 			&orderModel.Status,
+			&orderModel.CurrencyCode,
+			&orderModel.ExchangeRate,
+			&orderModel.DiscountTotal,
+			&orderModel.TaxTotal,
+			&orderModel.TaxInclusive,
+			&orderModel.ShippingZone,
+			&orderModel.ShippingFee,
 			&orderModel.CreatedAt,
 			&orderModel.UpdatedAt,
 			&orderModel.DeletedAt,
@@ -242,6 +446,16 @@ func (r *OrderRepo) ListOrders(ctx context.Context, req *order_service.ListOrder
 		}
 		orderList = append(orderList, makeOrderProto(orderModel))
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := attachOrderPromotions(ctx, r.db, orderList...); err != nil {
+		return nil, err
+	}
+	if err := attachOrderAddresses(ctx, r.db, orderList...); err != nil {
+		return nil, err
+	}
 
 	return &order_service.ListOrdersResponse{
 		Orders: orderList,
@@ -250,18 +464,32 @@ func (r *OrderRepo) ListOrders(ctx context.Context, req *order_service.ListOrder
 }
 
 func (r *OrderRepo) UpdateOrderStatus(ctx context.Context, req *order_service.UpdateOrderStatusRequest) (*order_service.Order, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	currentStatus, err := lockOrderStatus(ctx, tx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := statusUpdateTransition(currentStatus, req.Status); err != nil {
+		return nil, err
+	}
+
 	query := `
 		UPDATE orders
 		SET 
 			status = $1,
 			updated_at = NOW()
 		WHERE id = $2 AND deleted_at = 0
-		RETURNING id, client_id, delivery_latitude, delivery_longitude, total_price, status, created_at, updated_at
+		RETURNING id, client_id, delivery_latitude, delivery_longitude, total_price, status, currency_code, exchange_rate, discount_total, tax_total, tax_inclusive, shipping_zone, shipping_fee, created_at, updated_at
 	`
 
 	var orderModel models.Order
 
-	err := r.db.QueryRow(ctx, query,
+	err = tx.QueryRow(ctx, query,
 		req.Status,
 		req.Id,
 	).Scan(
@@ -271,6 +499,13 @@ func (r *OrderRepo) UpdateOrderStatus(ctx context.Context, req *order_service.Up
 		&orderModel.DeliveryLongitude,
 		&orderModel.TotalPrice,
 		&orderModel.Status,
+		&orderModel.CurrencyCode,
+		&orderModel.ExchangeRate,
+		&orderModel.DiscountTotal,
+		&orderModel.TaxTotal,
+		&orderModel.TaxInclusive,
+		&orderModel.ShippingZone,
+		&orderModel.ShippingFee,
 		&orderModel.CreatedAt,
 		&orderModel.UpdatedAt,
 	)
@@ -282,9 +517,262 @@ func (r *OrderRepo) UpdateOrderStatus(ctx context.Context, req *order_service.Up
 		return nil, err
 	}
 
+	if currentStatus != orderModel.Status {
+		if err := recordStatusChange(ctx, tx, &orderModel, currentStatus, req.Actor, req.Reason); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return makeOrderProto(orderModel), nil
+}
+
+// CancelOrder cancels an order and puts the units of all its items back into stock,
+// in one transaction.
+func (r *OrderRepo) CancelOrder(ctx context.Context, req *order_service.CancelOrderRequest) (*order_service.Order, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	currentStatus, err := lockOrderStatus(ctx, tx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if currentStatus == orderstatus.Cancelled {
+		return nil, fmt.Errorf("%w: order %s is already cancelled", orderstatus.ErrInvalidTransition, req.Id)
+	}
+	if err := orderstatus.Transition(currentStatus, orderstatus.Cancelled); err != nil {
+		return nil, err
+	}
+
+	released, err := restockOrderItems(ctx, tx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := reverseCouponRedemptions(ctx, tx, req.Id); err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE orders
+		SET 
+			status = $1,
+			updated_at = NOW()
+		WHERE id = $2 AND deleted_at = 0
+		RETURNING id, client_id, delivery_latitude, delivery_longitude, total_price, status, currency_code, exchange_rate, discount_total, tax_total, tax_inclusive, shipping_zone, shipping_fee, created_at, updated_at
+	`
+
+	var orderModel models.Order
+
+	err = tx.QueryRow(ctx, query,
+		orderstatus.Cancelled,
+		req.Id,
+	).Scan(
+		&orderModel.Id,
+		&orderModel.ClientId,
+		&orderModel.DeliveryLatitude,
+		&orderModel.DeliveryLongitude,
+		&orderModel.TotalPrice,
+		&orderModel.Status,
+		&orderModel.CurrencyCode,
+		&orderModel.ExchangeRate,
+		&orderModel.DiscountTotal,
+		&orderModel.TaxTotal,
+		&orderModel.TaxInclusive,
+		&orderModel.ShippingZone,
+		&orderModel.ShippingFee,
+		&orderModel.CreatedAt,
+		&orderModel.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordStatusChange(ctx, tx, &orderModel, currentStatus, req.Actor, req.Reason); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	releaseCachedFlashSaleStock(ctx, r.stockCache, released)
+
 	return makeOrderProto(orderModel), nil
 }
 
+func (r *OrderRepo) GetOrderHistory(ctx context.Context, req *order_service.GetOrderHistoryRequest) (*order_service.GetOrderHistoryResponse, error) {
+	query := `
+		SELECT
+			id,
+			order_id,
+			from_status,
+			to_status,
+			actor,
+			reason,
+			created_at
+		FROM order_status_history
+		WHERE order_id = $1
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.Query(ctx, query, req.OrderId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []*order_service.OrderStatusChange
+
+	for rows.Next() {
+		var (
+			changeModel models.OrderStatusChange
+			fromStatus  sql.NullString
+		)
+		err = rows.Scan(
+			&changeModel.Id,
+			&changeModel.OrderId,
+			&fromStatus,
+			&changeModel.ToStatus,
+			&changeModel.Actor,
+			&changeModel.Reason,
+			&changeModel.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if fromStatus.Valid {
+			changeModel.FromStatus = fromStatus.String
+		}
+		history = append(history, makeOrderStatusChangeProto(changeModel))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &order_service.GetOrderHistoryResponse{
+		History: history,
+	}, nil
+}
+
+// recordStatusChange appends a status change to the order's history and writes an
+// order.status_changed event. An empty fromStatus marks the status the order was created with.
+func recordStatusChange(ctx context.Context, tx pgx.Tx, order *models.Order, fromStatus, actor, reason string) error {
+	change := &order_service.OrderStatusChange{
+		Id:         uuid.NewString(),
+		OrderId:    order.Id,
+		FromStatus: fromStatus,
+		ToStatus:   order.Status,
+		Actor:      actor,
+		Reason:     reason,
+	}
+
+	var changedAt time.Time
+	err := tx.QueryRow(ctx, `
+		INSERT INTO order_status_history (
+			id,
+			order_id,
+			from_status,
+			to_status,
+			actor,
+			reason,
+			created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, NOW()
+		) RETURNING created_at
+	`,
+		change.Id,
+		change.OrderId,
+		sql.NullString{String: fromStatus, Valid: fromStatus != ""},
+		change.ToStatus,
+		change.Actor,
+		change.Reason,
+	).Scan(&changedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record order status change: %w", err)
+	}
+
+	// The status an order is created with goes out with order.created
+	if fromStatus == "" {
+		return nil
+	}
+	change.CreatedAt = timestamppb.New(changedAt)
+	return enqueueEvent(ctx, tx, outbox.TopicOrderStatusChanged, outbox.AggregateOrder, order.Id, &order_service.OrderStatusChanged{
+		Change:   change,
+		ClientId: order.ClientId,
+	})
+}
+
+// statusUpdateTransition checks a status change made by UpdateOrder or UpdateOrderStatus. Those
+// only change the status, so cancelling is left to CancelOrder, which also gives back the stock
+// and coupon redemptions of the order.
+func statusUpdateTransition(from, to string) error {
+	if to == orderstatus.Cancelled && from != orderstatus.Cancelled {
+		return fmt.Errorf("%w: %s -> %s, use CancelOrder to cancel an order", orderstatus.ErrInvalidTransition, from, to)
+	}
+	return orderstatus.Transition(from, to)
+}
+
+// lockOrderStatus returns the status of an order and locks its row until the transaction ends,
+// so two status changes cannot both start from the same status.
+func lockOrderStatus(ctx context.Context, tx pgx.Tx, orderID string) (string, error) {
+	var status string
+	err := tx.QueryRow(ctx, `
+		SELECT status
+		FROM orders
+		WHERE id = $1 AND deleted_at = 0
+		FOR UPDATE
+	`, orderID).Scan(&status)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", pgx.ErrNoRows
+		}
+		return "", err
+	}
+
+	return status, nil
+}
+
+// resolveDeliveryAddress copies the saved address an order refers to, if any, into the order
+// and checks where the order is delivered to.
+func resolveDeliveryAddress(ctx context.Context, db querier, order *order_service.Order) error {
+	if order.AddressId != "" {
+		saved, err := getClientAddress(ctx, db, order.AddressId, order.ClientId)
+		if err != nil {
+			return err
+		}
+		order.DeliveryLatitude = saved.Latitude
+		order.DeliveryLongitude = saved.Longitude
+		order.DeliveryAddress = makeAddressProto(saved).Address
+	}
+	if err := checkCoordinates(order.DeliveryLatitude, order.DeliveryLongitude); err != nil {
+		return err
+	}
+	if order.DeliveryAddress != nil {
+		return checkDeliveryAddress(order.DeliveryAddress)
+	}
+	return nil
+}
+
+// deliveryZone returns the name of the delivery zone of a delivery point. Without any
+// zones configured every point is delivered to, outside of any zone.
+func (r *OrderRepo) deliveryZone(latitude, longitude float64) (string, error) {
+	if r.zones == nil {
+		return "", nil
+	}
+	zone, ok := r.zones.Locate(shipping.Point{Latitude: latitude, Longitude: longitude})
+	if !ok {
+		return "", fmt.Errorf("%w: %f, %f", storage.ErrOutsideDeliveryZone, latitude, longitude)
+	}
+	return zone.Name, nil
+}
+
 // Convert db model to proto model
 func makeOrderProto(order models.Order) *order_service.Order {
 	return &order_service.Order{
@@ -292,8 +780,15 @@ func makeOrderProto(order models.Order) *order_service.Order {
 		ClientId:          order.ClientId,
 		DeliveryLatitude:  order.DeliveryLatitude,
 		DeliveryLongitude: order.DeliveryLongitude,
-		TotalPrice:        order.TotalPrice,
+		TotalPrice:        makeMoneyProto(order.TotalPrice, order.CurrencyCode),
 		Status:            order.Status,
+		CurrencyCode:      order.CurrencyCode,
+		ExchangeRate:      makeExchangeRateProto(order.ExchangeRate),
+		DiscountTotal:     makeMoneyProto(order.DiscountTotal, order.CurrencyCode),
+		TaxTotal:          makeMoneyProto(order.TaxTotal, order.CurrencyCode),
+		TaxInclusive:      order.TaxInclusive,
+		ShippingZone:      order.ShippingZone,
+		ShippingFee:       makeMoneyProto(order.ShippingFee, order.CurrencyCode),
 		CreatedAt:         timestamppb.New(order.CreatedAt),
 		UpdatedAt:         timestamppb.New(order.UpdatedAt),
 	}
@@ -306,9 +801,23 @@ func makeOrderModel(order *order_service.Order) models.Order {
 		ClientId:          order.ClientId,
 		DeliveryLatitude:  order.DeliveryLatitude,
 		DeliveryLongitude: order.DeliveryLongitude,
-		TotalPrice:        order.TotalPrice,
+		TotalPrice:        amountFromProto(order.TotalPrice),
 		Status:            order.Status,
+		CurrencyCode:      order.CurrencyCode,
 		CreatedAt:         order.CreatedAt.AsTime(),
 		UpdatedAt:         order.UpdatedAt.AsTime(),
 	}
 }
+
+// Convert db model to proto model
+func makeOrderStatusChangeProto(change models.OrderStatusChange) *order_service.OrderStatusChange {
+	return &order_service.OrderStatusChange{
+		Id:         change.Id,
+		OrderId:    change.OrderId,
+		FromStatus: change.FromStatus,
+		ToStatus:   change.ToStatus,
+		Actor:      change.Actor,
+		Reason:     change.Reason,
+		CreatedAt:  timestamppb.New(change.CreatedAt),
+	}
+}
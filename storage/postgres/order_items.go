@@ -3,23 +3,50 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/flash_sale/flash_sale_order_service/currency"
 	"github.com/flash_sale/flash_sale_order_service/genproto/order_service"
 	"github.com/flash_sale/flash_sale_order_service/models"
+	"github.com/flash_sale/flash_sale_order_service/money"
+	"github.com/flash_sale/flash_sale_order_service/orderstatus"
+	"github.com/flash_sale/flash_sale_order_service/outbox"
+	"github.com/flash_sale/flash_sale_order_service/pricing"
+	"github.com/flash_sale/flash_sale_order_service/quote"
+	"github.com/flash_sale/flash_sale_order_service/shipping"
+	"github.com/flash_sale/flash_sale_order_service/storage"
+	"github.com/flash_sale/flash_sale_order_service/tax"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type OrderItemRepo struct {
-	db *pgx.Conn
+	db             *pgxpool.Pool
+	stockCache     storage.StockCacheI
+	rules          FlashSaleRules
+	rates          currency.ExchangeRateProvider
+	pricing        *pricing.Engine
+	quotes         *quote.Signer
+	taxes          *tax.Table
+	zones          *shipping.Zones
+	idempotencyTTL time.Duration
 }
 
-func NewOrderItemRepo(db *pgx.Conn) *OrderItemRepo {
+func NewOrderItemRepo(db *pgxpool.Pool, stockCache storage.StockCacheI, rules FlashSaleRules, rates currency.ExchangeRateProvider, engine *pricing.Engine, quotes *quote.Signer, taxes *tax.Table, zones *shipping.Zones, idempotencyTTL time.Duration) *OrderItemRepo {
 	return &OrderItemRepo{
-		db: db,
+		db:             db,
+		stockCache:     stockCache,
+		rules:          rules,
+		rates:          rates,
+		pricing:        engine,
+		quotes:         quotes,
+		taxes:          taxes,
+		zones:          zones,
+		idempotencyTTL: idempotencyTTL,
 	}
 }
 func (r *OrderItemRepo) GetOrderItem(ctx context.Context, req *order_service.GetOrderItemRequest) (*order_service.OrderItem, error) {
@@ -40,7 +67,11 @@ func (r *OrderItemRepo) GetOrderItem(ctx context.Context, req *order_service.Get
 			unit_price,
 			total_price,
 			discount_applied,
+			tax_amount,
+			currency_code,
 			product_type,
+			cancelled_quantity,
+			cancellation_reason,
 			created_at,
 			updated_at,
 			deleted_at
@@ -58,7 +89,11 @@ func (r *OrderItemRepo) GetOrderItem(ctx context.Context, req *order_service.Get
 		&orderItemModel.UnitPrice,
 		&orderItemModel.TotalPrice,
 		&orderItemModel.DiscountApplied,
+		&orderItemModel.TaxAmount,
+		&orderItemModel.CurrencyCode,
 		&orderItemModel.ProductType,
+		&orderItemModel.CancelledQuantity,
+		&orderItemModel.CancellationReason,
 		&orderItemModel.CreatedAt,
 		&orderItemModel.UpdatedAt,
 		&orderItemModel.DeletedAt,
@@ -96,7 +131,11 @@ func (r *OrderItemRepo) ListOrderItems(ctx context.Context, req *order_service.L
 			unit_price,
 			total_price,
 			discount_applied,
+			tax_amount,
+			currency_code,
 			product_type,
+			cancelled_quantity,
+			cancellation_reason,
 			created_at,
 			updated_at,
 			deleted_at
@@ -158,7 +197,11 @@ func (r *OrderItemRepo) ListOrderItems(ctx context.Context, req *order_service.L
 			&orderItemModel.UnitPrice,
 			&orderItemModel.TotalPrice,
 			&orderItemModel.DiscountApplied,
+			&orderItemModel.TaxAmount,
+			&orderItemModel.CurrencyCode,
 			&orderItemModel.ProductType,
+			&orderItemModel.CancelledQuantity,
+			&orderItemModel.CancellationReason,
 			&orderItemModel.CreatedAt,
 			&orderItemModel.UpdatedAt,
 			&orderItemModel.DeletedAt,
@@ -184,125 +227,314 @@ func (r *OrderItemRepo) ListOrderItems(ctx context.Context, req *order_service.L
 	}, nil
 }
 func (r *OrderItemRepo) ConvertBasketToOrderItems(ctx context.Context, req *order_service.ConvertBasketToOrderItemsRequest) (*order_service.ConvertBasketToOrderItemsResponse, error) {
-	rBasket := NewBasketItemRepo(r.db)
-	// 1. Get basket items using ListBasketItems
-	basketItemsResponse, err := rBasket.ListBasketItems(ctx, &order_service.ListBasketItemsRequest{
-		BasketId: req.BasketId,
-		Page:     1,   // Get all items in the basket
-		Limit:    100, // Assuming a maximum of 100 items in a basket
-	})
+	requestHash, err := hashRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Everything below runs in one transaction so a failure never leaves a half-populated order
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// A retried request gets the result of the first one instead of finding the basket checked out
+	var replay order_service.ConvertBasketToOrderItemsResponse
+	replayed, err := claimIdempotencyKey(ctx, tx, convertBasketToOrderItemsOperation, req.IdempotencyKey, requestHash, r.idempotencyTTL, &replay)
+	if err != nil {
+		return nil, err
+	}
+	if replayed {
+		return &replay, nil
+	}
+
+	// 1. Lock the basket so the same basket cannot be checked out twice
+	var basketModel models.Basket
+	err = tx.QueryRow(ctx, `
+		SELECT user_id, status, currency_code
+		FROM baskets
+		WHERE id = $1 AND deleted_at = 0
+		FOR UPDATE
+	`, req.BasketId).Scan(&basketModel.UserId, &basketModel.Status, &basketModel.CurrencyCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get basket: %w", err)
+	}
+	if basketModel.Status == "CHECKED_OUT" {
+		return nil, fmt.Errorf("basket %s is already checked out", req.BasketId)
+	}
+
+	// 2. Get basket items
+	basketItems, err := listAllBasketItems(ctx, tx, req.BasketId)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get basket items: %w", err)
 	}
-	basketItems := basketItemsResponse.BasketItems // Extract basket items from the response
+	if len(basketItems) == 0 {
+		return nil, fmt.Errorf("basket %s is empty", req.BasketId)
+	}
+
+	// A quote, if given, replaces the current prices of the items with the ones it froze
+	var quoted map[string]pricing.LineResult
+	if req.QuoteId != "" {
+		quoted, err = quotedLines(ctx, tx, r.quotes, req.QuoteId, req.BasketId, basketModel.CurrencyCode, basketItems, time.Now())
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	// 2. Create order items from basket items
-	_, err = r.createOrderItemsFromBasketItems(ctx, req.OrderId, basketItems)
+	// Fix the exchange rate the order is charged at
+	rate, err := r.fixOrderExchangeRate(ctx, tx, req.OrderId, basketModel.CurrencyCode)
+	if err != nil {
+		return nil, err
+	}
+
+	// Serialize the checkouts of one user so concurrent baskets cannot slip past the purchase limits together
+	if hasFlashSaleLines(basketItems...) {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, basketModel.UserId); err != nil {
+			return nil, fmt.Errorf("failed to lock user purchases: %w", err)
+		}
+		if err := checkPurchaseLimits(ctx, tx, r.rules, basketModel.UserId, basketItems, false); err != nil {
+			return nil, err
+		}
+	}
+
+	// 3. Settle stock holds: expired ones give their units back, live ones are used by this order
+	releasedHolds, err := releaseExpiredBasketStockHolds(ctx, tx, req.BasketId)
+	if err != nil {
+		return nil, err
+	}
+	heldItems, err := consumeStockHolds(ctx, tx, req.BasketId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume stock holds: %w", err)
+	}
+
+	// 4. Take the remaining flash sale units from the stock cache before touching their rows,
+	// so a sold out product is rejected without locking it. The cache only gates the stock;
+	// whether each flash sale is still running is checked against PostgreSQL below.
+	reserved, err := r.reserveCachedFlashSaleStock(ctx, basketItems, heldItems)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve flash sale stock: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			releaseCachedFlashSaleStock(ctx, r.stockCache, reserved)
+		}
+	}()
+
+	// 5. Create order items from basket items
+	lines, err := r.createOrderItemsFromBasketItems(ctx, tx, req.OrderId, basketModel.CurrencyCode, rate, basketItems, heldItems, quoted)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create order items: %w", err)
 	}
 
-	// 3. Update order total price
-	if err := r.updateOrderTotalPrice(ctx, req.OrderId); err != nil {
+	// Flash sale units taken from the stock cache, by a hold or above, for items the pricing
+	// rules did not sell at the flash sale price go back once the order is committed
+	unclaimed := make(map[string]int32)
+	for i, basketItem := range basketItems {
+		if basketItem.FlashSaleEventProductId == "" || lines[i].Has(pricing.FlashSale) {
+			continue
+		}
+		if heldItems[basketItem.Id] || (basketItem.ProductType == "FLASH_SALE" && reserved[basketItem.FlashSaleEventProductId] > 0) {
+			unclaimed[basketItem.FlashSaleEventProductId] += basketItem.Quantity
+		}
+	}
+
+	// 6. Redeem the promo codes against the new items
+	if err := applyCoupons(ctx, tx, r.pricing, r.rates, req.OrderId, basketModel.UserId, basketModel.CurrencyCode, req.CouponCodes, lines); err != nil {
+		return nil, err
+	}
+
+	// 7. Update order total price
+	if err := updateOrderTotalPrice(ctx, tx, r.taxes, r.zones, req.OrderId); err != nil {
 		return nil, fmt.Errorf("failed to update order total price: %w", err)
 	}
 
-	return &order_service.ConvertBasketToOrderItemsResponse{
+	// 8. Mark the basket as checked out
+	_, err = tx.Exec(ctx, `
+		UPDATE baskets
+		SET status = 'CHECKED_OUT',
+			updated_at = NOW()
+		WHERE id = $1
+	`, req.BasketId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check out basket: %w", err)
+	}
+	err = enqueueEvent(ctx, tx, outbox.TopicBasketCheckedOut, outbox.AggregateBasket, req.BasketId, &order_service.BasketCheckedOut{
+		BasketId:     req.BasketId,
+		OrderId:      req.OrderId,
+		UserId:       basketModel.UserId,
+		CurrencyCode: basketModel.CurrencyCode,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &order_service.ConvertBasketToOrderItemsResponse{
 		Id: req.OrderId,
-	}, nil
+	}
+	if err := saveIdempotentResponse(ctx, tx, convertBasketToOrderItemsOperation, req.IdempotencyKey, r.idempotencyTTL, response); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	committed = true
+	releaseCachedStockHolds(ctx, r.stockCache, releasedHolds)
+	releaseCachedFlashSaleStock(ctx, r.stockCache, unclaimed)
+
+	return response, nil
 }
-func (r *OrderItemRepo) createOrderItemsFromBasketItems(ctx context.Context, orderID string, basketItems []*order_service.BasketItem) ([]*order_service.OrderItem, error) {
 
-	// Maps to store validation results for discounts and flash sale events
-	discountCache := make(map[string]bool)
-	flashSaleEventCache := make(map[string]bool)
+// fixOrderExchangeRate returns the rate prices are converted at for an order checked out
+// from a basket in currencyCode. The rate is taken from the provider the first time a
+// basket is checked out into the order and stored on it for every later one.
+func (r *OrderItemRepo) fixOrderExchangeRate(ctx context.Context, tx pgx.Tx, orderID, currencyCode string) (money.Rate, error) {
+	var (
+		orderCurrency string
+		rate          money.Rate
+	)
+	err := tx.QueryRow(ctx, `
+		SELECT currency_code, exchange_rate
+		FROM orders
+		WHERE id = $1 AND deleted_at = 0
+		FOR UPDATE
+	`, orderID).Scan(&orderCurrency, &rate)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get order: %w", err)
+	}
+	if orderCurrency != currencyCode {
+		return 0, fmt.Errorf("%w: basket is in %s but order %s is in %s", storage.ErrUnsupportedCurrency, currencyCode, orderID, orderCurrency)
+	}
+	if rate != 0 {
+		return rate, nil
+	}
 
-	var orderItems []*order_service.OrderItem
+	rate, err = r.rates.Rate(ctx, money.DefaultCurrency, currencyCode)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE orders
+		SET exchange_rate = $1,
+			updated_at = NOW()
+		WHERE id = $2
+	`, rate, orderID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to store exchange rate: %w", err)
+	}
+
+	return rate, nil
+}
+
+// reserveCachedFlashSaleStock takes the flash sale units of the basket from the stock cache,
+// skipping items whose units are already held. It returns the quantity reserved per flash sale
+// event product; products the cache does not know about are left for PostgreSQL to check.
+// On a sold out product everything taken so far is given back.
+func (r *OrderItemRepo) reserveCachedFlashSaleStock(ctx context.Context, basketItems []*order_service.BasketItem, heldItems map[string]bool) (map[string]int32, error) {
+	reserved := make(map[string]int32)
+	if r.stockCache == nil {
+		return reserved, nil
+	}
 
 	for _, basketItem := range basketItems {
-		// 1. Get product details
-		var product models.Product
-		err := r.db.QueryRow(ctx, `
-            SELECT id, name, base_price, current_price, image_url, stock_quantity, created_at, updated_at
-            FROM products
-            WHERE id = $1 AND deleted_at = 0
-        `, basketItem.ProductId).Scan(
-			&product.Id,
-			&product.Name,
-			&product.BasePrice,
-			&product.CurrentPrice,
-			&product.ImageUrl,
-			&product.StockQuantity,
-			&product.CreatedAt,
-			&product.UpdatedAt,
-		)
+		if basketItem.ProductType != "FLASH_SALE" || basketItem.FlashSaleEventProductId == "" || heldItems[basketItem.Id] {
+			continue
+		}
+
+		err := r.stockCache.ReserveFlashSaleStock(ctx, basketItem.FlashSaleEventProductId, basketItem.Quantity)
+		if errors.Is(err, storage.ErrStockNotCached) {
+			continue
+		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to get product: %w", err)
+			releaseCachedFlashSaleStock(ctx, r.stockCache, reserved)
+			return nil, err
 		}
+		reserved[basketItem.FlashSaleEventProductId] += basketItem.Quantity
+	}
 
-		// 2. Calculate unit price based on product type and validity of discounts/flash sales
-		unitPrice := product.BasePrice // Default to base price
-		discountApplied := float32(0)
-
-		switch basketItem.ProductType {
-		case "REGULAR":
-			// No discount or flash sale, use base price
-		case "FLASH_SALE":
-			if basketItem.FlashSaleEventProductId != "" {
-				isValid, ok := flashSaleEventCache[basketItem.FlashSaleEventProductId]
-				if !ok {
-					// Check if flash sale event is valid and cache the result
-					isValid = isFlashSaleEventProductValid(ctx, r.db, basketItem.FlashSaleEventProductId)
-					flashSaleEventCache[basketItem.FlashSaleEventProductId] = isValid
-				}
+	return reserved, nil
+}
+
+// createOrderItemsFromBasketItems prices and inserts the order items and returns their
+// pricing, in the order of basketItems. heldItems lists basket items whose flash sale units
+// were set aside by a stock hold. Items in quoted are charged the pricing given there instead
+// of their current one.
+func (r *OrderItemRepo) createOrderItemsFromBasketItems(ctx context.Context, tx pgx.Tx, orderID, currencyCode string, rate money.Rate, basketItems []*order_service.BasketItem, heldItems map[string]bool, quoted map[string]pricing.LineResult) ([]pricing.LineResult, error) {
+
+	validity := newPromotionValidity()
+
+	var lines []pricing.LineResult
+
+	for _, basketItem := range basketItems {
+		// 1. Get product details
+		product, err := getProduct(ctx, tx, basketItem.ProductId)
+		if err != nil {
+			return nil, err
+		}
 
-				if isValid {
-					var flashSaleEventProduct models.FlashSaleEventProduct
-					err = r.db.QueryRow(ctx, `
-                        SELECT sale_price
-                        FROM flash_sale_event_products
-                        WHERE id = $1 AND deleted_at = 0
-                    `, basketItem.FlashSaleEventProductId).Scan(
-						&flashSaleEventProduct.SalePrice,
-					)
-					if err != nil {
-						return nil, fmt.Errorf("failed to get flash sale event product: %w", err)
-					}
-
-					unitPrice = flashSaleEventProduct.SalePrice
-					discountApplied = product.BasePrice - unitPrice
-				} // else use base price
+		// 2. Weigh the promotions the item refers to against each other
+		priced, ok := quoted[basketItem.Id]
+		if !ok {
+			line, err := basketItemLine(ctx, tx, basketItem, product, rate, validity)
+			if err != nil {
+				return nil, err
 			}
-		case "DISCOUNT":
-			if basketItem.DiscountProductId != "" {
-				isValid, ok := discountCache[basketItem.DiscountProductId]
-				if !ok {
-					// Check if discount is valid and cache the result
-					isValid = isDiscountValid(ctx, r.db, basketItem.DiscountProductId)
-					discountCache[basketItem.DiscountProductId] = isValid
+			priced = r.pricing.PriceLine(line)
+		}
+		unitPrice := priced.UnitPrice
+		discountApplied := priced.BasePrice.Minus(unitPrice)
+
+		if basketItem.FlashSaleEventProductId != "" {
+			switch {
+			case priced.Has(pricing.FlashSale) && !heldItems[basketItem.Id]:
+				// Claim the flash sale units; the availability check and the decrement are one
+				// statement, so two concurrent checkouts can never both take the last units.
+				tag, err := tx.Exec(ctx, `
+					UPDATE flash_sale_event_products
+					SET available_quantity = available_quantity - $1,
+						updated_at = NOW()
+					WHERE id = $2 AND deleted_at = 0 AND available_quantity >= $1
+				`, basketItem.Quantity, basketItem.FlashSaleEventProductId)
+				if err != nil {
+					return nil, fmt.Errorf("failed to reserve flash sale event product: %w", err)
+				}
+				if tag.RowsAffected() == 0 {
+					return nil, fmt.Errorf("%w: flash sale product %q (%s) has fewer than %d units left",
+						storage.ErrSoldOut, product.Name, basketItem.FlashSaleEventProductId, basketItem.Quantity)
+				}
+			case !priced.Has(pricing.FlashSale) && heldItems[basketItem.Id]:
+				// The units were set aside by a stock hold but the item is not sold at the flash sale price
+				_, err := tx.Exec(ctx, `
+					UPDATE flash_sale_event_products
+					SET available_quantity = available_quantity + $1,
+						updated_at = NOW()
+					WHERE id = $2
+				`, basketItem.Quantity, basketItem.FlashSaleEventProductId)
+				if err != nil {
+					return nil, fmt.Errorf("failed to release flash sale event product: %w", err)
 				}
-
-				if isValid {
-					var discount models.Discount
-					err = r.db.QueryRow(ctx, `
-                        SELECT discount_type, discount_value
-                        FROM discounts
-                        WHERE id = $1 AND deleted_at = 0
-                    `, basketItem.DiscountProductId).Scan(
-						&discount.DiscountType,
-						&discount.DiscountValue,
-					)
-					if err != nil {
-						return nil, fmt.Errorf("failed to get discount: %w", err)
-					}
-
-					unitPrice = calculateDiscountedPrice(product.BasePrice, &discount)
-					discountApplied = product.BasePrice - unitPrice
-				} // else use base price
 			}
 		}
 
-		// 3. Create order item
+		// 3. Take the units out of the product stock
+		tag, err := tx.Exec(ctx, `
+			UPDATE products
+			SET stock_quantity = stock_quantity - $1,
+				updated_at = NOW()
+			WHERE id = $2 AND deleted_at = 0 AND stock_quantity >= $1
+		`, basketItem.Quantity, product.Id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to reserve product stock: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return nil, fmt.Errorf("%w: product %q (%s) has fewer than %d units left",
+				storage.ErrSoldOut, product.Name, product.Id, basketItem.Quantity)
+		}
+
+		// 4. Create order item
 		orderItem := &order_service.OrderItem{
 			Id:                      uuid.NewString(),
 			OrderId:                 orderID,
@@ -310,9 +542,9 @@ func (r *OrderItemRepo) createOrderItemsFromBasketItems(ctx context.Context, ord
 			FlashSaleEventProductId: basketItem.FlashSaleEventProductId,
 			DiscountProductId:       basketItem.DiscountProductId,
 			Quantity:                basketItem.Quantity,
-			UnitPrice:               unitPrice,
-			TotalPrice:              unitPrice * float32(basketItem.Quantity),
-			DiscountApplied:         discountApplied,
+			UnitPrice:               makeMoneyProto(unitPrice, currencyCode),
+			TotalPrice:              makeMoneyProto(unitPrice.Times(basketItem.Quantity), currencyCode),
+			DiscountApplied:         makeMoneyProto(discountApplied, currencyCode),
 			ProductType:             basketItem.ProductType,
 			CreatedAt:               timestamppb.Now(),
 			UpdatedAt:               timestamppb.Now(),
@@ -339,68 +571,281 @@ func (r *OrderItemRepo) createOrderItemsFromBasketItems(ctx context.Context, ord
 				unit_price,
 				total_price,
 				discount_applied,
+				currency_code,
 				product_type,
+				flash_sale_claimed,
 				created_at,
 				updated_at,
 				deleted_at
 			) VALUES (
-				$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW(), 0
+				$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NOW(), NOW(), 0
 			)
 		`
 
-		_, err = r.db.Exec(ctx, query,
+		_, err = tx.Exec(ctx, query,
 			orderItem.Id,
 			orderItem.OrderId,
 			orderItem.ProductId,
 			flashSaleEventProductID, // Pass NullString here
 			discountProductID,       // Pass NullString here
 			orderItem.Quantity,
-			orderItem.UnitPrice,
-			orderItem.TotalPrice,
-			orderItem.DiscountApplied,
+			unitPrice,
+			unitPrice.Times(basketItem.Quantity),
+			discountApplied,
+			currencyCode,
 			orderItem.ProductType,
+			basketItem.FlashSaleEventProductId != "" && priced.Has(pricing.FlashSale),
 		)
 		if err != nil {
 			return nil, err
 		}
 
-		orderItems = append(orderItems, orderItem)
+		lines = append(lines, priced)
 	}
 
-	return orderItems, nil
+	return lines, nil
 }
+
+// DeleteOrderItem cancels the remaining units of an order item, puts them back into stock
+// and soft-deletes the line.
 func (r *OrderItemRepo) DeleteOrderItem(ctx context.Context, req *order_service.DeleteOrderItemRequest) (string, error) {
-	// Get the order ID before deleting the order item
-	var orderID string
-	err := r.db.QueryRow(ctx, `
-		SELECT order_id
-		FROM order_items
-		WHERE id = $1
-	`, req.Id).Scan(&orderID)
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	item, err := lockCancellableOrderItem(ctx, tx, req.Id)
 	if err != nil {
-		return "", fmt.Errorf("failed to get order ID: %w", err)
+		return "", err
+	}
+
+	released := make(map[string]int32)
+	if remaining := item.Quantity - item.CancelledQuantity; remaining > 0 {
+		if _, err := cancelOrderItemUnits(ctx, tx, &item, remaining, "order item deleted"); err != nil {
+			return "", err
+		}
+		if item.FlashSaleClaimed {
+			released[item.FlashSaleEventProductId] = remaining
+		}
 	}
 
 	query := `
-		DELETE FROM order_items
-		WHERE id = $1
+		UPDATE order_items
+		SET deleted_at = $1
+		WHERE id = $2 AND deleted_at = 0
 	`
 
-	_, err = r.db.Exec(ctx, query, req.Id)
+	_, err = tx.Exec(ctx, query, time.Now().Unix(), req.Id)
 	if err != nil {
 		return "", err
 	}
 
 	// Update the order's total price after deleting the item
-	if err := r.updateOrderTotalPrice(ctx, orderID); err != nil {
+	if err := updateOrderTotalPrice(ctx, tx, r.taxes, r.zones, item.OrderId); err != nil {
 		return "", fmt.Errorf("failed to update order total price: %w", err)
 	}
 
-	return orderID, nil
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	releaseCachedFlashSaleStock(ctx, r.stockCache, released)
+
+	return item.OrderId, nil
+}
+
+// CancelOrderItem cancels req.Quantity units of an order item, or all of its remaining
+// units when req.Quantity is 0. The units go back into stock, the order total is
+// recomputed and the amount owed back for them is returned.
+func (r *OrderItemRepo) CancelOrderItem(ctx context.Context, req *order_service.CancelOrderItemRequest) (*order_service.CancelOrderItemResponse, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	item, err := lockCancellableOrderItem(ctx, tx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	quantity := req.Quantity
+	if quantity == 0 {
+		quantity = item.Quantity - item.CancelledQuantity
+	}
+
+	refund, err := cancelOrderItemUnits(ctx, tx, &item, quantity, req.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := updateOrderTotalPrice(ctx, tx, r.taxes, r.zones, item.OrderId); err != nil {
+		return nil, fmt.Errorf("failed to update order total price: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	if item.FlashSaleClaimed {
+		releaseCachedFlashSaleStock(ctx, r.stockCache, map[string]int32{item.FlashSaleEventProductId: quantity})
+	}
+
+	return &order_service.CancelOrderItemResponse{
+		OrderItem:    makeOrderItemProto(item),
+		RefundAmount: makeMoneyProto(refund, item.CurrencyCode),
+	}, nil
+}
+
+// lockCancellableOrderItem locks an order item together with its order, and fails with
+// orderstatus.ErrInvalidTransition once the order is past the point where it can be cancelled.
+func lockCancellableOrderItem(ctx context.Context, tx pgx.Tx, orderItemID string) (models.OrderItem, error) {
+	var item models.OrderItem
+
+	// The order is locked before its items, the same order CancelOrder takes them in.
+	err := tx.QueryRow(ctx, `
+		SELECT order_id
+		FROM order_items
+		WHERE id = $1 AND deleted_at = 0
+	`, orderItemID).Scan(&item.OrderId)
+	if err != nil {
+		return item, err
+	}
+
+	currentStatus, err := lockOrderStatus(ctx, tx, item.OrderId)
+	if err != nil {
+		return item, err
+	}
+	if !orderstatus.CanTransition(currentStatus, orderstatus.Cancelled) {
+		return item, fmt.Errorf("%w: order %s is %s and its items can no longer be cancelled", orderstatus.ErrInvalidTransition, item.OrderId, currentStatus)
+	}
+
+	var flashSaleEventProductID, discountProductID sql.NullString
+	err = tx.QueryRow(ctx, `
+		SELECT 
+			id,
+			order_id,
+			product_id,
+			flash_sale_event_product_id,
+			discount_product_id,
+			quantity,
+			unit_price,
+			total_price,
+			discount_applied,
+			tax_amount,
+			currency_code,
+			product_type,
+			flash_sale_claimed,
+			cancelled_quantity,
+			cancellation_reason,
+			created_at,
+			updated_at,
+			deleted_at
+		FROM order_items
+		WHERE id = $1 AND deleted_at = 0
+		FOR UPDATE
+	`, orderItemID).Scan(
+		&item.Id,
+		&item.OrderId,
+		&item.ProductId,
+		&flashSaleEventProductID,
+		&discountProductID,
+		&item.Quantity,
+		&item.UnitPrice,
+		&item.TotalPrice,
+		&item.DiscountApplied,
+		&item.TaxAmount,
+		&item.CurrencyCode,
+		&item.ProductType,
+		&item.FlashSaleClaimed,
+		&item.CancelledQuantity,
+		&item.CancellationReason,
+		&item.CreatedAt,
+		&item.UpdatedAt,
+		&item.DeletedAt,
+	)
+	if err != nil {
+		return item, err
+	}
+	item.FlashSaleEventProductId = flashSaleEventProductID.String
+	item.DiscountProductId = discountProductID.String
+
+	return item, nil
+}
+
+// cancelOrderItemUnits cancels quantity units of a locked order item, puts them back
+// into stock and returns the amount owed back for them: their price less their share of
+// the order's coupon discounts, which is taken off those discounts, plus their share of the
+// item's tax when it was added on top. item is updated in place.
+func cancelOrderItemUnits(ctx context.Context, tx pgx.Tx, item *models.OrderItem, quantity int32, reason string) (money.Amount, error) {
+	remaining := item.Quantity - item.CancelledQuantity
+	if quantity <= 0 || quantity > remaining {
+		return 0, fmt.Errorf("%w: cannot cancel %d units of order item %s, %d left", storage.ErrInvalidQuantity, quantity, item.Id, remaining)
+	}
+
+	var (
+		discountTotal money.Amount
+		taxInclusive  bool
+		subtotal      money.Amount
+	)
+	err := tx.QueryRow(ctx, `
+		SELECT o.discount_total, o.tax_inclusive, COALESCE((
+			SELECT SUM(oi.total_price)
+			FROM order_items oi
+			WHERE oi.order_id = o.id AND oi.deleted_at = 0
+		), 0)
+		FROM orders o
+		WHERE o.id = $1
+	`, item.OrderId).Scan(&discountTotal, &taxInclusive, &subtotal)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get order totals: %w", err)
+	}
+
+	// The coupon discounts are spread over the items in proportion to their totals, as they are for tax
+	cancelled := item.UnitPrice.Times(quantity)
+	var couponShare money.Amount
+	if subtotal > 0 {
+		couponShare = discountTotal.Share(int64(cancelled), int64(subtotal))
+		if couponShare > discountTotal {
+			couponShare = discountTotal
+		}
+	}
+	var taxShare money.Amount
+	if !taxInclusive {
+		taxShare = item.TaxAmount.Share(int64(quantity), int64(remaining))
+	}
+
+	err = tx.QueryRow(ctx, `
+		UPDATE order_items
+		SET 
+			cancelled_quantity = cancelled_quantity + $1,
+			total_price = unit_price * (quantity - cancelled_quantity - $1),
+			cancellation_reason = $2,
+			updated_at = NOW()
+		WHERE id = $3
+		RETURNING cancelled_quantity, total_price, cancellation_reason, updated_at
+	`, quantity, reason, item.Id).Scan(
+		&item.CancelledQuantity,
+		&item.TotalPrice,
+		&item.CancellationReason,
+		&item.UpdatedAt,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to cancel order item: %w", err)
+	}
+
+	if err := restockUnits(ctx, tx, item.ProductId, claimedFlashSaleEventProductID(item), quantity); err != nil {
+		return 0, err
+	}
+
+	if err := reduceCouponDiscounts(ctx, tx, item.OrderId, couponShare); err != nil {
+		return 0, err
+	}
+
+	return cancelled.Minus(couponShare) + taxShare, nil
 }
 
 // Helper function to check if a flash sale event product is valid
-func isFlashSaleEventProductValid(ctx context.Context, db *pgx.Conn, flashSaleEventProductID string) bool {
+func isFlashSaleEventProductValid(ctx context.Context, db querier, flashSaleEventProductID string) bool {
 	var (
 		status  string
 		endTime time.Time
@@ -420,7 +865,7 @@ func isFlashSaleEventProductValid(ctx context.Context, db *pgx.Conn, flashSaleEv
 }
 
 // Helper function to check if a discount is valid
-func isDiscountValid(ctx context.Context, db *pgx.Conn, discountID string) bool {
+func isDiscountValid(ctx context.Context, db querier, discountID string) bool {
 	var (
 		isActive bool
 		endDate  time.Time
@@ -447,43 +892,113 @@ func makeOrderItemProto(item models.OrderItem) *order_service.OrderItem {
 		FlashSaleEventProductId: item.FlashSaleEventProductId,
 		DiscountProductId:       item.DiscountProductId,
 		Quantity:                item.Quantity,
-		UnitPrice:               item.UnitPrice,
-		TotalPrice:              item.TotalPrice,
-		DiscountApplied:         item.DiscountApplied,
+		UnitPrice:               makeMoneyProto(item.UnitPrice, item.CurrencyCode),
+		TotalPrice:              makeMoneyProto(item.TotalPrice, item.CurrencyCode),
+		DiscountApplied:         makeMoneyProto(item.DiscountApplied, item.CurrencyCode),
+		TaxAmount:               makeMoneyProto(item.TaxAmount, item.CurrencyCode),
 		ProductType:             item.ProductType,
+		CancelledQuantity:       item.CancelledQuantity,
+		CancellationReason:      item.CancellationReason,
 		CreatedAt:               timestamppb.New(item.CreatedAt),
 		UpdatedAt:               timestamppb.New(item.UpdatedAt),
 	}
 }
 
-// Helper function to calculate the discounted price
-func calculateDiscountedPrice(basePrice float32, discount *models.Discount) float32 {
-	if discount.DiscountType == "PERCENTAGE" {
-		return basePrice * (1 - discount.DiscountValue/100)
-	} else if discount.DiscountType == "FIXED_AMOUNT" {
-		return basePrice - discount.DiscountValue
+// Helper function to update the order's total price, the tax of its items and its shipping fee
+func updateOrderTotalPrice(ctx context.Context, db querier, taxes *tax.Table, zones *shipping.Zones, orderID string) error {
+	var (
+		latitude, longitude float64
+		discountTotal       money.Amount
+		rate                money.Rate
+		shippingZone        string
+		freeShipping        bool
+	)
+	err := db.QueryRow(ctx, `
+		SELECT delivery_latitude, delivery_longitude, discount_total, exchange_rate, shipping_zone,
+			EXISTS (SELECT 1 FROM order_promotions WHERE order_id = o.id AND effect = 'FREE_SHIPPING')
+		FROM orders o
+		WHERE id = $1
+	`, orderID).Scan(&latitude, &longitude, &discountTotal, &rate, &shippingZone, &freeShipping)
+	if err != nil {
+		return err
 	}
-	return basePrice
-}
 
-// Helper function to update the order's total price
-func (r *OrderItemRepo) updateOrderTotalPrice(ctx context.Context, orderID string) error {
-	var totalPrice sql.NullFloat64
-	err := r.db.QueryRow(ctx, `
-		SELECT SUM(total_price)
-		FROM order_items
-		WHERE order_id = $1 AND deleted_at = 0
-	`, orderID).Scan(&totalPrice)
+	rows, err := db.Query(ctx, `
+		SELECT oi.id, oi.total_price, COALESCE(p.tax_category, $2)
+		FROM order_items oi
+		LEFT JOIN products p ON p.id = oi.product_id
+		WHERE oi.order_id = $1 AND oi.deleted_at = 0
+		ORDER BY oi.created_at, oi.id
+	`, orderID, tax.DefaultCategory)
 	if err != nil {
 		return err
 	}
+	var (
+		itemIDs    []string
+		items      []tax.Item
+		totalPrice money.Amount
+	)
+	for rows.Next() {
+		var (
+			itemID string
+			item   tax.Item
+		)
+		if err := rows.Scan(&itemID, &item.Total, &item.Category); err != nil {
+			rows.Close()
+			return err
+		}
+		itemIDs = append(itemIDs, itemID)
+		items = append(items, item)
+		totalPrice += item.Total
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Tax is worked out on what is left of each item once the coupon discounts are spread over them
+	region, _ := taxes.Region(latitude, longitude)
+	var taxTotal money.Amount
+	for i, taxAmount := range region.Items(items, discountTotal) {
+		_, err := db.Exec(ctx, `
+			UPDATE order_items
+			SET tax_amount = $1
+			WHERE id = $2
+		`, taxAmount, itemIDs[i])
+		if err != nil {
+			return fmt.Errorf("failed to store order item tax: %w", err)
+		}
+		taxTotal += taxAmount
+	}
 
+	// The delivery zone was resolved when the order was created; a zone that has since been
+	// removed from the configuration is looked up again from the coordinates
+	var shippingFee money.Amount
+	if len(items) > 0 && !freeShipping {
+		destination := shipping.Point{Latitude: latitude, Longitude: longitude}
+		zone, ok := zones.Get(shippingZone)
+		if !ok {
+			zone, ok = zones.Locate(destination)
+		}
+		if ok {
+			shippingFee = zone.Fee.Fee(totalPrice.Minus(discountTotal), rate, destination)
+		}
+	}
+
+	// Coupon discounts were taken off the subtotal when they were applied
 	query := `
 		UPDATE orders
-		SET total_price = $1
-		WHERE id = $2
+		SET total_price = GREATEST($1 - discount_total, 0) + $2 + $3,
+			tax_total = $4,
+			tax_inclusive = $5,
+			shipping_fee = $3
+		WHERE id = $6
 	`
 
-	_, err = r.db.Exec(ctx, query, totalPrice.Float64, orderID)
+	addedTax := taxTotal
+	if region.Inclusive {
+		addedTax = 0
+	}
+	_, err = db.Exec(ctx, query, totalPrice, addedTax, shippingFee, taxTotal, region.Inclusive, orderID)
 	return err
 }
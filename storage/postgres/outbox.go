@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flash_sale/flash_sale_order_service/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// outboxLock names the advisory lock held while the outbox is being published, so two relays
+// never publish the events of one aggregate out of order.
+const outboxLock = "outbox_relay"
+
+type OutboxRepo struct {
+	db *pgxpool.Pool
+}
+
+func NewOutboxRepo(db *pgxpool.Pool) *OutboxRepo {
+	return &OutboxRepo{
+		db: db,
+	}
+}
+
+// PublishOutboxEvents passes up to limit of the oldest events to publish and deletes them once
+// it succeeds. If another caller is publishing already, nothing is published.
+func (r *OutboxRepo) PublishOutboxEvents(ctx context.Context, limit int, publish func(ctx context.Context, events []models.OutboxEvent) error) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var locked bool
+	if err := tx.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock(hashtext($1))`, outboxLock).Scan(&locked); err != nil {
+		return 0, fmt.Errorf("failed to lock outbox: %w", err)
+	}
+	if !locked {
+		return 0, nil
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, event_id, topic, aggregate_type, aggregate_id, payload, created_at
+		FROM outbox
+		ORDER BY id
+		LIMIT $1
+	`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get outbox events: %w", err)
+	}
+	var events []models.OutboxEvent
+	for rows.Next() {
+		var event models.OutboxEvent
+		if err := rows.Scan(&event.Id, &event.EventId, &event.Topic, &event.AggregateType, &event.AggregateId, &event.Payload, &event.CreatedAt); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		events = append(events, event)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to get outbox events: %w", err)
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	if err := publish(ctx, events); err != nil {
+		return 0, err
+	}
+
+	ids := make([]int64, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.Id)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM outbox WHERE id = ANY($1)`, ids); err != nil {
+		return 0, fmt.Errorf("failed to delete published outbox events: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return len(events), nil
+}
+
+// enqueueEvent writes a domain event to the outbox, to be published once tx commits.
+func enqueueEvent(ctx context.Context, tx pgx.Tx, topic, aggregateType, aggregateID string, event proto.Message) error {
+	payload, err := protojson.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", topic, err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO outbox (event_id, topic, aggregate_type, aggregate_id, payload, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+	`, uuid.NewString(), topic, aggregateType, aggregateID, payload)
+	if err != nil {
+		return fmt.Errorf("failed to write %s event to the outbox: %w", topic, err)
+	}
+	return nil
+}
@@ -5,22 +5,43 @@ import (
 	"fmt"
 
 	"github.com/flash_sale/flash_sale_order_service/config"
+	"github.com/flash_sale/flash_sale_order_service/currency"
+	"github.com/flash_sale/flash_sale_order_service/pricing"
+	"github.com/flash_sale/flash_sale_order_service/quote"
+	"github.com/flash_sale/flash_sale_order_service/shipping"
 	"github.com/flash_sale/flash_sale_order_service/storage"
+	"github.com/flash_sale/flash_sale_order_service/tax"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// querier is the part of the pgx API shared by pools and transactions,
+// so helpers can run either on their own or inside a pgx.Tx.
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 // StoragePg implements the storage.StorageI interface for PostgreSQL.
 type StoragePg struct {
-	db             *pgx.Conn
+	db             *pgxpool.Pool
 	basketRepo     storage.BasketI
 	basketItemRepo storage.BasketItemI
 	orderRepo      storage.OrderI
 	orderItemRepo  storage.OrderItemI
+	flashSaleRepo  storage.FlashSaleI
+	couponRepo     storage.CouponI
+	addressRepo    storage.AddressI
+	outboxRepo     storage.OutboxI
 }
 
-// NewStoragePg creates a new PostgreSQL storage instance.
-func NewStoragePg(cfg config.Config) (storage.StorageI, error) {
+// NewStoragePg creates a new PostgreSQL storage instance backed by a connection pool.
+// stockCache may be nil, in which case flash sale stock is only checked in PostgreSQL,
+// taxes may be nil, in which case orders are not taxed, and zones may be nil, in which case
+// orders are delivered anywhere without a shipping fee.
+func NewStoragePg(cfg config.Config, stockCache storage.StockCacheI, rates currency.ExchangeRateProvider, engine *pricing.Engine, quotes *quote.Signer, taxes *tax.Table, zones *shipping.Zones) (storage.StorageI, error) {
 	dbCon := fmt.Sprintf("postgresql://%s:%s@%s:%d/%s",
 		cfg.PostgresUser,
 		cfg.PostgresPassword,
@@ -29,33 +50,50 @@ func NewStoragePg(cfg config.Config) (storage.StorageI, error) {
 		cfg.PostgresDB,
 	)
 
-	db, err := pgx.Connect(context.Background(), dbCon)
+	poolConfig, err := pgxpool.ParseConfig(dbCon)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing postgres config: %w", err)
+	}
+
+	poolConfig.MaxConns = cfg.PostgresMaxConns
+	poolConfig.MinConns = cfg.PostgresMinConns
+	poolConfig.MaxConnLifetime = cfg.PostgresMaxConnLifetime
+	poolConfig.MaxConnIdleTime = cfg.PostgresMaxConnIdleTime
+	poolConfig.HealthCheckPeriod = cfg.PostgresHealthCheckPeriod
+
+	db, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("error connecting to postgres: %w", err)
 	}
 
 	if err = db.Ping(context.Background()); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("error pinging postgres: %w", err)
 	}
 
+	rules := NewFlashSaleRules(cfg)
+
 	return &StoragePg{
 		db:             db,
-		basketRepo:     NewBasketRepo(db),
-		basketItemRepo: NewBasketItemRepo(db),
-		orderRepo:      NewOrderRepo(db),
-		orderItemRepo:  NewOrderItemRepo(db),
+		basketRepo:     NewBasketRepo(db, stockCache, rates, engine, quotes),
+		basketItemRepo: NewBasketItemRepo(db, stockCache, rules, rates, engine),
+		orderRepo:      NewOrderRepo(db, stockCache, taxes, zones, cfg.IdempotencyKeyTTL),
+		orderItemRepo:  NewOrderItemRepo(db, stockCache, rules, rates, engine, quotes, taxes, zones, cfg.IdempotencyKeyTTL),
+		flashSaleRepo:  NewFlashSaleRepo(db, stockCache),
+		couponRepo:     NewCouponRepo(db),
+		addressRepo:    NewAddressRepo(db),
+		outboxRepo:     NewOutboxRepo(db),
 	}, nil
 }
 
-// Close closes the PostgreSQL connection.
+// Close closes every connection in the PostgreSQL pool.
 func (s *StoragePg) Close() {
-	if err := s.db.Close(context.Background()); err != nil {
-		if pgErr, ok := err.(*pgconn.PgError); ok {
-			fmt.Printf("Error closing database connection: %s (Code: %s)\n", pgErr.Message, pgErr.Code)
-		} else {
-			fmt.Printf("Error closing database connection: %s\n", err.Error())
-		}
-	}
+	s.db.Close()
+}
+
+// Ping checks that PostgreSQL is reachable through the pool.
+func (s *StoragePg) Ping(ctx context.Context) error {
+	return s.db.Ping(ctx)
 }
 
 // Basket returns the BasketI implementation for PostgreSQL.
@@ -77,3 +115,23 @@ func (s *StoragePg) Order() storage.OrderI {
 func (s *StoragePg) OrderItem() storage.OrderItemI {
 	return s.orderItemRepo
 }
+
+// FlashSale returns the FlashSaleI implementation for PostgreSQL.
+func (s *StoragePg) FlashSale() storage.FlashSaleI {
+	return s.flashSaleRepo
+}
+
+// Coupon returns the CouponI implementation for PostgreSQL.
+func (s *StoragePg) Coupon() storage.CouponI {
+	return s.couponRepo
+}
+
+// Address returns the AddressI implementation for PostgreSQL.
+func (s *StoragePg) Address() storage.AddressI {
+	return s.addressRepo
+}
+
+// Outbox returns the OutboxI implementation for PostgreSQL.
+func (s *StoragePg) Outbox() storage.OutboxI {
+	return s.outboxRepo
+}
@@ -0,0 +1,184 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flash_sale/flash_sale_order_service/genproto/order_service"
+	"github.com/flash_sale/flash_sale_order_service/models"
+	"github.com/flash_sale/flash_sale_order_service/money"
+	"github.com/flash_sale/flash_sale_order_service/pricing"
+)
+
+// promotionValidity caches whether the flash sale event products and discounts referenced
+// by the items of one basket are running, so each is only checked once.
+type promotionValidity struct {
+	flashSales map[string]bool
+	discounts  map[string]bool
+}
+
+func newPromotionValidity() *promotionValidity {
+	return &promotionValidity{
+		flashSales: make(map[string]bool),
+		discounts:  make(map[string]bool),
+	}
+}
+
+func (v *promotionValidity) flashSale(ctx context.Context, db querier, flashSaleEventProductID string) bool {
+	isValid, ok := v.flashSales[flashSaleEventProductID]
+	if !ok {
+		isValid = isFlashSaleEventProductValid(ctx, db, flashSaleEventProductID)
+		v.flashSales[flashSaleEventProductID] = isValid
+	}
+	return isValid
+}
+
+func (v *promotionValidity) discount(ctx context.Context, db querier, discountID string) bool {
+	isValid, ok := v.discounts[discountID]
+	if !ok {
+		isValid = isDiscountValid(ctx, db, discountID)
+		v.discounts[discountID] = isValid
+	}
+	return isValid
+}
+
+// getProduct reads a product with its prices.
+func getProduct(ctx context.Context, db querier, productID string) (models.Product, error) {
+	var (
+		product                 models.Product
+		basePrice, currentPrice string
+	)
+	// Prices are read as text so they are parsed exactly, whatever column type holds them. They go
+	// through numeric first, since a float column would print large or small values as 1e+06.
+	err := db.QueryRow(ctx, `
+		SELECT id, name, base_price::numeric::text, current_price::numeric::text, image_url, stock_quantity, created_at, updated_at
+		FROM products
+		WHERE id = $1 AND deleted_at = 0
+	`, productID).Scan(
+		&product.Id,
+		&product.Name,
+		&basePrice,
+		&currentPrice,
+		&product.ImageUrl,
+		&product.StockQuantity,
+		&product.CreatedAt,
+		&product.UpdatedAt,
+	)
+	if err != nil {
+		return product, fmt.Errorf("failed to get product: %w", err)
+	}
+	if product.BasePrice, err = money.Parse(basePrice); err != nil {
+		return product, fmt.Errorf("failed to read base price of product %s: %w", product.Id, err)
+	}
+	if product.CurrentPrice, err = money.Parse(currentPrice); err != nil {
+		return product, fmt.Errorf("failed to read current price of product %s: %w", product.Id, err)
+	}
+	return product, nil
+}
+
+// basketItemLine gathers the base price of a basket item and the promotions it refers to,
+// converted into the basket's currency at rate. Promotions that are not running are kept,
+// marked ineligible, so the pricing breakdown shows why they were not applied. Only a
+// FLASH_SALE item gets its flash sale and only a DISCOUNT item its discount, since the
+// stock holds, stock cache and purchase limits only guard FLASH_SALE items.
+func basketItemLine(ctx context.Context, db querier, basketItem *order_service.BasketItem, product models.Product, rate money.Rate, validity *promotionValidity) (pricing.Line, error) {
+	line := pricing.Line{
+		ID:        basketItem.Id,
+		BasePrice: product.BasePrice.Convert(rate),
+		Quantity:  basketItem.Quantity,
+	}
+
+	if basketItem.ProductType == "FLASH_SALE" && basketItem.FlashSaleEventProductId != "" {
+		promotion := pricing.Promotion{
+			ID:     basketItem.FlashSaleEventProductId,
+			Kind:   pricing.FlashSale,
+			Effect: pricing.FixedPrice,
+		}
+		if validity.flashSale(ctx, db, basketItem.FlashSaleEventProductId) {
+			var salePrice string
+			err := db.QueryRow(ctx, `
+				SELECT sale_price::numeric::text
+				FROM flash_sale_event_products
+				WHERE id = $1 AND deleted_at = 0
+			`, basketItem.FlashSaleEventProductId).Scan(&salePrice)
+			if err != nil {
+				return line, fmt.Errorf("failed to get flash sale event product: %w", err)
+			}
+			price, err := money.Parse(salePrice)
+			if err != nil {
+				return line, fmt.Errorf("failed to read sale price of flash sale event product %s: %w", basketItem.FlashSaleEventProductId, err)
+			}
+			promotion.Price = price.Convert(rate)
+		} else {
+			promotion.Ineligible = "flash sale is not running"
+		}
+		line.Promotions = append(line.Promotions, promotion)
+	}
+
+	if basketItem.ProductType == "DISCOUNT" && basketItem.DiscountProductId != "" {
+		promotion := pricing.Promotion{
+			ID:   basketItem.DiscountProductId,
+			Kind: pricing.Discount,
+		}
+		if validity.discount(ctx, db, basketItem.DiscountProductId) {
+			var discount models.Discount
+			err := db.QueryRow(ctx, `
+				SELECT discount_type, discount_value::numeric::text
+				FROM discounts
+				WHERE id = $1 AND deleted_at = 0
+			`, basketItem.DiscountProductId).Scan(
+				&discount.DiscountType,
+				&discount.DiscountValue,
+			)
+			if err != nil {
+				return line, fmt.Errorf("failed to get discount: %w", err)
+			}
+			if err := setDiscountEffect(&promotion, &discount, rate); err != nil {
+				return line, fmt.Errorf("failed to apply discount %s: %w", basketItem.DiscountProductId, err)
+			}
+		} else {
+			promotion.Ineligible = "discount is not active"
+		}
+		line.Promotions = append(line.Promotions, promotion)
+	}
+
+	return line, nil
+}
+
+// setDiscountEffect turns a product discount into the effect of a promotion.
+func setDiscountEffect(promotion *pricing.Promotion, discount *models.Discount, rate money.Rate) error {
+	switch discount.DiscountType {
+	case "PERCENTAGE":
+		percent, err := money.ParsePercent(discount.DiscountValue)
+		if err != nil {
+			return err
+		}
+		promotion.Effect = pricing.Percentage
+		promotion.Percent = percent
+	case "FIXED_AMOUNT":
+		amount, err := money.Parse(discount.DiscountValue)
+		if err != nil {
+			return err
+		}
+		promotion.Effect = pricing.FixedAmount
+		promotion.Amount = amount.Convert(rate)
+	default:
+		promotion.Ineligible = fmt.Sprintf("unknown discount type %q", discount.DiscountType)
+	}
+	return nil
+}
+
+// makePromotionStepProtos converts a pricing breakdown to protos.
+func makePromotionStepProtos(steps []pricing.Step, currencyCode string) []*order_service.PromotionStep {
+	protos := make([]*order_service.PromotionStep, 0, len(steps))
+	for _, step := range steps {
+		protos = append(protos, &order_service.PromotionStep{
+			PromotionId: step.PromotionID,
+			Kind:        string(step.Kind),
+			Applied:     step.Applied,
+			Discount:    makeMoneyProto(step.Discount, currencyCode),
+			Reason:      step.Reason,
+		})
+	}
+	return protos
+}
@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/flash_sale/flash_sale_order_service/config"
+	"github.com/flash_sale/flash_sale_order_service/genproto/order_service"
+	"github.com/flash_sale/flash_sale_order_service/storage"
+)
+
+// FlashSaleRules holds the flash sale settings shared by the basket item and order item repos.
+// Zero values turn the matching feature off.
+type FlashSaleRules struct {
+	// HoldTTL is how long a flash sale basket item holds its stock.
+	HoldTTL time.Duration
+	// MaxPerUserPerProduct caps the units of one flash sale product a user can buy, unless the
+	// product sets its own max_per_user.
+	MaxPerUserPerProduct int32
+	// MaxPerUserPerEvent caps the units a user can buy across all products of a flash sale event,
+	// unless the event sets its own max_per_user.
+	MaxPerUserPerEvent int32
+}
+
+// NewFlashSaleRules reads the flash sale settings from the configuration.
+func NewFlashSaleRules(cfg config.Config) FlashSaleRules {
+	rules := FlashSaleRules{
+		MaxPerUserPerProduct: cfg.FlashSaleMaxPerUserPerProduct,
+		MaxPerUserPerEvent:   cfg.FlashSaleMaxPerUserPerEvent,
+	}
+	if cfg.FlashSaleHoldEnabled {
+		rules.HoldTTL = cfg.FlashSaleHoldTTL
+	}
+	return rules
+}
+
+// hasFlashSaleLines reports whether any of items is a flash sale line, which a per-user cap
+// may apply to.
+func hasFlashSaleLines(items ...*order_service.BasketItem) bool {
+	for _, item := range items {
+		if item.ProductType == "FLASH_SALE" && item.FlashSaleEventProductId != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPurchaseLimits makes sure the user stays within the per-user caps once the flash sale
+// lines in items are bought. The caps set on a flash sale event or product take precedence
+// over the ones in rules. Units in orders that are not cancelled always count; units sitting
+// in the user's open baskets count too when countOpenBaskets is set.
+func checkPurchaseLimits(ctx context.Context, db querier, rules FlashSaleRules, userID string, items []*order_service.BasketItem, countOpenBaskets bool) error {
+	type usage struct {
+		eventID        string
+		productLimit   int32
+		productBought  int32
+		eventBought    int32
+		productPending int32
+	}
+	products := make(map[string]*usage)
+	eventLimits := make(map[string]int32)
+	eventPending := make(map[string]int32)
+
+	for _, item := range items {
+		if item.ProductType != "FLASH_SALE" || item.FlashSaleEventProductId == "" {
+			continue
+		}
+
+		u, ok := products[item.FlashSaleEventProductId]
+		if !ok {
+			var productLimit, eventLimit sql.NullInt32
+			u = &usage{}
+			err := db.QueryRow(ctx, `
+				WITH bought AS (
+					SELECT oi.flash_sale_event_product_id, oi.quantity - oi.cancelled_quantity AS quantity
+					FROM order_items oi
+					JOIN orders o ON o.id = oi.order_id
+					WHERE o.client_id = $1
+						AND o.status <> 'CANCELLED'
+						AND o.deleted_at = 0
+						AND oi.deleted_at = 0
+					UNION ALL
+					SELECT bi.flash_sale_event_product_id, bi.quantity
+					FROM basket_items bi
+					JOIN baskets b ON b.id = bi.basket_id
+					WHERE $3
+						AND b.user_id = $1
+						AND b.status = 'OPEN'
+						AND b.deleted_at = 0
+						AND bi.deleted_at = 0
+				)
+				SELECT
+					target.event_id,
+					target.max_per_user,
+					fse.max_per_user,
+					COALESCE(SUM(bought.quantity) FILTER (WHERE bought.flash_sale_event_product_id = target.id), 0),
+					COALESCE(SUM(bought.quantity), 0)
+				FROM flash_sale_event_products target
+				JOIN flash_sale_events fse ON fse.id = target.event_id
+				LEFT JOIN flash_sale_event_products fsep ON fsep.event_id = target.event_id
+				LEFT JOIN bought ON bought.flash_sale_event_product_id = fsep.id
+				WHERE target.id = $2
+				GROUP BY target.id, target.event_id, target.max_per_user, fse.max_per_user
+			`, userID, item.FlashSaleEventProductId, countOpenBaskets).Scan(
+				&u.eventID,
+				&productLimit,
+				&eventLimit,
+				&u.productBought,
+				&u.eventBought,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to count flash sale purchases: %w", err)
+			}
+
+			u.productLimit = rules.MaxPerUserPerProduct
+			if productLimit.Valid {
+				u.productLimit = productLimit.Int32
+			}
+			eventLimits[u.eventID] = rules.MaxPerUserPerEvent
+			if eventLimit.Valid {
+				eventLimits[u.eventID] = eventLimit.Int32
+			}
+			products[item.FlashSaleEventProductId] = u
+		}
+
+		u.productPending += item.Quantity
+		eventPending[u.eventID] += item.Quantity
+	}
+
+	for flashSaleEventProductID, u := range products {
+		if u.productLimit > 0 && u.productBought+u.productPending > u.productLimit {
+			return fmt.Errorf("%w: at most %d units of flash sale product %s per user, %d already taken",
+				storage.ErrPurchaseLimitExceeded, u.productLimit, flashSaleEventProductID, u.productBought)
+		}
+		if eventLimit := eventLimits[u.eventID]; eventLimit > 0 && u.eventBought+eventPending[u.eventID] > eventLimit {
+			return fmt.Errorf("%w: at most %d units of flash sale event %s per user, %d already taken",
+				storage.ErrPurchaseLimitExceeded, eventLimit, u.eventID, u.eventBought)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,241 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/flash_sale/flash_sale_order_service/genproto/order_service"
+	"github.com/flash_sale/flash_sale_order_service/money"
+	"github.com/flash_sale/flash_sale_order_service/pricing"
+	"github.com/flash_sale/flash_sale_order_service/quote"
+	"github.com/flash_sale/flash_sale_order_service/storage"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// QuoteBasket freezes the current prices of a basket's items into a signed quote. Coupons
+// are not part of the quote; they are still applied on top of it at checkout.
+func (r *BasketRepo) QuoteBasket(ctx context.Context, req *order_service.QuoteBasketRequest) (*order_service.Quote, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var currencyCode string
+	err = tx.QueryRow(ctx, `
+		SELECT currency_code
+		FROM baskets
+		WHERE id = $1 AND deleted_at = 0
+	`, req.Id).Scan(&currencyCode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get basket: %w", err)
+	}
+
+	rate, err := r.rates.Rate(ctx, money.DefaultCurrency, currencyCode)
+	if err != nil {
+		return nil, err
+	}
+
+	basketItems, err := listAllBasketItems(ctx, tx, req.Id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get basket items: %w", err)
+	}
+	if len(basketItems) == 0 {
+		return nil, fmt.Errorf("basket %s is empty", req.Id)
+	}
+
+	q := quote.Quote{
+		ID:           uuid.NewString(),
+		BasketID:     req.Id,
+		CurrencyCode: currencyCode,
+		ExpiresAt:    r.quotes.ExpiresAt(time.Now()),
+	}
+	validity := newPromotionValidity()
+	for _, basketItem := range basketItems {
+		product, err := getProduct(ctx, tx, basketItem.ProductId)
+		if err != nil {
+			return nil, err
+		}
+		line, err := basketItemLine(ctx, tx, basketItem, product, rate, validity)
+		if err != nil {
+			return nil, err
+		}
+		priced := r.pricing.PriceLine(line)
+
+		quoteLine := quote.Line{
+			BasketItemID: basketItem.Id,
+			Quantity:     basketItem.Quantity,
+			BasePrice:    priced.BasePrice,
+			UnitPrice:    priced.UnitPrice,
+		}
+		for _, kind := range priced.Applied() {
+			quoteLine.Promotions = append(quoteLine.Promotions, string(kind))
+		}
+		q.Lines = append(q.Lines, quoteLine)
+		q.Total += priced.Total
+	}
+	signature := r.quotes.Sign(q)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO price_quotes (id, basket_id, currency_code, total_price, expires_at, signature, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+	`, q.ID, q.BasketID, q.CurrencyCode, q.Total, q.ExpiresAt, signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create quote: %w", err)
+	}
+	for i, line := range q.Lines {
+		promotions := line.Promotions
+		if promotions == nil {
+			promotions = []string{}
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO price_quote_lines (quote_id, position, basket_item_id, quantity, base_price, unit_price, promotions)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, q.ID, i, line.BasketItemID, line.Quantity, line.BasePrice, line.UnitPrice, promotions)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create quote line: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return makeQuoteProto(q, signature), nil
+}
+
+// getQuote reads a quote with its lines and signature.
+func getQuote(ctx context.Context, db querier, quoteID string) (quote.Quote, string, error) {
+	q := quote.Quote{ID: quoteID}
+	var signature string
+	err := db.QueryRow(ctx, `
+		SELECT basket_id, currency_code, total_price, expires_at, signature
+		FROM price_quotes
+		WHERE id = $1
+	`, quoteID).Scan(&q.BasketID, &q.CurrencyCode, &q.Total, &q.ExpiresAt, &signature)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return q, "", fmt.Errorf("%w: %s", storage.ErrQuoteNotFound, quoteID)
+	}
+	if err != nil {
+		return q, "", fmt.Errorf("failed to get quote: %w", err)
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT basket_item_id, quantity, base_price, unit_price, promotions
+		FROM price_quote_lines
+		WHERE quote_id = $1
+		ORDER BY position
+	`, quoteID)
+	if err != nil {
+		return q, "", fmt.Errorf("failed to get quote lines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line quote.Line
+		if err := rows.Scan(&line.BasketItemID, &line.Quantity, &line.BasePrice, &line.UnitPrice, &line.Promotions); err != nil {
+			return q, "", fmt.Errorf("failed to scan quote line: %w", err)
+		}
+		if len(line.Promotions) == 0 {
+			line.Promotions = nil
+		}
+		q.Lines = append(q.Lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return q, "", fmt.Errorf("failed to read quote lines: %w", err)
+	}
+
+	return q, signature, nil
+}
+
+// quotedLines checks that a quote can be honored for a checkout of basketItems at now and
+// returns the frozen pricing of each item, by basket item ID.
+func quotedLines(ctx context.Context, db querier, signer *quote.Signer, quoteID, basketID, currencyCode string, basketItems []*order_service.BasketItem, now time.Time) (map[string]pricing.LineResult, error) {
+	q, signature, err := getQuote(ctx, db, quoteID)
+	if err != nil {
+		return nil, err
+	}
+	if !signer.Verify(q, signature) {
+		return nil, fmt.Errorf("%w: quote %s has a bad signature", storage.ErrInvalidQuote, quoteID)
+	}
+	if q.BasketID != basketID || q.CurrencyCode != currencyCode {
+		return nil, fmt.Errorf("%w: quote %s is not for basket %s", storage.ErrInvalidQuote, quoteID, basketID)
+	}
+	if q.Expired(now) {
+		return nil, fmt.Errorf("%w: quote %s expired at %s", storage.ErrQuoteExpired, quoteID, q.ExpiresAt.Format(time.RFC3339))
+	}
+
+	lines := make(map[string]pricing.LineResult, len(q.Lines))
+	for _, line := range q.Lines {
+		lines[line.BasketItemID] = quotedLine(line)
+	}
+	if len(lines) != len(basketItems) {
+		return nil, fmt.Errorf("%w: basket %s changed since it was quoted", storage.ErrInvalidQuote, basketID)
+	}
+	for _, basketItem := range basketItems {
+		line, ok := lines[basketItem.Id]
+		if !ok || line.Quantity != basketItem.Quantity {
+			return nil, fmt.Errorf("%w: basket %s changed since it was quoted", storage.ErrInvalidQuote, basketID)
+		}
+		line.Steps = quotedSteps(line.Steps, basketItem)
+		lines[basketItem.Id] = line
+	}
+
+	return lines, nil
+}
+
+// quotedLine turns a quote line back into the pricing of a line.
+func quotedLine(line quote.Line) pricing.LineResult {
+	result := pricing.LineResult{
+		ID:        line.BasketItemID,
+		BasePrice: line.BasePrice,
+		UnitPrice: line.UnitPrice,
+		Quantity:  line.Quantity,
+		Total:     line.UnitPrice.Times(line.Quantity),
+	}
+	for _, kind := range line.Promotions {
+		result.Steps = append(result.Steps, pricing.Step{Kind: pricing.Kind(kind), Applied: true})
+	}
+	return result
+}
+
+// quotedSteps fills in which promotion of the basket item each quoted step stands for.
+func quotedSteps(steps []pricing.Step, basketItem *order_service.BasketItem) []pricing.Step {
+	for i := range steps {
+		switch steps[i].Kind {
+		case pricing.FlashSale:
+			steps[i].PromotionID = basketItem.FlashSaleEventProductId
+		case pricing.Discount:
+			steps[i].PromotionID = basketItem.DiscountProductId
+		}
+	}
+	return steps
+}
+
+// Convert quote to proto model
+func makeQuoteProto(q quote.Quote, signature string) *order_service.Quote {
+	lines := make([]*order_service.QuoteLine, 0, len(q.Lines))
+	for _, line := range q.Lines {
+		lines = append(lines, &order_service.QuoteLine{
+			BasketItemId: line.BasketItemID,
+			Quantity:     line.Quantity,
+			UnitPrice:    makeMoneyProto(line.UnitPrice, q.CurrencyCode),
+			TotalPrice:   makeMoneyProto(line.UnitPrice.Times(line.Quantity), q.CurrencyCode),
+			Promotions:   line.Promotions,
+		})
+	}
+	return &order_service.Quote{
+		Id:           q.ID,
+		BasketId:     q.BasketID,
+		CurrencyCode: q.CurrencyCode,
+		Lines:        lines,
+		TotalPrice:   makeMoneyProto(q.Total, q.CurrencyCode),
+		ExpiresAt:    timestamppb.New(q.ExpiresAt),
+		Signature:    signature,
+	}
+}
@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/flash_sale/flash_sale_order_service/models"
+	"github.com/flash_sale/flash_sale_order_service/storage"
+	"github.com/jackc/pgx/v5"
+)
+
+// restockUnits puts units of a product back into stock, and into its flash sale
+// when the units were taken from one.
+func restockUnits(ctx context.Context, tx pgx.Tx, productID, flashSaleEventProductID string, quantity int32) error {
+	if quantity <= 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(ctx, `
+		UPDATE products
+		SET stock_quantity = stock_quantity + $1,
+			updated_at = NOW()
+		WHERE id = $2
+	`, quantity, productID)
+	if err != nil {
+		return fmt.Errorf("failed to restock product: %w", err)
+	}
+
+	if flashSaleEventProductID == "" {
+		return nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE flash_sale_event_products
+		SET available_quantity = available_quantity + $1,
+			updated_at = NOW()
+		WHERE id = $2
+	`, quantity, flashSaleEventProductID)
+	if err != nil {
+		return fmt.Errorf("failed to restock flash sale event product: %w", err)
+	}
+
+	return nil
+}
+
+// claimedFlashSaleEventProductID returns the flash sale event product the units of an order item
+// were taken from, or "" if they were not taken from a flash sale.
+func claimedFlashSaleEventProductID(item *models.OrderItem) string {
+	if !item.FlashSaleClaimed {
+		return ""
+	}
+	return item.FlashSaleEventProductId
+}
+
+// restockOrderItems puts the uncancelled units of every live item of an order back into stock and
+// returns the flash sale units released, per flash sale event product.
+func restockOrderItems(ctx context.Context, tx pgx.Tx, orderID string) (map[string]int32, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT product_id, flash_sale_event_product_id, flash_sale_claimed, quantity - cancelled_quantity
+		FROM order_items
+		WHERE order_id = $1 AND deleted_at = 0
+		FOR UPDATE
+	`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order items: %w", err)
+	}
+
+	type line struct {
+		productID               string
+		flashSaleEventProductID sql.NullString
+		flashSaleClaimed        bool
+		quantity                int32
+	}
+	var lines []line
+	for rows.Next() {
+		var l line
+		if err := rows.Scan(&l.productID, &l.flashSaleEventProductID, &l.flashSaleClaimed, &l.quantity); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to get order items: %w", err)
+		}
+		lines = append(lines, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get order items: %w", err)
+	}
+
+	released := make(map[string]int32)
+	for _, l := range lines {
+		// Lines not sold at the flash sale price never took units from it
+		flashSaleEventProductID := ""
+		if l.flashSaleClaimed {
+			flashSaleEventProductID = l.flashSaleEventProductID.String
+		}
+		if err := restockUnits(ctx, tx, l.productID, flashSaleEventProductID, l.quantity); err != nil {
+			return nil, err
+		}
+		if flashSaleEventProductID != "" {
+			released[flashSaleEventProductID] += l.quantity
+		}
+	}
+
+	return released, nil
+}
+
+// releaseCachedFlashSaleStock gives released flash sale units back to the stock cache.
+// It is called once the PostgreSQL side is settled.
+func releaseCachedFlashSaleStock(ctx context.Context, stockCache storage.StockCacheI, released map[string]int32) {
+	if stockCache == nil {
+		return
+	}
+	for flashSaleEventProductID, quantity := range released {
+		if err := stockCache.ReleaseFlashSaleStock(ctx, flashSaleEventProductID, quantity); err != nil {
+			log.Printf("failed to release cached stock of flash sale product %s: %v", flashSaleEventProductID, err)
+		}
+	}
+}
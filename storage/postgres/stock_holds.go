@@ -0,0 +1,195 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/flash_sale/flash_sale_order_service/models"
+	"github.com/flash_sale/flash_sale_order_service/storage"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// placeStockHold takes quantity units of a flash sale product out of its available
+// quantity and records them as held by the basket item until ttl passes.
+func placeStockHold(ctx context.Context, tx pgx.Tx, basketItemID, flashSaleEventProductID string, quantity int32, ttl time.Duration) error {
+	tag, err := tx.Exec(ctx, `
+		UPDATE flash_sale_event_products
+		SET available_quantity = available_quantity - $1,
+			updated_at = NOW()
+		WHERE id = $2 AND deleted_at = 0 AND available_quantity >= $1
+	`, quantity, flashSaleEventProductID)
+	if err != nil {
+		return fmt.Errorf("failed to reserve flash sale event product: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: flash sale product %s has fewer than %d units left",
+			storage.ErrSoldOut, flashSaleEventProductID, quantity)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO stock_holds (
+			id,
+			basket_item_id,
+			flash_sale_event_product_id,
+			quantity,
+			status,
+			expires_at,
+			created_at,
+			updated_at
+		) VALUES (
+			$1, $2, $3, $4, 'ACTIVE', NOW() + make_interval(secs => $5), NOW(), NOW()
+		)
+	`, uuid.NewString(), basketItemID, flashSaleEventProductID, quantity, ttl.Seconds())
+	if err != nil {
+		return fmt.Errorf("failed to insert stock hold: %w", err)
+	}
+
+	return nil
+}
+
+// releaseBasketItemStockHold releases the active hold of a basket item, if it has one.
+func releaseBasketItemStockHold(ctx context.Context, tx pgx.Tx, basketItemID string) ([]*models.StockHold, error) {
+	return releaseStockHolds(ctx, tx, `
+		UPDATE stock_holds
+		SET status = 'RELEASED',
+			updated_at = NOW()
+		WHERE basket_item_id = $1 AND status = 'ACTIVE'
+		RETURNING id, basket_item_id, flash_sale_event_product_id, quantity, status, expires_at, created_at, updated_at
+	`, basketItemID)
+}
+
+// releaseBasketStockHolds releases the active holds of every item of a basket.
+func releaseBasketStockHolds(ctx context.Context, tx pgx.Tx, basketID string) ([]*models.StockHold, error) {
+	return releaseStockHolds(ctx, tx, `
+		UPDATE stock_holds
+		SET status = 'RELEASED',
+			updated_at = NOW()
+		WHERE status = 'ACTIVE'
+			AND basket_item_id IN (SELECT id FROM basket_items WHERE basket_id = $1 AND deleted_at = 0)
+		RETURNING id, basket_item_id, flash_sale_event_product_id, quantity, status, expires_at, created_at, updated_at
+	`, basketID)
+}
+
+// releaseExpiredBasketStockHolds releases the holds of a basket that have run out but were not swept yet.
+func releaseExpiredBasketStockHolds(ctx context.Context, tx pgx.Tx, basketID string) ([]*models.StockHold, error) {
+	return releaseStockHolds(ctx, tx, `
+		UPDATE stock_holds
+		SET status = 'RELEASED',
+			updated_at = NOW()
+		WHERE status = 'ACTIVE'
+			AND expires_at <= NOW()
+			AND basket_item_id IN (SELECT id FROM basket_items WHERE basket_id = $1)
+		RETURNING id, basket_item_id, flash_sale_event_product_id, quantity, status, expires_at, created_at, updated_at
+	`, basketID)
+}
+
+// releaseExpiredStockHolds releases up to limit expired holds. Rows locked by another
+// transaction are skipped, so several instances can sweep at the same time.
+func releaseExpiredStockHolds(ctx context.Context, tx pgx.Tx, limit int) ([]*models.StockHold, error) {
+	return releaseStockHolds(ctx, tx, `
+		UPDATE stock_holds
+		SET status = 'RELEASED',
+			updated_at = NOW()
+		WHERE id IN (
+			SELECT id
+			FROM stock_holds
+			WHERE status = 'ACTIVE' AND expires_at <= NOW()
+			ORDER BY expires_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, basket_item_id, flash_sale_event_product_id, quantity, status, expires_at, created_at, updated_at
+	`, limit)
+}
+
+// releaseStockHolds runs an UPDATE that moves holds to RELEASED and puts their units
+// back into the available quantity of the flash sale products.
+func releaseStockHolds(ctx context.Context, tx pgx.Tx, query string, args ...interface{}) ([]*models.StockHold, error) {
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to release stock holds: %w", err)
+	}
+	holds, err := scanStockHolds(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to release stock holds: %w", err)
+	}
+
+	for _, hold := range holds {
+		_, err = tx.Exec(ctx, `
+			UPDATE flash_sale_event_products
+			SET available_quantity = available_quantity + $1,
+				updated_at = NOW()
+			WHERE id = $2
+		`, hold.Quantity, hold.FlashSaleEventProductId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to restock flash sale event product: %w", err)
+		}
+	}
+
+	return holds, nil
+}
+
+// consumeStockHolds marks the live holds of a basket as used by an order and returns
+// the IDs of the basket items they cover.
+func consumeStockHolds(ctx context.Context, tx pgx.Tx, basketID string) (map[string]bool, error) {
+	rows, err := tx.Query(ctx, `
+		UPDATE stock_holds
+		SET status = 'CONSUMED',
+			updated_at = NOW()
+		WHERE status = 'ACTIVE'
+			AND expires_at > NOW()
+			AND basket_item_id IN (SELECT id FROM basket_items WHERE basket_id = $1 AND deleted_at = 0)
+		RETURNING id, basket_item_id, flash_sale_event_product_id, quantity, status, expires_at, created_at, updated_at
+	`, basketID)
+	if err != nil {
+		return nil, err
+	}
+	holds, err := scanStockHolds(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	held := make(map[string]bool, len(holds))
+	for _, hold := range holds {
+		held[hold.BasketItemId] = true
+	}
+
+	return held, nil
+}
+
+func scanStockHolds(rows pgx.Rows) ([]*models.StockHold, error) {
+	defer rows.Close()
+
+	var holds []*models.StockHold
+	for rows.Next() {
+		var hold models.StockHold
+		err := rows.Scan(
+			&hold.Id,
+			&hold.BasketItemId,
+			&hold.FlashSaleEventProductId,
+			&hold.Quantity,
+			&hold.Status,
+			&hold.ExpiresAt,
+			&hold.CreatedAt,
+			&hold.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		holds = append(holds, &hold)
+	}
+
+	return holds, rows.Err()
+}
+
+// releaseCachedStockHolds gives the units of released holds back to the stock cache.
+// It is called after the releasing transaction has committed.
+func releaseCachedStockHolds(ctx context.Context, stockCache storage.StockCacheI, holds []*models.StockHold) {
+	released := make(map[string]int32)
+	for _, hold := range holds {
+		released[hold.FlashSaleEventProductId] += hold.Quantity
+	}
+	releaseCachedFlashSaleStock(ctx, stockCache, released)
+}
@@ -0,0 +1,144 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/flash_sale/flash_sale_order_service/models"
+	"github.com/flash_sale/flash_sale_order_service/storage"
+	"github.com/go-redis/redis/v8"
+)
+
+// reserveStockScript takes ARGV[1] units from the counter in KEYS[1] only if that many are left.
+// It returns -2 when the counter is not loaded, -1 when there are not enough units,
+// and the remaining quantity otherwise.
+var reserveStockScript = redis.NewScript(`
+local available = redis.call('GET', KEYS[1])
+if not available then
+	return -2
+end
+local quantity = tonumber(ARGV[1])
+if tonumber(available) < quantity then
+	return -1
+end
+return redis.call('DECRBY', KEYS[1], quantity)
+`)
+
+// releaseStockScript gives ARGV[1] units back to the counter in KEYS[1], if it is still loaded.
+var releaseStockScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return -2
+end
+return redis.call('INCRBY', KEYS[1], ARGV[1])
+`)
+
+// flashSaleStockKeyPrefix starts the key of every flash sale product counter.
+const flashSaleStockKeyPrefix = "flash_sale_stock:"
+
+func flashSaleStockKey(flashSaleEventProductID string) string {
+	return flashSaleStockKeyPrefix + flashSaleEventProductID
+}
+
+// SetFlashSaleStock loads the given counters, each expiring when its flash sale event ends, and
+// drops every other counter, so products whose event was deactivated or deleted stop being gated.
+func (c *Client) SetFlashSaleStock(ctx context.Context, stockList []*models.FlashSaleStock) error {
+	active := make(map[string]bool, len(stockList))
+	pipe := c.Pipeline()
+	for _, stock := range stockList {
+		key := flashSaleStockKey(stock.FlashSaleEventProductId)
+		ttl := time.Until(stock.EndTime)
+		if ttl <= 0 {
+			pipe.Del(ctx, key)
+			continue
+		}
+		active[key] = true
+		pipe.Set(ctx, key, stock.AvailableQuantity, ttl)
+	}
+
+	iter := c.Scan(ctx, 0, flashSaleStockKeyPrefix+"*", 0).Iterator()
+	for iter.Next(ctx) {
+		if !active[iter.Val()] {
+			pipe.Del(ctx, iter.Val())
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return fmt.Errorf("failed to list cached flash sale stock: %w", err)
+	}
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// ReserveFlashSaleStock atomically takes quantity units from a flash sale product counter.
+func (c *Client) ReserveFlashSaleStock(ctx context.Context, flashSaleEventProductID string, quantity int32) error {
+	remaining, err := reserveStockScript.Run(ctx, c.Client, []string{flashSaleStockKey(flashSaleEventProductID)}, quantity).Int64()
+	if err != nil {
+		return err
+	}
+
+	switch remaining {
+	case -2:
+		return storage.ErrStockNotCached
+	case -1:
+		return fmt.Errorf("%w: flash sale product %s has fewer than %d units left", storage.ErrSoldOut, flashSaleEventProductID, quantity)
+	}
+
+	return nil
+}
+
+// ReleaseFlashSaleStock gives quantity units back to a flash sale product counter.
+func (c *Client) ReleaseFlashSaleStock(ctx context.Context, flashSaleEventProductID string, quantity int32) error {
+	return releaseStockScript.Run(ctx, c.Client, []string{flashSaleStockKey(flashSaleEventProductID)}, quantity).Err()
+}
+
+// StockReconciler keeps the Redis flash sale counters in line with PostgreSQL.
+//
+// Checkouts decrement Redis first and PostgreSQL inside their transaction, so the two
+// can drift when a process dies between the steps. PostgreSQL is authoritative: every
+// run overwrites the counters with its available_quantity. Ended events simply expire, and
+// the counters of events that are no longer active are removed.
+type StockReconciler struct {
+	client   *Client
+	storage  storage.StorageI
+	interval time.Duration
+}
+
+// NewStockReconciler creates a new StockReconciler instance.
+func NewStockReconciler(client *Client, storage storage.StorageI, interval time.Duration) *StockReconciler {
+	return &StockReconciler{
+		client:   client,
+		storage:  storage,
+		interval: interval,
+	}
+}
+
+// Run preloads the counters and then reconciles them every interval until ctx is done.
+func (r *StockReconciler) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Reconcile(ctx); err != nil {
+			log.Printf("failed to reconcile flash sale stock: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile copies the current PostgreSQL stock of every active flash sale product into Redis
+// and removes the counters of the others.
+func (r *StockReconciler) Reconcile(ctx context.Context) error {
+	stockList, err := r.storage.FlashSale().ListActiveFlashSaleStock(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list flash sale stock: %w", err)
+	}
+
+	return r.client.SetFlashSaleStock(ctx, stockList)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/flash_sale/flash_sale_order_service/genproto/order_service"
+	"github.com/flash_sale/flash_sale_order_service/models"
 )
 
 // StorageI defines the interface for interacting with the storage layer.
@@ -12,6 +13,13 @@ type StorageI interface {
 	BasketItem() BasketItemI
 	Order() OrderI
 	OrderItem() OrderItemI
+	FlashSale() FlashSaleI
+	Coupon() CouponI
+	Address() AddressI
+	Outbox() OutboxI
+
+	Ping(ctx context.Context) error
+	Close()
 }
 
 // BasketI defines methods for interacting with basket data.
@@ -22,6 +30,8 @@ type BasketI interface {
 	DeleteBasket(ctx context.Context, req *order_service.DeleteBasketRequest) (*order_service.DeleteBasketResponse, error)
 	ListBaskets(ctx context.Context, req *order_service.ListBasketsRequest) (*order_service.ListBasketsResponse, error)
 	UpdateBasketStatus(ctx context.Context, req *order_service.UpdateBasketStatusRequest) (*order_service.Basket, error)
+	GetBasketSummary(ctx context.Context, req *order_service.GetBasketSummaryRequest) (*order_service.GetBasketSummaryResponse, error)
+	QuoteBasket(ctx context.Context, req *order_service.QuoteBasketRequest) (*order_service.Quote, error)
 }
 
 // BasketItemI defines methods for interacting with basket item data.
@@ -29,6 +39,8 @@ type BasketItemI interface {
 	CreateBasketItem(ctx context.Context, req *order_service.CreateBasketItemRequest) (*order_service.BasketItem, error)
 	GetBasketItem(ctx context.Context, req *order_service.GetBasketItemRequest) (*order_service.BasketItem, error)
 	DeleteBasketItem(ctx context.Context, req *order_service.DeleteBasketItemRequest) (*order_service.DeleteBasketItemResponse, error)
+	UpdateBasketItemQuantity(ctx context.Context, req *order_service.UpdateBasketItemQuantityRequest) (*order_service.BasketItem, error)
+	ClearBasketItems(ctx context.Context, req *order_service.ClearBasketItemsRequest) (*order_service.ClearBasketItemsResponse, error)
 	ListBasketItems(ctx context.Context, req *order_service.ListBasketItemsRequest) (*order_service.ListBasketItemsResponse, error)
 }
 
@@ -40,6 +52,9 @@ type OrderI interface {
 	DeleteOrder(ctx context.Context, req *order_service.DeleteOrderRequest) (*order_service.DeleteOrderResponse, error)
 	ListOrders(ctx context.Context, req *order_service.ListOrdersRequest) (*order_service.ListOrdersResponse, error)
 	UpdateOrderStatus(ctx context.Context, req *order_service.UpdateOrderStatusRequest) (*order_service.Order, error)
+	GetOrderHistory(ctx context.Context, req *order_service.GetOrderHistoryRequest) (*order_service.GetOrderHistoryResponse, error)
+	CancelOrder(ctx context.Context, req *order_service.CancelOrderRequest) (*order_service.Order, error)
+	DeleteExpiredIdempotencyKeys(ctx context.Context) (int, error)
 }
 
 // OrderItemI defines methods for interacting with order item data.
@@ -48,4 +63,45 @@ type OrderItemI interface {
 	ListOrderItems(ctx context.Context, req *order_service.ListOrderItemsRequest) (*order_service.ListOrderItemsResponse, error)
 	ConvertBasketToOrderItems(ctx context.Context, req *order_service.ConvertBasketToOrderItemsRequest) (*order_service.ConvertBasketToOrderItemsResponse, error)
 	DeleteOrderItem(ctx context.Context, req *order_service.DeleteOrderItemRequest) (string, error)
+	CancelOrderItem(ctx context.Context, req *order_service.CancelOrderItemRequest) (*order_service.CancelOrderItemResponse, error)
+}
+
+// CouponI defines methods for interacting with coupon data.
+type CouponI interface {
+	CreateCoupon(ctx context.Context, req *order_service.CreateCouponRequest) (*order_service.Coupon, error)
+	GetCoupon(ctx context.Context, req *order_service.GetCouponRequest) (*order_service.Coupon, error)
+	UpdateCoupon(ctx context.Context, req *order_service.UpdateCouponRequest) (*order_service.Coupon, error)
+	DeleteCoupon(ctx context.Context, req *order_service.DeleteCouponRequest) (*order_service.DeleteCouponResponse, error)
+	ListCoupons(ctx context.Context, req *order_service.ListCouponsRequest) (*order_service.ListCouponsResponse, error)
+}
+
+// AddressI defines methods for interacting with the saved addresses of clients.
+type AddressI interface {
+	CreateAddress(ctx context.Context, req *order_service.CreateAddressRequest) (*order_service.Address, error)
+	GetAddress(ctx context.Context, req *order_service.GetAddressRequest) (*order_service.Address, error)
+	UpdateAddress(ctx context.Context, req *order_service.UpdateAddressRequest) (*order_service.Address, error)
+	DeleteAddress(ctx context.Context, req *order_service.DeleteAddressRequest) (*order_service.DeleteAddressResponse, error)
+	ListAddresses(ctx context.Context, req *order_service.ListAddressesRequest) (*order_service.ListAddressesResponse, error)
+}
+
+// OutboxI defines methods for publishing the domain events waiting in the outbox.
+type OutboxI interface {
+	// PublishOutboxEvents passes up to limit of the oldest events to publish and removes them
+	// once it succeeds, returning how many were published. Events are only ever handed to one
+	// caller at a time, so they are published in the order they were written.
+	PublishOutboxEvents(ctx context.Context, limit int, publish func(ctx context.Context, events []models.OutboxEvent) error) (int, error)
+}
+
+// FlashSaleI defines methods for interacting with flash sale stock and stock holds.
+type FlashSaleI interface {
+	ListActiveFlashSaleStock(ctx context.Context) ([]*models.FlashSaleStock, error)
+	GetFlashSaleStock(ctx context.Context, req *order_service.GetFlashSaleStockRequest) (*order_service.GetFlashSaleStockResponse, error)
+	ReleaseExpiredStockHolds(ctx context.Context) (int, error)
+}
+
+// StockCacheI is a fast gate in front of the flash sale stock kept in PostgreSQL.
+// It only rejects requests early; PostgreSQL stays the source of truth.
+type StockCacheI interface {
+	ReserveFlashSaleStock(ctx context.Context, flashSaleEventProductID string, quantity int32) error
+	ReleaseFlashSaleStock(ctx context.Context, flashSaleEventProductID string, quantity int32) error
 }
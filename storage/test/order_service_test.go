@@ -2,25 +2,47 @@ package test
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/flash_sale/flash_sale_order_service/currency"
 	"github.com/flash_sale/flash_sale_order_service/genproto/order_service"
+	"github.com/flash_sale/flash_sale_order_service/money"
+	"github.com/flash_sale/flash_sale_order_service/orderstatus"
+	"github.com/flash_sale/flash_sale_order_service/outbox"
+	"github.com/flash_sale/flash_sale_order_service/pricing"
+	"github.com/flash_sale/flash_sale_order_service/quote"
+	"github.com/flash_sale/flash_sale_order_service/shipping"
+	"github.com/flash_sale/flash_sale_order_service/storage"
 	"github.com/flash_sale/flash_sale_order_service/storage/postgres"
+	"github.com/flash_sale/flash_sale_order_service/tax"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func TestOrderRepo(t *testing.T) {
 	db := createDBConnection(t) // Use the existing createDBConnection function
-	defer db.Close(context.Background())
+	defer db.Close()
 
 	// Initialize repositories
-	basketRepo := postgres.NewBasketRepo(db)
-	basketItemRepo := postgres.NewBasketItemRepo(db)
-	orderRepo := postgres.NewOrderRepo(db)
-	orderItemRepo := postgres.NewOrderItemRepo(db)
+	exchangeRates := currency.NewStaticProvider("USD", map[string]money.Rate{"EUR": 92000000}) // 0.92
+	pricingEngine := pricing.NewEngine(pricing.DefaultRules())
+	quoteSigner := quote.NewSigner([]byte("test key"), time.Minute)
+	taxTable := tax.NewTable([]tax.Region{{
+		Name:   "taxed",
+		Bounds: &tax.Bounds{MinLatitude: 60, MinLongitude: 60, MaxLatitude: 61, MaxLongitude: 61},
+		Rates:  map[string]money.Percent{tax.DefaultCategory: 1000}, // 10% on top
+	}})
+	basketRepo := postgres.NewBasketRepo(db, nil, exchangeRates, pricingEngine, quoteSigner)
+	basketItemRepo := postgres.NewBasketItemRepo(db, nil, postgres.FlashSaleRules{}, exchangeRates, pricingEngine)
+	orderRepo := postgres.NewOrderRepo(db, nil, taxTable, nil, time.Hour)
+	orderItemRepo := postgres.NewOrderItemRepo(db, nil, postgres.FlashSaleRules{}, exchangeRates, pricingEngine, quoteSigner, taxTable, nil, time.Hour)
+	couponRepo := postgres.NewCouponRepo(db)
 
 	// 1. Create a user
 	userID := uuid.NewString()
@@ -102,13 +124,19 @@ func TestOrderRepo(t *testing.T) {
 		assert.NotNil(t, createdBasket)
 
 		// Update the basket
-		createdBasket.Status = "CHECKED_OUT"
 		updatedBasket, err := basketRepo.UpdateBasket(context.Background(), &order_service.UpdateBasketRequest{
 			Basket: createdBasket,
 		})
 		assert.NoError(t, err)
 		assert.NotNil(t, updatedBasket)
-		assert.Equal(t, "CHECKED_OUT", updatedBasket.Status)
+		assert.Equal(t, "OPEN", updatedBasket.Status)
+
+		// Only a checkout may check a basket out
+		createdBasket.Status = "CHECKED_OUT"
+		_, err = basketRepo.UpdateBasket(context.Background(), &order_service.UpdateBasketRequest{
+			Basket: createdBasket,
+		})
+		assert.ErrorIs(t, err, storage.ErrInvalidBasketStatus)
 
 		defer deleteBasket(t, db, createdBasket.Id)
 	})
@@ -142,7 +170,7 @@ func TestOrderRepo(t *testing.T) {
 			},
 			{
 				UserId: userID,
-				Status: "CHECKED_OUT",
+				Status: "OPEN",
 			},
 		}
 
@@ -177,11 +205,18 @@ func TestOrderRepo(t *testing.T) {
 		// Update the basket status
 		updatedBasket, err := basketRepo.UpdateBasketStatus(context.Background(), &order_service.UpdateBasketStatusRequest{
 			Id:     createdBasket.Id,
-			Status: "CHECKED_OUT",
+			Status: "OPEN",
 		})
 		assert.NoError(t, err)
 		assert.NotNil(t, updatedBasket)
-		assert.Equal(t, "CHECKED_OUT", updatedBasket.Status)
+		assert.Equal(t, "OPEN", updatedBasket.Status)
+
+		// Only a checkout may check a basket out
+		_, err = basketRepo.UpdateBasketStatus(context.Background(), &order_service.UpdateBasketStatusRequest{
+			Id:     createdBasket.Id,
+			Status: "CHECKED_OUT",
+		})
+		assert.ErrorIs(t, err, storage.ErrInvalidBasketStatus)
 
 		defer deleteBasket(t, db, createdBasket.Id)
 	})
@@ -207,8 +242,8 @@ func TestOrderRepo(t *testing.T) {
 				FlashSaleEventProductId: "", // Not a flash sale item
 				DiscountProductId:       "", // Not a discount item
 				Quantity:                2,
-				UnitPrice:               10.0,
-				TotalPrice:              20.0,
+				UnitPrice:               usd(1000),
+				TotalPrice:              usd(2000),
 				ProductType:             "REGULAR",
 			},
 		})
@@ -220,6 +255,88 @@ func TestOrderRepo(t *testing.T) {
 		defer deleteBasket(t, db, createdBasket.Id)
 	})
 
+	t.Run("CreateBasketItemWithLargePrice", func(t *testing.T) {
+		// A float column prints this price as 1e+06
+		productID := uuid.NewString()
+		createProduct(t, db, productID, "Large Product", 1000000.0)
+		defer deleteProduct(t, db, productID)
+
+		basketID := uuid.NewString()
+		createBasket(t, db, basketID, userID, "OPEN")
+		defer deleteBasket(t, db, basketID)
+
+		basketItem, err := basketItemRepo.CreateBasketItem(context.Background(), &order_service.CreateBasketItemRequest{
+			BasketItem: &order_service.BasketItem{
+				BasketId:    basketID,
+				ProductId:   productID,
+				Quantity:    1,
+				ProductType: "REGULAR",
+			},
+		})
+		assert.NoError(t, err)
+		defer deleteBasketItem(t, db, basketItem.GetId())
+		assert.Equal(t, int64(100000000), basketItem.GetUnitPrice().GetMinorUnits())
+	})
+
+	t.Run("GetBasketSummary", func(t *testing.T) {
+		basket, err := basketRepo.CreateBasket(context.Background(), &order_service.CreateBasketRequest{
+			Basket: &order_service.Basket{UserId: userID, Status: "OPEN"},
+		})
+		assert.NoError(t, err)
+		defer deleteBasket(t, db, basket.Id)
+
+		// Prices sent by the client are replaced with the ones worked out from the promotions
+		discounted, err := basketItemRepo.CreateBasketItem(context.Background(), &order_service.CreateBasketItemRequest{
+			BasketItem: &order_service.BasketItem{
+				BasketId:          basket.Id,
+				ProductId:         product1ID,
+				DiscountProductId: discountID,
+				Quantity:          2,
+				UnitPrice:         usd(1),
+				TotalPrice:        usd(2),
+				ProductType:       "DISCOUNT",
+			},
+		})
+		assert.NoError(t, err)
+		defer deleteBasketItem(t, db, discounted.Id)
+		assert.Equal(t, int64(900), discounted.UnitPrice.GetMinorUnits())
+		assert.Equal(t, int64(1800), discounted.TotalPrice.GetMinorUnits())
+
+		flashSale, err := basketItemRepo.CreateBasketItem(context.Background(), &order_service.CreateBasketItemRequest{
+			BasketItem: &order_service.BasketItem{
+				BasketId:                basket.Id,
+				ProductId:               product2ID,
+				FlashSaleEventProductId: flashSaleEventProductID,
+				Quantity:                1,
+				ProductType:             "FLASH_SALE",
+			},
+		})
+		assert.NoError(t, err)
+		defer deleteBasketItem(t, db, flashSale.Id)
+		assert.Equal(t, int64(1800), flashSale.UnitPrice.GetMinorUnits())
+
+		summary, err := basketRepo.GetBasketSummary(context.Background(), &order_service.GetBasketSummaryRequest{Id: basket.Id})
+		assert.NoError(t, err)
+		assert.Len(t, summary.Lines, 2)
+		assert.Equal(t, int64(4000), summary.Subtotal.GetMinorUnits())
+		assert.Equal(t, int64(400), summary.Savings.GetMinorUnits())
+		assert.Equal(t, int64(3600), summary.Total.GetMinorUnits())
+		assert.Empty(t, summary.Warnings)
+
+		// An ended discount falls back to the base price, with a warning
+		_, err = db.Exec(context.Background(), "UPDATE discounts SET is_active = false WHERE id = $1", discountID)
+		assert.NoError(t, err)
+		defer db.Exec(context.Background(), "UPDATE discounts SET is_active = true WHERE id = $1", discountID)
+
+		summary, err = basketRepo.GetBasketSummary(context.Background(), &order_service.GetBasketSummaryRequest{Id: basket.Id})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3800), summary.Total.GetMinorUnits())
+		assert.Equal(t, []string{
+			"Product 1: discount is not active",
+			"Product 1: price changed from 9.00 to 10.00 USD",
+		}, summary.Warnings)
+	})
+
 	t.Run("GetBasketItem", func(t *testing.T) {
 		// Create a basket first
 		createdBasket, err := basketRepo.CreateBasket(context.Background(), &order_service.CreateBasketRequest{
@@ -239,8 +356,8 @@ func TestOrderRepo(t *testing.T) {
 				FlashSaleEventProductId: "", // Not a flash sale item
 				DiscountProductId:       "", // Not a discount item
 				Quantity:                2,
-				UnitPrice:               10.0,
-				TotalPrice:              20.0,
+				UnitPrice:               usd(1000),
+				TotalPrice:              usd(2000),
 				ProductType:             "REGULAR",
 			},
 		})
@@ -276,8 +393,8 @@ func TestOrderRepo(t *testing.T) {
 				FlashSaleEventProductId: "", // Not a flash sale item
 				DiscountProductId:       "", // Not a discount item
 				Quantity:                2,
-				UnitPrice:               10.0,
-				TotalPrice:              20.0,
+				UnitPrice:               usd(1000),
+				TotalPrice:              usd(2000),
 				ProductType:             "REGULAR",
 			},
 		})
@@ -314,8 +431,8 @@ func TestOrderRepo(t *testing.T) {
 				FlashSaleEventProductId: "", // Not a flash sale item
 				DiscountProductId:       "", // Not a discount item
 				Quantity:                2,
-				UnitPrice:               10.0,
-				TotalPrice:              20.0,
+				UnitPrice:               usd(1000),
+				TotalPrice:              usd(2000),
 				ProductType:             "REGULAR",
 			},
 			{
@@ -324,8 +441,8 @@ func TestOrderRepo(t *testing.T) {
 				FlashSaleEventProductId: flashSaleEventProductID,
 				DiscountProductId:       "",
 				Quantity:                1,
-				UnitPrice:               18.0,
-				TotalPrice:              18.0,
+				UnitPrice:               usd(1800),
+				TotalPrice:              usd(1800),
 				ProductType:             "FLASH_SALE",
 			},
 		}
@@ -349,6 +466,293 @@ func TestOrderRepo(t *testing.T) {
 		defer deleteBasket(t, db, createdBasket.Id)
 	})
 
+	t.Run("UpdateBasketItemQuantity", func(t *testing.T) {
+		createdBasket, err := basketRepo.CreateBasket(context.Background(), &order_service.CreateBasketRequest{
+			Basket: &order_service.Basket{
+				UserId: userID,
+				Status: "OPEN",
+			},
+		})
+		assert.NoError(t, err)
+		defer deleteBasket(t, db, createdBasket.Id)
+
+		createdBasketItem, err := basketItemRepo.CreateBasketItem(context.Background(), &order_service.CreateBasketItemRequest{
+			BasketItem: &order_service.BasketItem{
+				BasketId:    createdBasket.Id,
+				ProductId:   product1ID,
+				Quantity:    2,
+				ProductType: "REGULAR",
+			},
+		})
+		assert.NoError(t, err)
+		defer deleteBasketItem(t, db, createdBasketItem.Id)
+
+		// The item is priced again for the new quantity
+		updatedBasketItem, err := basketItemRepo.UpdateBasketItemQuantity(context.Background(), &order_service.UpdateBasketItemQuantityRequest{
+			Id:       createdBasketItem.Id,
+			Quantity: 3,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int32(3), updatedBasketItem.Quantity)
+		assert.Equal(t, createdBasketItem.UnitPrice.GetMinorUnits(), updatedBasketItem.UnitPrice.GetMinorUnits())
+		assert.Equal(t, 3*createdBasketItem.UnitPrice.GetMinorUnits(), updatedBasketItem.TotalPrice.GetMinorUnits())
+
+		_, err = basketItemRepo.UpdateBasketItemQuantity(context.Background(), &order_service.UpdateBasketItemQuantityRequest{
+			Id:       createdBasketItem.Id,
+			Quantity: 0,
+		})
+		assert.ErrorIs(t, err, storage.ErrInvalidQuantity)
+
+		_, err = basketItemRepo.UpdateBasketItemQuantity(context.Background(), &order_service.UpdateBasketItemQuantityRequest{
+			Id:       uuid.NewString(),
+			Quantity: 1,
+		})
+		assert.ErrorIs(t, err, pgx.ErrNoRows)
+	})
+
+	t.Run("UpdateBasketItemQuantityWithStockHold", func(t *testing.T) {
+		productID := uuid.NewString()
+		createProduct(t, db, productID, "Held Product", 20.0)
+		defer deleteProduct(t, db, productID)
+		fsepID := uuid.NewString()
+		createFlashSaleEventProduct(t, db, fsepID, flashSaleEventID, productID, 20.0, 18.0)
+		defer deleteFlashSaleEventProduct(t, db, fsepID)
+
+		stockCache := newMemoryStockCache()
+		stockCache.set(fsepID, 10)
+		heldBasketItemRepo := postgres.NewBasketItemRepo(db, stockCache, postgres.FlashSaleRules{HoldTTL: time.Hour}, exchangeRates, pricingEngine)
+
+		basketID := uuid.NewString()
+		createBasket(t, db, basketID, userID, "OPEN")
+		defer deleteBasket(t, db, basketID)
+
+		item, err := heldBasketItemRepo.CreateBasketItem(context.Background(), &order_service.CreateBasketItemRequest{
+			BasketItem: &order_service.BasketItem{
+				BasketId:                basketID,
+				ProductId:               productID,
+				FlashSaleEventProductId: fsepID,
+				Quantity:                2,
+				ProductType:             "FLASH_SALE",
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int32(8), stockCache.get(fsepID))
+		assert.Equal(t, int32(8), flashSaleAvailable(t, db, fsepID))
+
+		// Only the units added are taken, the held ones are kept
+		_, err = heldBasketItemRepo.UpdateBasketItemQuantity(context.Background(), &order_service.UpdateBasketItemQuantityRequest{Id: item.Id, Quantity: 5})
+		assert.NoError(t, err)
+		assert.Equal(t, int32(5), stockCache.get(fsepID))
+		assert.Equal(t, int32(5), flashSaleAvailable(t, db, fsepID))
+
+		// Growing past what is left is refused, even though the held units would cover part of it
+		_, err = heldBasketItemRepo.UpdateBasketItemQuantity(context.Background(), &order_service.UpdateBasketItemQuantityRequest{Id: item.Id, Quantity: 11})
+		assert.ErrorIs(t, err, storage.ErrSoldOut)
+		assert.Equal(t, int32(5), stockCache.get(fsepID))
+
+		// Shrinking gives the units no longer held back
+		_, err = heldBasketItemRepo.UpdateBasketItemQuantity(context.Background(), &order_service.UpdateBasketItemQuantityRequest{Id: item.Id, Quantity: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, int32(9), stockCache.get(fsepID))
+		assert.Equal(t, int32(9), flashSaleAvailable(t, db, fsepID))
+
+		_, err = heldBasketItemRepo.DeleteBasketItem(context.Background(), &order_service.DeleteBasketItemRequest{Id: item.Id})
+		assert.NoError(t, err)
+		assert.Equal(t, int32(10), stockCache.get(fsepID))
+		assert.Equal(t, int32(10), flashSaleAvailable(t, db, fsepID))
+	})
+
+	t.Run("StockHolds", func(t *testing.T) {
+		productID := uuid.NewString()
+		createProduct(t, db, productID, "Held Product", 20.0)
+		defer deleteProduct(t, db, productID)
+		fsepID := uuid.NewString()
+		createFlashSaleEventProduct(t, db, fsepID, flashSaleEventID, productID, 20.0, 18.0)
+		defer deleteFlashSaleEventProduct(t, db, fsepID)
+
+		heldBasketItemRepo := postgres.NewBasketItemRepo(db, nil, postgres.FlashSaleRules{HoldTTL: time.Hour}, exchangeRates, pricingEngine)
+		flashSaleRepo := postgres.NewFlashSaleRepo(db, nil)
+
+		basketID := uuid.NewString()
+		createBasket(t, db, basketID, userID, "OPEN")
+		defer deleteBasket(t, db, basketID)
+
+		addItem := func(quantity int32) string {
+			item, err := heldBasketItemRepo.CreateBasketItem(context.Background(), &order_service.CreateBasketItemRequest{
+				BasketItem: &order_service.BasketItem{
+					BasketId:                basketID,
+					ProductId:               productID,
+					FlashSaleEventProductId: fsepID,
+					Quantity:                quantity,
+					ProductType:             "FLASH_SALE",
+				},
+			})
+			assert.NoError(t, err)
+			return item.GetId()
+		}
+
+		// Adding the item holds its units until the TTL passes
+		itemID := addItem(3)
+		status, quantity, expiresAt := stockHold(t, db, itemID)
+		assert.Equal(t, "ACTIVE", status)
+		assert.Equal(t, int32(3), quantity)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), expiresAt, time.Minute)
+		assert.Equal(t, int32(7), flashSaleAvailable(t, db, fsepID))
+
+		// Changing the quantity does not push the expiry back
+		_, err := db.Exec(context.Background(), "UPDATE stock_holds SET expires_at = NOW() + INTERVAL '10 minutes' WHERE basket_item_id = $1 AND status = 'ACTIVE'", itemID)
+		assert.NoError(t, err)
+		_, err = heldBasketItemRepo.UpdateBasketItemQuantity(context.Background(), &order_service.UpdateBasketItemQuantityRequest{Id: itemID, Quantity: 4})
+		assert.NoError(t, err)
+		status, quantity, expiresAt = stockHold(t, db, itemID)
+		assert.Equal(t, "ACTIVE", status)
+		assert.Equal(t, int32(4), quantity)
+		assert.WithinDuration(t, time.Now().Add(10*time.Minute), expiresAt, time.Minute)
+		assert.Equal(t, int32(6), flashSaleAvailable(t, db, fsepID))
+
+		// Deleting the item gives them back
+		_, err = heldBasketItemRepo.DeleteBasketItem(context.Background(), &order_service.DeleteBasketItemRequest{Id: itemID})
+		assert.NoError(t, err)
+		status, _, _ = stockHold(t, db, itemID)
+		assert.Equal(t, "RELEASED", status)
+		assert.Equal(t, int32(10), flashSaleAvailable(t, db, fsepID))
+
+		// So does a hold that runs out, once it is swept
+		itemID = addItem(2)
+		assert.Equal(t, int32(8), flashSaleAvailable(t, db, fsepID))
+		_, err = db.Exec(context.Background(), "UPDATE stock_holds SET expires_at = NOW() - INTERVAL '1 minute' WHERE basket_item_id = $1", itemID)
+		assert.NoError(t, err)
+
+		released, err := flashSaleRepo.ReleaseExpiredStockHolds(context.Background())
+		assert.NoError(t, err)
+		assert.GreaterOrEqual(t, released, 1)
+		status, _, _ = stockHold(t, db, itemID)
+		assert.Equal(t, "RELEASED", status)
+		assert.Equal(t, int32(10), flashSaleAvailable(t, db, fsepID))
+
+		_, err = heldBasketItemRepo.DeleteBasketItem(context.Background(), &order_service.DeleteBasketItemRequest{Id: itemID})
+		assert.NoError(t, err)
+		assert.Equal(t, int32(10), flashSaleAvailable(t, db, fsepID)) // Not given back twice
+
+		// Deleting the basket gives back what its items hold
+		itemID = addItem(2)
+		assert.Equal(t, int32(8), flashSaleAvailable(t, db, fsepID))
+		_, err = basketRepo.DeleteBasket(context.Background(), &order_service.DeleteBasketRequest{Id: basketID})
+		assert.NoError(t, err)
+		status, _, _ = stockHold(t, db, itemID)
+		assert.Equal(t, "RELEASED", status)
+		assert.Equal(t, int32(10), flashSaleAvailable(t, db, fsepID))
+	})
+
+	t.Run("PurchaseLimits", func(t *testing.T) {
+		// The event caps each user at 3 units, and one of its products at 1, whatever the configuration says
+		eventID := uuid.NewString()
+		createFlashSaleEvent(t, db, eventID, "Limited Flash Sale", time.Now().Add(-time.Hour), time.Now().Add(24*time.Hour), "ACTIVE")
+		defer deleteFlashSaleEvent(t, db, eventID)
+		_, err := db.Exec(context.Background(), "UPDATE flash_sale_events SET max_per_user = 3 WHERE id = $1", eventID)
+		assert.NoError(t, err)
+
+		productID := uuid.NewString()
+		createProduct(t, db, productID, "Limited Product", 20.0)
+		defer deleteProduct(t, db, productID)
+		fsepID := uuid.NewString()
+		createFlashSaleEventProduct(t, db, fsepID, eventID, productID, 20.0, 18.0)
+		defer deleteFlashSaleEventProduct(t, db, fsepID)
+
+		singleProductID := uuid.NewString()
+		createProduct(t, db, singleProductID, "Single Product", 20.0)
+		defer deleteProduct(t, db, singleProductID)
+		singleID := uuid.NewString()
+		createFlashSaleEventProduct(t, db, singleID, eventID, singleProductID, 20.0, 18.0)
+		defer deleteFlashSaleEventProduct(t, db, singleID)
+		_, err = db.Exec(context.Background(), "UPDATE flash_sale_event_products SET max_per_user = 1 WHERE id = $1", singleID)
+		assert.NoError(t, err)
+
+		limitedBasketItemRepo := postgres.NewBasketItemRepo(db, nil, postgres.FlashSaleRules{MaxPerUserPerProduct: 10, MaxPerUserPerEvent: 10}, exchangeRates, pricingEngine)
+		limitedOrderItemRepo := postgres.NewOrderItemRepo(db, nil, postgres.FlashSaleRules{MaxPerUserPerProduct: 10, MaxPerUserPerEvent: 10}, exchangeRates, pricingEngine, quoteSigner, taxTable, nil, time.Hour)
+
+		limitedUserID := uuid.NewString()
+		createUser(t, db, limitedUserID)
+		defer deleteUser(t, db, limitedUserID)
+
+		newBasket := func() string {
+			basketID := uuid.NewString()
+			createBasket(t, db, basketID, limitedUserID, "OPEN")
+			return basketID
+		}
+		addItem := func(basketID, productID, fsepID string, quantity int32) error {
+			_, err := limitedBasketItemRepo.CreateBasketItem(context.Background(), &order_service.CreateBasketItemRequest{
+				BasketItem: &order_service.BasketItem{
+					BasketId:                basketID,
+					ProductId:               productID,
+					FlashSaleEventProductId: fsepID,
+					Quantity:                quantity,
+					ProductType:             "FLASH_SALE",
+				},
+			})
+			return err
+		}
+
+		basketA := newBasket()
+		assert.NoError(t, addItem(basketA, productID, fsepID, 2))
+
+		// Units in another open basket count too
+		basketB := newBasket()
+		assert.ErrorIs(t, addItem(basketB, productID, fsepID, 2), storage.ErrPurchaseLimitExceeded)
+		assert.NoError(t, addItem(basketB, productID, fsepID, 1))
+		assert.ErrorIs(t, addItem(newBasket(), singleProductID, singleID, 2), storage.ErrPurchaseLimitExceeded)
+
+		// So do units that were ordered
+		orderID := uuid.NewString()
+		createOrder(t, db, orderID, limitedUserID, 0, 0, 0, "PENDING")
+		defer deleteOrder(t, db, orderID)
+		_, err = limitedOrderItemRepo.ConvertBasketToOrderItems(context.Background(), &order_service.ConvertBasketToOrderItemsRequest{
+			BasketId: basketA,
+			OrderId:  orderID,
+		})
+		assert.NoError(t, err)
+		assert.ErrorIs(t, addItem(newBasket(), productID, fsepID, 1), storage.ErrPurchaseLimitExceeded)
+
+		// But not once they are cancelled
+		_, err = orderRepo.CancelOrder(context.Background(), &order_service.CancelOrderRequest{Id: orderID, Reason: "changed my mind"})
+		assert.NoError(t, err)
+		assert.NoError(t, addItem(newBasket(), productID, fsepID, 2))
+	})
+
+	t.Run("ClearBasketItems", func(t *testing.T) {
+		createdBasket, err := basketRepo.CreateBasket(context.Background(), &order_service.CreateBasketRequest{
+			Basket: &order_service.Basket{
+				UserId: userID,
+				Status: "OPEN",
+			},
+		})
+		assert.NoError(t, err)
+		defer deleteBasket(t, db, createdBasket.Id)
+
+		for _, productID := range []string{product1ID, product2ID} {
+			createdBasketItem, err := basketItemRepo.CreateBasketItem(context.Background(), &order_service.CreateBasketItemRequest{
+				BasketItem: &order_service.BasketItem{
+					BasketId:    createdBasket.Id,
+					ProductId:   productID,
+					Quantity:    1,
+					ProductType: "REGULAR",
+				},
+			})
+			assert.NoError(t, err)
+			defer deleteBasketItem(t, db, createdBasketItem.Id)
+		}
+
+		cleared, err := basketItemRepo.ClearBasketItems(context.Background(), &order_service.ClearBasketItemsRequest{BasketId: createdBasket.Id})
+		assert.NoError(t, err)
+		assert.Equal(t, int32(2), cleared.Deleted)
+
+		basketItems, err := basketItemRepo.ListBasketItems(context.Background(), &order_service.ListBasketItemsRequest{
+			BasketId: createdBasket.Id,
+		})
+		assert.NoError(t, err)
+		assert.Empty(t, basketItems.BasketItems)
+	})
+
 	// --- Order Tests ---
 
 	t.Run("CreateOrder", func(t *testing.T) {
@@ -357,7 +761,7 @@ func TestOrderRepo(t *testing.T) {
 				ClientId:          userID,
 				DeliveryLatitude:  37.7749,
 				DeliveryLongitude: -122.4194,
-				TotalPrice:        0.0, // Will be updated later
+				TotalPrice:        usd(0), // Will be updated later
 				Status:            "PENDING",
 			},
 		})
@@ -374,7 +778,7 @@ func TestOrderRepo(t *testing.T) {
 				ClientId:          userID,
 				DeliveryLatitude:  37.7749,
 				DeliveryLongitude: -122.4194,
-				TotalPrice:        0.0, // Will be updated later
+				TotalPrice:        usd(0), // Will be updated later
 				Status:            "PENDING",
 			},
 		})
@@ -396,7 +800,7 @@ func TestOrderRepo(t *testing.T) {
 				ClientId:          userID,
 				DeliveryLatitude:  37.7749,
 				DeliveryLongitude: -122.4194,
-				TotalPrice:        0.0, // Will be updated later
+				TotalPrice:        usd(0), // Will be updated later
 				Status:            "PENDING",
 			},
 		})
@@ -415,6 +819,45 @@ func TestOrderRepo(t *testing.T) {
 		defer deleteOrder(t, db, createdOrder.Id)
 	})
 
+	t.Run("UpdateOrderDeliveryPoint", func(t *testing.T) {
+		basketID := uuid.NewString()
+		createBasket(t, db, basketID, userID, "OPEN")
+		defer deleteBasket(t, db, basketID)
+
+		basketItemID := uuid.NewString()
+		createBasketItemRegular(t, db, basketItemID, basketID, product1ID, 2, 1000, 2000)
+		defer deleteBasketItem(t, db, basketItemID)
+
+		orderID := uuid.NewString()
+		createOrder(t, db, orderID, userID, 0, 0, 0, "PENDING")
+		defer deleteOrder(t, db, orderID)
+
+		_, err := orderItemRepo.ConvertBasketToOrderItems(context.Background(), &order_service.ConvertBasketToOrderItemsRequest{
+			BasketId: basketID,
+			OrderId:  orderID,
+		})
+		assert.NoError(t, err)
+
+		order, err := orderRepo.GetOrder(context.Background(), &order_service.GetOrderRequest{Id: orderID})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2000), order.TotalPrice.GetMinorUnits())
+
+		// Moved into the taxed region; the total sent along is not taken
+		order.DeliveryLatitude = 60.5
+		order.DeliveryLongitude = 60.5
+		order.TotalPrice = usd(1)
+		updatedOrder, err := orderRepo.UpdateOrder(context.Background(), &order_service.UpdateOrderRequest{Order: order})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(200), updatedOrder.TaxTotal.GetMinorUnits())
+		assert.Equal(t, int64(2200), updatedOrder.TotalPrice.GetMinorUnits())
+
+		// Left where it is, the totals stay as they are
+		updatedOrder.TotalPrice = usd(1)
+		updatedOrder, err = orderRepo.UpdateOrder(context.Background(), &order_service.UpdateOrderRequest{Order: updatedOrder})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2200), updatedOrder.TotalPrice.GetMinorUnits())
+	})
+
 	t.Run("DeleteOrder", func(t *testing.T) {
 		// Create an order first
 		createdOrder, err := orderRepo.CreateOrder(context.Background(), &order_service.CreateOrderRequest{
@@ -422,7 +865,7 @@ func TestOrderRepo(t *testing.T) {
 				ClientId:          userID,
 				DeliveryLatitude:  37.7749,
 				DeliveryLongitude: -122.4194,
-				TotalPrice:        0.0, // Will be updated later
+				TotalPrice:        usd(0), // Will be updated later
 				Status:            "PENDING",
 			},
 		})
@@ -445,15 +888,15 @@ func TestOrderRepo(t *testing.T) {
 				ClientId:          userID,
 				DeliveryLatitude:  37.7749,
 				DeliveryLongitude: -122.4194,
-				TotalPrice:        10.0,
+				TotalPrice:        usd(1000),
 				Status:            "PENDING",
 			},
 			{
 				ClientId:          userID,
 				DeliveryLatitude:  34.0522,
 				DeliveryLongitude: -118.2437,
-				TotalPrice:        20.0,
-				Status:            "PROCESSING",
+				TotalPrice:        usd(2000),
+				Status:            "PENDING",
 			},
 		}
 
@@ -463,6 +906,15 @@ func TestOrderRepo(t *testing.T) {
 			defer deleteOrder(t, db, order.Id)
 		}
 
+		// Orders cannot skip the lifecycle by being created in a later status
+		_, err := orderRepo.CreateOrder(context.Background(), &order_service.CreateOrderRequest{Order: &order_service.Order{
+			ClientId:          userID,
+			DeliveryLatitude:  34.0522,
+			DeliveryLongitude: -118.2437,
+			Status:            "DELIVERED",
+		}})
+		assert.ErrorIs(t, err, orderstatus.ErrInvalidInitialStatus)
+
 		// Test ListOrders with client ID and status filters
 		orders, err := orderRepo.ListOrders(context.Background(), &order_service.ListOrdersRequest{
 			ClientId: userID,
@@ -482,7 +934,7 @@ func TestOrderRepo(t *testing.T) {
 				ClientId:          userID,
 				DeliveryLatitude:  37.7749,
 				DeliveryLongitude: -122.4194,
-				TotalPrice:        0.0, // Will be updated later
+				TotalPrice:        usd(0), // Will be updated later
 				Status:            "PENDING",
 			},
 		})
@@ -492,74 +944,703 @@ func TestOrderRepo(t *testing.T) {
 		// Update the order status
 		updatedOrder, err := orderRepo.UpdateOrderStatus(context.Background(), &order_service.UpdateOrderStatusRequest{
 			Id:     createdOrder.Id,
-			Status: "SHIPPED",
+			Status: "PROCESSING",
 		})
 		assert.NoError(t, err)
 		assert.NotNil(t, updatedOrder)
-		assert.Equal(t, "SHIPPED", updatedOrder.Status)
+		assert.Equal(t, "PROCESSING", updatedOrder.Status)
+
+		// A PROCESSING order cannot go back to PENDING
+		_, err = orderRepo.UpdateOrderStatus(context.Background(), &order_service.UpdateOrderStatusRequest{
+			Id:     createdOrder.Id,
+			Status: "PENDING",
+		})
+		assert.ErrorIs(t, err, orderstatus.ErrInvalidTransition)
+
+		// Cancelling has to go through CancelOrder, so the stock is given back
+		_, err = orderRepo.UpdateOrderStatus(context.Background(), &order_service.UpdateOrderStatusRequest{
+			Id:     createdOrder.Id,
+			Status: "CANCELLED",
+		})
+		assert.ErrorIs(t, err, orderstatus.ErrInvalidTransition)
+		updatedOrder.Status = "CANCELLED"
+		_, err = orderRepo.UpdateOrder(context.Background(), &order_service.UpdateOrderRequest{Order: updatedOrder})
+		assert.ErrorIs(t, err, orderstatus.ErrInvalidTransition)
 
 		defer deleteOrder(t, db, createdOrder.Id)
 	})
 
-	// --- Order Item Tests ---
-
-	t.Run("ConvertBasketToOrderItems", func(t *testing.T) {
-		// Create a basket
-		basketID := uuid.NewString()
-		createBasket(t, db, basketID, userID, "OPEN")
-		defer deleteBasket(t, db, basketID)
+	t.Run("GetOrderHistory", func(t *testing.T) {
+		// Create an order first
+		createdOrder, err := orderRepo.CreateOrder(context.Background(), &order_service.CreateOrderRequest{
+			Order: &order_service.Order{
+				ClientId:          userID,
+				DeliveryLatitude:  37.7749,
+				DeliveryLongitude: -122.4194,
+				Status:            "PENDING",
+			},
+		})
+		assert.NoError(t, err)
+		assert.NotNil(t, createdOrder)
 
-		// Create basket items
-		basketItem1ID := uuid.NewString()
-		createBasketItemRegular(t, db, basketItem1ID, basketID, product1ID, 2, 10.0, 20.0)
-		defer deleteBasketItem(t, db, basketItem1ID)
+		_, err = orderRepo.UpdateOrderStatus(context.Background(), &order_service.UpdateOrderStatusRequest{
+			Id:     createdOrder.Id,
+			Status: "PROCESSING",
+			Actor:  "warehouse",
+			Reason: "picked",
+		})
+		assert.NoError(t, err)
 
-		basketItem2ID := uuid.NewString()
-		createBasketItemFlashSale(t, db, basketItem2ID, basketID, product2ID, flashSaleEventProductID, 1, 18.0, 18.0)
-		defer deleteBasketItem(t, db, basketItem2ID)
+		history, err := orderRepo.GetOrderHistory(context.Background(), &order_service.GetOrderHistoryRequest{OrderId: createdOrder.Id})
+		assert.NoError(t, err)
+		assert.Len(t, history.History, 2)
+		assert.Equal(t, "", history.History[0].FromStatus)
+		assert.Equal(t, "PENDING", history.History[0].ToStatus)
+		assert.Equal(t, "PENDING", history.History[1].FromStatus)
+		assert.Equal(t, "PROCESSING", history.History[1].ToStatus)
+		assert.Equal(t, "warehouse", history.History[1].Actor)
+		assert.Equal(t, "picked", history.History[1].Reason)
 
-		// Create an order
-		orderID := uuid.NewString()
-		createOrder(t, db, orderID, userID, 0, 0, 0, "PENDING")
-		defer deleteOrder(t, db, orderID)
+		defer deleteOrder(t, db, createdOrder.Id)
+	})
 
-		// Convert basket items to order items
-		returnedOrderID, err := orderItemRepo.ConvertBasketToOrderItems(context.Background(), &order_service.ConvertBasketToOrderItemsRequest{
-			BasketId: basketID,
-			OrderId:  orderID,
+	t.Run("CancelOrder", func(t *testing.T) {
+		createdOrder, err := orderRepo.CreateOrder(context.Background(), &order_service.CreateOrderRequest{
+			Order: &order_service.Order{
+				ClientId:          userID,
+				DeliveryLatitude:  37.7749,
+				DeliveryLongitude: -122.4194,
+				Status:            "PENDING",
+			},
 		})
 		assert.NoError(t, err)
-		assert.Equal(t, orderID, returnedOrderID.Id)
+		assert.NotNil(t, createdOrder)
+		defer deleteOrder(t, db, createdOrder.Id)
 
-		// Check if order items were created
-		orderItems, err := orderItemRepo.ListOrderItems(context.Background(), &order_service.ListOrderItemsRequest{
-			OrderId: orderID,
-			Page:    1,
-			Limit:   10,
+		cancelledOrder, err := orderRepo.CancelOrder(context.Background(), &order_service.CancelOrderRequest{
+			Id:     createdOrder.Id,
+			Reason: "changed my mind",
+			Actor:  userID,
 		})
 		assert.NoError(t, err)
-		assert.NotNil(t, orderItems)
-		assert.Equal(t, 2, len(orderItems.OrderItems))
+		assert.Equal(t, "CANCELLED", cancelledOrder.Status)
 
-		// Check if order total price is updated
-		order, err := orderRepo.GetOrder(context.Background(), &order_service.GetOrderRequest{Id: orderID})
-		assert.NoError(t, err)
-		assert.Equal(t, float32(38.0), order.TotalPrice) // 20.0 (regular) + 18.0 (flash sale)
+		// Cancelling twice is rejected
+		_, err = orderRepo.CancelOrder(context.Background(), &order_service.CancelOrderRequest{
+			Id:     createdOrder.Id,
+			Reason: "again",
+		})
+		assert.ErrorIs(t, err, orderstatus.ErrInvalidTransition)
 	})
-	t.Run("DeleteOrderItem", func(t *testing.T) {
-		// Create a basket
-		basketID := uuid.NewString()
-		createBasket(t, db, basketID, userID, "OPEN")
-		defer deleteBasket(t, db, basketID)
 
-		// Create a basket item
-		basketItem1ID := uuid.NewString()
-		createBasketItemRegular(t, db, basketItem1ID, basketID, product1ID, 2, 10.0, 20.0)
-		defer deleteBasketItem(t, db, basketItem1ID)
+	// --- Order Item Tests ---
+
+	t.Run("CreateOrderFromAddressBook", func(t *testing.T) {
+		addressRepo := postgres.NewAddressRepo(db)
+
+		_, err := orderRepo.CreateOrder(context.Background(), &order_service.CreateOrderRequest{
+			Order: &order_service.Order{ClientId: userID},
+		})
+		assert.ErrorIs(t, err, storage.ErrInvalidCoordinates) // 0,0 is no position at all
+
+		_, err = addressRepo.CreateAddress(context.Background(), &order_service.CreateAddressRequest{})
+		assert.ErrorIs(t, err, storage.ErrInvalidAddress)
+		_, err = addressRepo.UpdateAddress(context.Background(), &order_service.UpdateAddressRequest{})
+		assert.ErrorIs(t, err, storage.ErrInvalidAddress)
+
+		_, err = addressRepo.CreateAddress(context.Background(), &order_service.CreateAddressRequest{
+			Address: &order_service.Address{
+				ClientId: userID,
+				Address:  &order_service.DeliveryAddress{Street: "1 Main St", City: "Tashkent", RecipientPhone: "90 123 45 67"},
+				Latitude: 41.3111, Longitude: 69.2797,
+			},
+		})
+		assert.ErrorIs(t, err, storage.ErrInvalidAddress)
+
+		address, err := addressRepo.CreateAddress(context.Background(), &order_service.CreateAddressRequest{
+			Address: &order_service.Address{
+				ClientId: userID,
+				Label:    "Home",
+				Address:  &order_service.DeliveryAddress{Street: " 1 Main St ", City: "Tashkent", RecipientPhone: "+998901234567"},
+				Latitude: 41.3111, Longitude: 69.2797,
+			},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "1 Main St", address.Address.Street)
+
+		addresses, err := addressRepo.ListAddresses(context.Background(), &order_service.ListAddressesRequest{ClientId: userID})
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), addresses.Total)
+
+		order, err := orderRepo.CreateOrder(context.Background(), &order_service.CreateOrderRequest{
+			Order: &order_service.Order{ClientId: userID, AddressId: address.Id, Status: "PENDING"},
+		})
+		assert.NoError(t, err)
+		defer deleteOrder(t, db, order.Id)
+
+		order, err = orderRepo.GetOrder(context.Background(), &order_service.GetOrderRequest{Id: order.Id})
+		assert.NoError(t, err)
+		assert.Equal(t, 41.3111, order.DeliveryLatitude)
+		assert.Equal(t, 69.2797, order.DeliveryLongitude)
+		assert.Equal(t, address.Id, order.AddressId)
+		assert.Equal(t, "1 Main St", order.DeliveryAddress.GetStreet())
+
+		// The order keeps its copy once the saved address is gone
+		_, err = addressRepo.DeleteAddress(context.Background(), &order_service.DeleteAddressRequest{Id: address.Id})
+		assert.NoError(t, err)
+
+		order, err = orderRepo.GetOrder(context.Background(), &order_service.GetOrderRequest{Id: order.Id})
+		assert.NoError(t, err)
+		assert.Equal(t, "Tashkent", order.DeliveryAddress.GetCity())
+
+		_, err = orderRepo.CreateOrder(context.Background(), &order_service.CreateOrderRequest{
+			Order: &order_service.Order{ClientId: userID, AddressId: address.Id},
+		})
+		assert.ErrorIs(t, err, storage.ErrAddressNotFound)
+	})
+
+	t.Run("CreateOrderIdempotency", func(t *testing.T) {
+		key := uuid.NewString()
+		newRequest := func() *order_service.CreateOrderRequest {
+			return &order_service.CreateOrderRequest{
+				Order:          &order_service.Order{ClientId: userID, DeliveryLatitude: 41.3111, DeliveryLongitude: 69.2797},
+				IdempotencyKey: key,
+			}
+		}
+
+		first, err := orderRepo.CreateOrder(context.Background(), newRequest())
+		assert.NoError(t, err)
+		defer deleteOrder(t, db, first.Id)
+
+		retried, err := orderRepo.CreateOrder(context.Background(), newRequest())
+		assert.NoError(t, err)
+		assert.Equal(t, first.Id, retried.Id) // Not a second order with a new ID
+
+		// The same key with another request is refused
+		other := newRequest()
+		other.Order.DeliveryLatitude = 41.2
+		_, err = orderRepo.CreateOrder(context.Background(), other)
+		assert.ErrorIs(t, err, storage.ErrIdempotencyKeyReused)
+	})
+
+	t.Run("ConvertBasketToOrderItemsIdempotency", func(t *testing.T) {
+		basketID := uuid.NewString()
+		createBasket(t, db, basketID, userID, "OPEN")
+		defer deleteBasket(t, db, basketID)
+
+		basketItemID := uuid.NewString()
+		createBasketItemRegular(t, db, basketItemID, basketID, product1ID, 2, 1000, 2000)
+		defer deleteBasketItem(t, db, basketItemID)
+
+		orderID := uuid.NewString()
+		createOrder(t, db, orderID, userID, 41.3111, 69.2797, 0, "PENDING")
+		defer deleteOrder(t, db, orderID)
+
+		req := &order_service.ConvertBasketToOrderItemsRequest{
+			BasketId:       basketID,
+			OrderId:        orderID,
+			IdempotencyKey: uuid.NewString(),
+		}
+		_, err := orderItemRepo.ConvertBasketToOrderItems(context.Background(), req)
+		assert.NoError(t, err)
+
+		// A redelivery returns the first result instead of failing on the checked out basket
+		response, err := orderItemRepo.ConvertBasketToOrderItems(context.Background(), req)
+		assert.NoError(t, err)
+		assert.Equal(t, orderID, response.Id)
+
+		orderItems, err := orderItemRepo.ListOrderItems(context.Background(), &order_service.ListOrderItemsRequest{
+			OrderId: orderID,
+			Page:    1,
+			Limit:   10,
+		})
+		assert.NoError(t, err)
+		assert.Len(t, orderItems.OrderItems, 1)
+
+		// Without a key the basket cannot be checked out again
+		_, err = orderItemRepo.ConvertBasketToOrderItems(context.Background(), &order_service.ConvertBasketToOrderItemsRequest{
+			BasketId: basketID,
+			OrderId:  orderID,
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("OutboxEvents", func(t *testing.T) {
+		order, err := orderRepo.CreateOrder(context.Background(), &order_service.CreateOrderRequest{
+			Order: &order_service.Order{ClientId: userID, DeliveryLatitude: 41.3111, DeliveryLongitude: 69.2797},
+		})
+		assert.NoError(t, err)
+		defer deleteOrder(t, db, order.Id)
+
+		_, err = orderRepo.UpdateOrderStatus(context.Background(), &order_service.UpdateOrderStatusRequest{
+			Id:     order.Id,
+			Status: "PROCESSING",
+		})
+		assert.NoError(t, err)
+
+		rows, err := db.Query(context.Background(), `SELECT topic, payload FROM outbox WHERE aggregate_id = $1 ORDER BY id`, order.Id)
+		assert.NoError(t, err)
+		var topics []string
+		var payloads [][]byte
+		for rows.Next() {
+			var topic string
+			var payload []byte
+			assert.NoError(t, rows.Scan(&topic, &payload))
+			topics = append(topics, topic)
+			payloads = append(payloads, payload)
+		}
+		rows.Close()
+		assert.Equal(t, []string{outbox.TopicOrderCreated, outbox.TopicOrderStatusChanged}, topics)
+
+		var changed order_service.OrderStatusChanged
+		if assert.Len(t, payloads, 2) {
+			assert.NoError(t, protojson.Unmarshal(payloads[1], &changed))
+		}
+		assert.Equal(t, userID, changed.ClientId)
+		assert.Equal(t, "PENDING", changed.Change.GetFromStatus())
+		assert.Equal(t, "PROCESSING", changed.Change.GetToStatus())
+	})
+
+	t.Run("ConvertBasketToOrderItems", func(t *testing.T) {
+		// Create a basket
+		basketID := uuid.NewString()
+		createBasket(t, db, basketID, userID, "OPEN")
+		defer deleteBasket(t, db, basketID)
+
+		// Create basket items
+		basketItem1ID := uuid.NewString()
+		createBasketItemRegular(t, db, basketItem1ID, basketID, product1ID, 2, 1000, 2000)
+		defer deleteBasketItem(t, db, basketItem1ID)
+
+		basketItem2ID := uuid.NewString()
+		createBasketItemFlashSale(t, db, basketItem2ID, basketID, product2ID, flashSaleEventProductID, 1, 1800, 1800)
+		defer deleteBasketItem(t, db, basketItem2ID)
+
+		// Create an order
+		orderID := uuid.NewString()
+		createOrder(t, db, orderID, userID, 0, 0, 0, "PENDING")
+		defer deleteOrder(t, db, orderID)
+
+		// Convert basket items to order items
+		returnedOrderID, err := orderItemRepo.ConvertBasketToOrderItems(context.Background(), &order_service.ConvertBasketToOrderItemsRequest{
+			BasketId: basketID,
+			OrderId:  orderID,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, orderID, returnedOrderID.Id)
+
+		// Check if order items were created
+		orderItems, err := orderItemRepo.ListOrderItems(context.Background(), &order_service.ListOrderItemsRequest{
+			OrderId: orderID,
+			Page:    1,
+			Limit:   10,
+		})
+		assert.NoError(t, err)
+		assert.NotNil(t, orderItems)
+		assert.Equal(t, 2, len(orderItems.OrderItems))
+
+		// Check if order total price is updated
+		order, err := orderRepo.GetOrder(context.Background(), &order_service.GetOrderRequest{Id: orderID})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3800), order.TotalPrice.GetMinorUnits()) // 20.00 (regular) + 18.00 (flash sale)
+	})
+
+	t.Run("ConvertBasketToOrderItemsSoldOut", func(t *testing.T) {
+		productID := uuid.NewString()
+		createProduct(t, db, productID, "Scarce Product", 20.0)
+		defer deleteProduct(t, db, productID)
+		fsepID := uuid.NewString()
+		createFlashSaleEventProduct(t, db, fsepID, flashSaleEventID, productID, 20.0, 18.0)
+		defer deleteFlashSaleEventProduct(t, db, fsepID)
+
+		basketID := uuid.NewString()
+		createBasket(t, db, basketID, userID, "OPEN")
+		defer deleteBasket(t, db, basketID)
+
+		// One more unit than the flash sale has
+		basketItemID := uuid.NewString()
+		createBasketItemFlashSale(t, db, basketItemID, basketID, productID, fsepID, 11, 1800, 19800)
+		defer deleteBasketItem(t, db, basketItemID)
+
+		orderID := uuid.NewString()
+		createOrder(t, db, orderID, userID, 0, 0, 0, "PENDING")
+		defer deleteOrder(t, db, orderID)
+
+		_, err := orderItemRepo.ConvertBasketToOrderItems(context.Background(), &order_service.ConvertBasketToOrderItemsRequest{
+			BasketId: basketID,
+			OrderId:  orderID,
+		})
+		assert.ErrorIs(t, err, storage.ErrSoldOut)
+		assert.Equal(t, int32(10), flashSaleAvailable(t, db, fsepID))
+		assert.Equal(t, int32(100), productStock(t, db, productID))
+	})
+
+	t.Run("ConvertBasketToOrderItemsRollsBack", func(t *testing.T) {
+		productID := uuid.NewString()
+		createProduct(t, db, productID, "Rollback Product", 20.0)
+		defer deleteProduct(t, db, productID)
+		fsepID := uuid.NewString()
+		createFlashSaleEventProduct(t, db, fsepID, flashSaleEventID, productID, 20.0, 18.0)
+		defer deleteFlashSaleEventProduct(t, db, fsepID)
+
+		basketID := uuid.NewString()
+		createBasket(t, db, basketID, userID, "OPEN")
+		defer deleteBasket(t, db, basketID)
+
+		regularItemID := uuid.NewString()
+		createBasketItemRegular(t, db, regularItemID, basketID, productID, 2, 2000, 4000)
+		defer deleteBasketItem(t, db, regularItemID)
+		flashSaleItemID := uuid.NewString()
+		createBasketItemFlashSale(t, db, flashSaleItemID, basketID, productID, fsepID, 3, 1800, 5400)
+		defer deleteBasketItem(t, db, flashSaleItemID)
+
+		orderID := uuid.NewString()
+		createOrder(t, db, orderID, userID, 0, 0, 0, "PENDING")
+		defer deleteOrder(t, db, orderID)
+
+		// The coupon is redeemed after the items are created and their stock taken
+		_, err := orderItemRepo.ConvertBasketToOrderItems(context.Background(), &order_service.ConvertBasketToOrderItemsRequest{
+			BasketId:    basketID,
+			OrderId:     orderID,
+			CouponCodes: []string{"NO-SUCH-CODE"},
+		})
+		assert.ErrorIs(t, err, storage.ErrCouponNotFound)
+
+		assert.Equal(t, int32(100), productStock(t, db, productID))
+		assert.Equal(t, int32(10), flashSaleAvailable(t, db, fsepID))
+
+		orderItems, err := orderItemRepo.ListOrderItems(context.Background(), &order_service.ListOrderItemsRequest{
+			OrderId: orderID,
+			Page:    1,
+			Limit:   10,
+		})
+		assert.NoError(t, err)
+		assert.Empty(t, orderItems.OrderItems)
+
+		var status string
+		err = db.QueryRow(context.Background(), `SELECT status FROM baskets WHERE id = $1`, basketID).Scan(&status)
+		assert.NoError(t, err)
+		assert.Equal(t, "OPEN", status)
+
+		order, err := orderRepo.GetOrder(context.Background(), &order_service.GetOrderRequest{Id: orderID})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), order.TotalPrice.GetMinorUnits())
+	})
+
+	t.Run("ConvertBasketToOrderItemsRegularWithFlashSale", func(t *testing.T) {
+		productID := uuid.NewString()
+		createProduct(t, db, productID, "Mislabelled Product", 20.0)
+		defer deleteProduct(t, db, productID)
+		fsepID := uuid.NewString()
+		createFlashSaleEventProduct(t, db, fsepID, flashSaleEventID, productID, 20.0, 18.0)
+		defer deleteFlashSaleEventProduct(t, db, fsepID)
+
+		basketID := uuid.NewString()
+		createBasket(t, db, basketID, userID, "OPEN")
+		defer deleteBasket(t, db, basketID)
+
+		// A REGULAR item that refers to a flash sale is not sold at the flash sale price
+		basketItemID := uuid.NewString()
+		_, err := db.Exec(context.Background(), `
+			INSERT INTO basket_items (id, basket_id, product_id, flash_sale_event_product_id, quantity, unit_price, total_price, product_type, created_at, updated_at, deleted_at)
+			VALUES ($1, $2, $3, $4, 2, 1800, 3600, 'REGULAR', NOW(), NOW(), 0)
+		`, basketItemID, basketID, productID, fsepID)
+		assert.NoError(t, err)
+		defer deleteBasketItem(t, db, basketItemID)
+
+		orderID := uuid.NewString()
+		createOrder(t, db, orderID, userID, 0, 0, 0, "PENDING")
+		defer deleteOrder(t, db, orderID)
+
+		_, err = orderItemRepo.ConvertBasketToOrderItems(context.Background(), &order_service.ConvertBasketToOrderItemsRequest{
+			BasketId: basketID,
+			OrderId:  orderID,
+		})
+		assert.NoError(t, err)
+
+		orderItems, err := orderItemRepo.ListOrderItems(context.Background(), &order_service.ListOrderItemsRequest{
+			OrderId: orderID,
+			Page:    1,
+			Limit:   10,
+		})
+		assert.NoError(t, err)
+		assert.Len(t, orderItems.OrderItems, 1)
+		assert.Equal(t, int64(2000), orderItems.OrderItems[0].UnitPrice.GetMinorUnits())
+		assert.Equal(t, int64(0), orderItems.OrderItems[0].DiscountApplied.GetMinorUnits())
+		assert.Equal(t, int32(10), flashSaleAvailable(t, db, fsepID))
+		assert.Equal(t, int32(98), productStock(t, db, productID))
+	})
+
+	t.Run("ConvertBasketToOrderItemsInAnotherCurrency", func(t *testing.T) {
+		basket, err := basketRepo.CreateBasket(context.Background(), &order_service.CreateBasketRequest{
+			Basket: &order_service.Basket{UserId: userID, Status: "OPEN", CurrencyCode: "EUR"},
+		})
+		assert.NoError(t, err)
+		defer deleteBasket(t, db, basket.Id)
+
+		basketItemID := uuid.NewString()
+		createBasketItemRegular(t, db, basketItemID, basket.Id, product1ID, 2, 920, 1840)
+		defer deleteBasketItem(t, db, basketItemID)
+
+		order, err := orderRepo.CreateOrder(context.Background(), &order_service.CreateOrderRequest{
+			Order: &order_service.Order{ClientId: userID, DeliveryLatitude: 48.8566, DeliveryLongitude: 2.3522, Status: "PENDING", CurrencyCode: "EUR"},
+		})
+		assert.NoError(t, err)
+		defer deleteOrder(t, db, order.Id)
+		assert.Equal(t, "", order.ExchangeRate) // Not fixed until checkout
+
+		_, err = orderItemRepo.ConvertBasketToOrderItems(context.Background(), &order_service.ConvertBasketToOrderItemsRequest{
+			BasketId: basket.Id,
+			OrderId:  order.Id,
+		})
+		assert.NoError(t, err)
+
+		order, err = orderRepo.GetOrder(context.Background(), &order_service.GetOrderRequest{Id: order.Id})
+		assert.NoError(t, err)
+		assert.Equal(t, "EUR", order.CurrencyCode)
+		assert.Equal(t, "0.92000000", order.ExchangeRate)
+		assert.Equal(t, int64(1840), order.TotalPrice.GetMinorUnits()) // 2 x 10.00 USD at 0.92
+		assert.Equal(t, "EUR", order.TotalPrice.GetCurrencyCode())
+	})
+	t.Run("ConvertBasketToOrderItemsWithCoupons", func(t *testing.T) {
+		percentOff, err := couponRepo.CreateCoupon(context.Background(), &order_service.CreateCouponRequest{
+			Coupon: &order_service.Coupon{
+				Code:           "ten-" + uuid.NewString()[:8],
+				Effect:         "PERCENTAGE",
+				Percentage:     "10",
+				MaxUsesPerUser: 1,
+				StartsAt:       timestamppb.New(time.Now().Add(-time.Hour)),
+				EndsAt:         timestamppb.New(time.Now().Add(time.Hour)),
+				IsActive:       true,
+			},
+		})
+		assert.NoError(t, err)
+		defer couponRepo.DeleteCoupon(context.Background(), &order_service.DeleteCouponRequest{Id: percentOff.Id})
+
+		amountOff, err := couponRepo.CreateCoupon(context.Background(), &order_service.CreateCouponRequest{
+			Coupon: &order_service.Coupon{
+				Code:           "FIVE-" + uuid.NewString()[:8],
+				Effect:         "FIXED_AMOUNT",
+				Amount:         usd(500),
+				MinBasketValue: usd(1500),
+				StartsAt:       timestamppb.New(time.Now().Add(-time.Hour)),
+				EndsAt:         timestamppb.New(time.Now().Add(time.Hour)),
+				IsActive:       true,
+			},
+		})
+		assert.NoError(t, err)
+		defer couponRepo.DeleteCoupon(context.Background(), &order_service.DeleteCouponRequest{Id: amountOff.Id})
+
+		checkout := func(codes ...string) (string, error) {
+			basketID := uuid.NewString()
+			createBasket(t, db, basketID, userID, "OPEN")
+			basketItemID := uuid.NewString()
+			createBasketItemRegular(t, db, basketItemID, basketID, product1ID, 2, 1000, 2000)
+			t.Cleanup(func() {
+				deleteBasketItem(t, db, basketItemID)
+				deleteBasket(t, db, basketID)
+			})
+			orderID := uuid.NewString()
+			createOrder(t, db, orderID, userID, 0, 0, 0, "PENDING")
+
+			_, err := orderItemRepo.ConvertBasketToOrderItems(context.Background(), &order_service.ConvertBasketToOrderItemsRequest{
+				BasketId:    basketID,
+				OrderId:     orderID,
+				CouponCodes: codes,
+			})
+			return orderID, err
+		}
+
+		// Codes are matched regardless of case
+		orderID, err := checkout(strings.ToLower(percentOff.Code), amountOff.Code)
+		assert.NoError(t, err)
+
+		order, err := orderRepo.GetOrder(context.Background(), &order_service.GetOrderRequest{Id: orderID})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(700), order.DiscountTotal.GetMinorUnits()) // 10% of 20.00, then 5.00
+		assert.Equal(t, int64(1300), order.TotalPrice.GetMinorUnits())
+		assert.Len(t, order.AppliedPromotions, 2)
+
+		coupon, err := couponRepo.GetCoupon(context.Background(), &order_service.GetCouponRequest{Id: percentOff.Id})
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), coupon.TimesUsed)
+
+		// The percentage coupon may be used once per user
+		_, err = checkout(percentOff.Code)
+		assert.ErrorIs(t, err, storage.ErrCouponNotApplicable)
+
+		_, err = checkout("NO-SUCH-CODE")
+		assert.ErrorIs(t, err, storage.ErrCouponNotFound)
+
+		// Cancelling the order gives the use back
+		_, err = orderRepo.CancelOrder(context.Background(), &order_service.CancelOrderRequest{Id: orderID, Reason: "changed my mind"})
+		assert.NoError(t, err)
+		coupon, err = couponRepo.GetCoupon(context.Background(), &order_service.GetCouponRequest{Id: percentOff.Id})
+		assert.NoError(t, err)
+		assert.Equal(t, int32(0), coupon.TimesUsed)
+	})
+
+	t.Run("ConvertBasketToOrderItemsWithTax", func(t *testing.T) {
+		basketID := uuid.NewString()
+		createBasket(t, db, basketID, userID, "OPEN")
+		defer deleteBasket(t, db, basketID)
+
+		basketItemID := uuid.NewString()
+		createBasketItemRegular(t, db, basketItemID, basketID, product1ID, 2, 1000, 2000)
+		defer deleteBasketItem(t, db, basketItemID)
+
+		// Delivered inside the taxed region
+		orderID := uuid.NewString()
+		createOrder(t, db, orderID, userID, 60.5, 60.5, 0, "PENDING")
+		defer deleteOrder(t, db, orderID)
+
+		_, err := orderItemRepo.ConvertBasketToOrderItems(context.Background(), &order_service.ConvertBasketToOrderItemsRequest{
+			BasketId: basketID,
+			OrderId:  orderID,
+		})
+		assert.NoError(t, err)
+
+		order, err := orderRepo.GetOrder(context.Background(), &order_service.GetOrderRequest{Id: orderID})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(200), order.TaxTotal.GetMinorUnits())
+		assert.False(t, order.TaxInclusive)
+		assert.Equal(t, int64(2200), order.TotalPrice.GetMinorUnits()) // 20.00 plus 10% tax
+
+		orderItems, err := orderItemRepo.ListOrderItems(context.Background(), &order_service.ListOrderItemsRequest{
+			OrderId: orderID,
+			Page:    1,
+			Limit:   10,
+		})
+		assert.NoError(t, err)
+		assert.Len(t, orderItems.OrderItems, 1)
+		assert.Equal(t, int64(200), orderItems.OrderItems[0].TaxAmount.GetMinorUnits())
+	})
+
+	t.Run("ConvertBasketToOrderItemsWithShipping", func(t *testing.T) {
+		zones := shipping.NewZones([]shipping.Zone{{
+			Name: "zone",
+			Polygons: []shipping.Polygon{{{
+				{Latitude: 50, Longitude: 50}, {Latitude: 50, Longitude: 51}, {Latitude: 51, Longitude: 51},
+				{Latitude: 51, Longitude: 50}, {Latitude: 50, Longitude: 50},
+			}}},
+			Fee: shipping.Schedule{Type: shipping.Flat, Amount: 500},
+		}})
+		zonedOrderRepo := postgres.NewOrderRepo(db, nil, nil, zones, time.Hour)
+		zonedOrderItemRepo := postgres.NewOrderItemRepo(db, nil, postgres.FlashSaleRules{}, exchangeRates, pricingEngine, quoteSigner, nil, zones, time.Hour)
+
+		_, err := zonedOrderRepo.CreateOrder(context.Background(), &order_service.CreateOrderRequest{
+			Order: &order_service.Order{ClientId: userID, DeliveryLatitude: 10, DeliveryLongitude: 10},
+		})
+		assert.ErrorIs(t, err, storage.ErrOutsideDeliveryZone)
+
+		order, err := zonedOrderRepo.CreateOrder(context.Background(), &order_service.CreateOrderRequest{
+			Order: &order_service.Order{ClientId: userID, DeliveryLatitude: 50.5, DeliveryLongitude: 50.5},
+		})
+		assert.NoError(t, err)
+		defer deleteOrder(t, db, order.Id)
+		assert.Equal(t, "zone", order.ShippingZone)
+
+		basketID := uuid.NewString()
+		createBasket(t, db, basketID, userID, "OPEN")
+		defer deleteBasket(t, db, basketID)
+		basketItemID := uuid.NewString()
+		createBasketItemRegular(t, db, basketItemID, basketID, product1ID, 2, 1000, 2000)
+		defer deleteBasketItem(t, db, basketItemID)
+
+		_, err = zonedOrderItemRepo.ConvertBasketToOrderItems(context.Background(), &order_service.ConvertBasketToOrderItemsRequest{
+			BasketId: basketID,
+			OrderId:  order.Id,
+		})
+		assert.NoError(t, err)
+
+		order, err = zonedOrderRepo.GetOrder(context.Background(), &order_service.GetOrderRequest{Id: order.Id})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(500), order.ShippingFee.GetMinorUnits())
+		assert.Equal(t, int64(2500), order.TotalPrice.GetMinorUnits()) // 20.00 plus 5.00 shipping
+	})
+
+	t.Run("ConvertBasketToOrderItemsWithQuote", func(t *testing.T) {
+		basketID := uuid.NewString()
+		createBasket(t, db, basketID, userID, "OPEN")
+		defer deleteBasket(t, db, basketID)
+
+		basketItemID := uuid.NewString()
+		createBasketItemRegular(t, db, basketItemID, basketID, product1ID, 2, 1000, 2000)
+		defer deleteBasketItem(t, db, basketItemID)
+
+		q, err := basketRepo.QuoteBasket(context.Background(), &order_service.QuoteBasketRequest{Id: basketID})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2000), q.TotalPrice.GetMinorUnits())
+		assert.Len(t, q.Lines, 1)
+		assert.NotEmpty(t, q.Signature)
+
+		// The product gets dearer after the quote was issued
+		_, err = db.Exec(context.Background(), "UPDATE products SET base_price = 12 WHERE id = $1", product1ID)
+		assert.NoError(t, err)
+		defer db.Exec(context.Background(), "UPDATE products SET base_price = 10 WHERE id = $1", product1ID)
+
+		// A quote for another basket is rejected
+		otherBasketID := uuid.NewString()
+		createBasket(t, db, otherBasketID, userID, "OPEN")
+		defer deleteBasket(t, db, otherBasketID)
+		orderID := uuid.NewString()
+		createOrder(t, db, orderID, userID, 0, 0, 0, "PENDING")
+		defer deleteOrder(t, db, orderID)
+		_, err = orderItemRepo.ConvertBasketToOrderItems(context.Background(), &order_service.ConvertBasketToOrderItemsRequest{
+			BasketId: otherBasketID,
+			OrderId:  orderID,
+			QuoteId:  q.Id,
+		})
+		assert.Error(t, err)
+
+		_, err = orderItemRepo.ConvertBasketToOrderItems(context.Background(), &order_service.ConvertBasketToOrderItemsRequest{
+			BasketId: basketID,
+			OrderId:  orderID,
+			QuoteId:  q.Id,
+		})
+		assert.NoError(t, err)
+
+		order, err := orderRepo.GetOrder(context.Background(), &order_service.GetOrderRequest{Id: orderID})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2000), order.TotalPrice.GetMinorUnits()) // The quoted 10.00, not 12.00
+
+		// An expired quote is refused
+		expiredBasketID := uuid.NewString()
+		createBasket(t, db, expiredBasketID, userID, "OPEN")
+		defer deleteBasket(t, db, expiredBasketID)
+		expiredItemID := uuid.NewString()
+		createBasketItemRegular(t, db, expiredItemID, expiredBasketID, product1ID, 1, 1200, 1200)
+		defer deleteBasketItem(t, db, expiredItemID)
+
+		expiring := postgres.NewBasketRepo(db, nil, exchangeRates, pricingEngine, quote.NewSigner([]byte("test key"), -time.Minute))
+		expired, err := expiring.QuoteBasket(context.Background(), &order_service.QuoteBasketRequest{Id: expiredBasketID})
+		assert.NoError(t, err)
+
+		expiredOrderID := uuid.NewString()
+		createOrder(t, db, expiredOrderID, userID, 0, 0, 0, "PENDING")
+		defer deleteOrder(t, db, expiredOrderID)
+		_, err = orderItemRepo.ConvertBasketToOrderItems(context.Background(), &order_service.ConvertBasketToOrderItemsRequest{
+			BasketId: expiredBasketID,
+			OrderId:  expiredOrderID,
+			QuoteId:  expired.Id,
+		})
+		assert.ErrorIs(t, err, storage.ErrQuoteExpired)
+	})
+
+	t.Run("DeleteOrderItem", func(t *testing.T) {
+		// Create a basket
+		basketID := uuid.NewString()
+		createBasket(t, db, basketID, userID, "OPEN")
+		defer deleteBasket(t, db, basketID)
+
+		// Create a basket item
+		basketItem1ID := uuid.NewString()
+		createBasketItemRegular(t, db, basketItem1ID, basketID, product1ID, 2, 1000, 2000)
+		defer deleteBasketItem(t, db, basketItem1ID)
 
 		// Create an order
 		orderID := uuid.NewString()
-		createOrder(t, db, orderID, userID, 0, 0, 20.0, "PENDING") // Initial total price is 20.0
+		createOrder(t, db, orderID, userID, 0, 0, 2000, "PENDING") // Initial total price is 20.00
 		defer deleteOrder(t, db, orderID)
 
 		// Convert basket items to order items
@@ -591,12 +1672,202 @@ func TestOrderRepo(t *testing.T) {
 		// Check if order total price is updated
 		order, err := orderRepo.GetOrder(context.Background(), &order_service.GetOrderRequest{Id: orderID})
 		assert.NoError(t, err)
-		assert.Equal(t, float32(0.0), order.TotalPrice) // Total price should be 0 after deleting the only item
+		assert.Equal(t, int64(0), order.TotalPrice.GetMinorUnits()) // Total price should be 0 after deleting the only item
+	})
+
+	t.Run("CancelOrderItem", func(t *testing.T) {
+		basketID := uuid.NewString()
+		createBasket(t, db, basketID, userID, "OPEN")
+		defer deleteBasket(t, db, basketID)
+
+		basketItemID := uuid.NewString()
+		createBasketItemRegular(t, db, basketItemID, basketID, product1ID, 2, 1000, 2000)
+		defer deleteBasketItem(t, db, basketItemID)
+
+		orderID := uuid.NewString()
+		createOrder(t, db, orderID, userID, 0, 0, 0, "PENDING")
+		defer deleteOrder(t, db, orderID)
+
+		_, err := orderItemRepo.ConvertBasketToOrderItems(context.Background(), &order_service.ConvertBasketToOrderItemsRequest{
+			BasketId: basketID,
+			OrderId:  orderID,
+		})
+		assert.NoError(t, err)
+
+		orderItems, err := orderItemRepo.ListOrderItems(context.Background(), &order_service.ListOrderItemsRequest{
+			OrderId: orderID,
+			Page:    1,
+			Limit:   10,
+		})
+		assert.NoError(t, err)
+		assert.Len(t, orderItems.OrderItems, 1)
+		orderItemID := orderItems.OrderItems[0].Id
+
+		// Cancel one of the two units
+		response, err := orderItemRepo.CancelOrderItem(context.Background(), &order_service.CancelOrderItemRequest{
+			Id:       orderItemID,
+			Quantity: 1,
+			Reason:   "damaged",
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1000), response.RefundAmount.GetMinorUnits())
+		assert.Equal(t, int32(2), response.OrderItem.Quantity)
+		assert.Equal(t, int32(1), response.OrderItem.CancelledQuantity)
+		assert.Equal(t, int64(1000), response.OrderItem.TotalPrice.GetMinorUnits())
+		assert.Equal(t, "damaged", response.OrderItem.CancellationReason)
+
+		order, err := orderRepo.GetOrder(context.Background(), &order_service.GetOrderRequest{Id: orderID})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1000), order.TotalPrice.GetMinorUnits())
+
+		// Cancelling more units than are left is rejected
+		_, err = orderItemRepo.CancelOrderItem(context.Background(), &order_service.CancelOrderItemRequest{
+			Id:       orderItemID,
+			Quantity: 2,
+		})
+		assert.ErrorIs(t, err, storage.ErrInvalidQuantity)
+
+		// Quantity 0 cancels the rest of the line
+		response, err = orderItemRepo.CancelOrderItem(context.Background(), &order_service.CancelOrderItemRequest{Id: orderItemID})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1000), response.RefundAmount.GetMinorUnits())
+		assert.Equal(t, int32(2), response.OrderItem.CancelledQuantity)
+		assert.Equal(t, int64(0), response.OrderItem.TotalPrice.GetMinorUnits())
+	})
+
+	t.Run("CancelOrderItemWithCoupon", func(t *testing.T) {
+		coupon, err := couponRepo.CreateCoupon(context.Background(), &order_service.CreateCouponRequest{
+			Coupon: &order_service.Coupon{
+				Code:       "TEN-" + uuid.NewString()[:8],
+				Effect:     "PERCENTAGE",
+				Percentage: "10",
+				StartsAt:   timestamppb.New(time.Now().Add(-time.Hour)),
+				EndsAt:     timestamppb.New(time.Now().Add(time.Hour)),
+				IsActive:   true,
+			},
+		})
+		assert.NoError(t, err)
+		defer couponRepo.DeleteCoupon(context.Background(), &order_service.DeleteCouponRequest{Id: coupon.Id})
+
+		basketID := uuid.NewString()
+		createBasket(t, db, basketID, userID, "OPEN")
+		defer deleteBasket(t, db, basketID)
+
+		basketItemID := uuid.NewString()
+		createBasketItemRegular(t, db, basketItemID, basketID, product1ID, 2, 1000, 2000)
+		defer deleteBasketItem(t, db, basketItemID)
+
+		// Delivered inside the taxed region
+		orderID := uuid.NewString()
+		createOrder(t, db, orderID, userID, 60.5, 60.5, 0, "PENDING")
+		defer deleteOrder(t, db, orderID)
+
+		_, err = orderItemRepo.ConvertBasketToOrderItems(context.Background(), &order_service.ConvertBasketToOrderItemsRequest{
+			BasketId:    basketID,
+			OrderId:     orderID,
+			CouponCodes: []string{coupon.Code},
+		})
+		assert.NoError(t, err)
+
+		order, err := orderRepo.GetOrder(context.Background(), &order_service.GetOrderRequest{Id: orderID})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(200), order.DiscountTotal.GetMinorUnits())
+		assert.Equal(t, int64(1980), order.TotalPrice.GetMinorUnits()) // 20.00 less 10%, plus 10% tax
+
+		orderItems, err := orderItemRepo.ListOrderItems(context.Background(), &order_service.ListOrderItemsRequest{
+			OrderId: orderID,
+			Page:    1,
+			Limit:   10,
+		})
+		assert.NoError(t, err)
+		assert.Len(t, orderItems.OrderItems, 1)
+
+		// One unit gives back its price less its half of the coupon, plus its tax
+		response, err := orderItemRepo.CancelOrderItem(context.Background(), &order_service.CancelOrderItemRequest{
+			Id:       orderItems.OrderItems[0].Id,
+			Quantity: 1,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(990), response.RefundAmount.GetMinorUnits())
+
+		order, err = orderRepo.GetOrder(context.Background(), &order_service.GetOrderRequest{Id: orderID})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(100), order.DiscountTotal.GetMinorUnits())
+		assert.Equal(t, int64(90), order.TaxTotal.GetMinorUnits())
+		assert.Equal(t, int64(990), order.TotalPrice.GetMinorUnits())
+		assert.Len(t, order.AppliedPromotions, 1)
+		assert.Equal(t, int64(100), order.AppliedPromotions[0].Discount.GetMinorUnits())
+	})
+
+	t.Run("CancelOrderRestocksClaimedFlashSaleUnits", func(t *testing.T) {
+		flashProductID := uuid.NewString()
+		createProduct(t, db, flashProductID, "Flash Product", 20.0)
+		defer deleteProduct(t, db, flashProductID)
+		regularProductID := uuid.NewString()
+		createProduct(t, db, regularProductID, "Regular Product", 10.0)
+		defer deleteProduct(t, db, regularProductID)
+		lateProductID := uuid.NewString()
+		createProduct(t, db, lateProductID, "Late Product", 20.0)
+		defer deleteProduct(t, db, lateProductID)
+
+		flashID := uuid.NewString()
+		createFlashSaleEventProduct(t, db, flashID, flashSaleEventID, flashProductID, 20.0, 18.0)
+		defer deleteFlashSaleEventProduct(t, db, flashID)
+
+		// A flash sale that is over, so its item is sold at the regular price
+		endedEventID := uuid.NewString()
+		createFlashSaleEvent(t, db, endedEventID, "Ended Flash Sale", time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour), "ACTIVE")
+		defer deleteFlashSaleEvent(t, db, endedEventID)
+		endedID := uuid.NewString()
+		createFlashSaleEventProduct(t, db, endedID, endedEventID, lateProductID, 20.0, 18.0)
+		defer deleteFlashSaleEventProduct(t, db, endedID)
+
+		basketID := uuid.NewString()
+		createBasket(t, db, basketID, userID, "OPEN")
+		defer deleteBasket(t, db, basketID)
+		for _, item := range []struct{ productID, flashSaleEventProductID string }{
+			{flashProductID, flashID},
+			{regularProductID, ""},
+			{lateProductID, endedID},
+		} {
+			basketItemID := uuid.NewString()
+			if item.flashSaleEventProductID == "" {
+				createBasketItemRegular(t, db, basketItemID, basketID, item.productID, 2, 1000, 2000)
+			} else {
+				createBasketItemFlashSale(t, db, basketItemID, basketID, item.productID, item.flashSaleEventProductID, 2, 1800, 3600)
+			}
+			defer deleteBasketItem(t, db, basketItemID)
+		}
+
+		orderID := uuid.NewString()
+		createOrder(t, db, orderID, userID, 0, 0, 0, "PENDING")
+		defer deleteOrder(t, db, orderID)
+
+		_, err := orderItemRepo.ConvertBasketToOrderItems(context.Background(), &order_service.ConvertBasketToOrderItemsRequest{
+			BasketId: basketID,
+			OrderId:  orderID,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, int32(98), productStock(t, db, flashProductID))
+		assert.Equal(t, int32(98), productStock(t, db, regularProductID))
+		assert.Equal(t, int32(98), productStock(t, db, lateProductID))
+		assert.Equal(t, int32(8), flashSaleAvailable(t, db, flashID))
+		assert.Equal(t, int32(10), flashSaleAvailable(t, db, endedID))
+
+		_, err = orderRepo.CancelOrder(context.Background(), &order_service.CancelOrderRequest{Id: orderID, Reason: "changed my mind"})
+		assert.NoError(t, err)
+
+		// Only the units taken from the running flash sale go back to it
+		assert.Equal(t, int32(100), productStock(t, db, flashProductID))
+		assert.Equal(t, int32(100), productStock(t, db, regularProductID))
+		assert.Equal(t, int32(100), productStock(t, db, lateProductID))
+		assert.Equal(t, int32(10), flashSaleAvailable(t, db, flashID))
+		assert.Equal(t, int32(10), flashSaleAvailable(t, db, endedID))
 	})
 }
 
 // Helper functions to create and delete test data
-func createUser(t *testing.T, db *pgx.Conn, userID string) {
+func createUser(t *testing.T, db *pgxpool.Pool, userID string) {
 	_, err := db.Exec(context.Background(), `
 		INSERT INTO users (id, username, email, password_hash, full_name, date_of_birth, role, created_at, updated_at, deleted_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW(), 0)
@@ -604,12 +1875,12 @@ func createUser(t *testing.T, db *pgx.Conn, userID string) {
 	assert.NoError(t, err)
 }
 
-func deleteUser(t *testing.T, db *pgx.Conn, userID string) {
+func deleteUser(t *testing.T, db *pgxpool.Pool, userID string) {
 	// _, err := db.Exec(context.Background(), "DELETE FROM users WHERE id = $1", userID)
 	// assert.NoError(t, err)
 }
 
-func createProduct(t *testing.T, db *pgx.Conn, productID, name string, price float32) {
+func createProduct(t *testing.T, db *pgxpool.Pool, productID, name string, price float32) {
 	_, err := db.Exec(context.Background(), `
 		INSERT INTO products (id, name, description, base_price, current_price, image_url, stock_quantity, created_at, updated_at, deleted_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW(), 0)
@@ -617,12 +1888,12 @@ func createProduct(t *testing.T, db *pgx.Conn, productID, name string, price flo
 	assert.NoError(t, err)
 }
 
-func deleteProduct(t *testing.T, db *pgx.Conn, productID string) {
+func deleteProduct(t *testing.T, db *pgxpool.Pool, productID string) {
 	// _, err := db.Exec(context.Background(), "DELETE FROM products WHERE id = $1", productID)
 	// assert.NoError(t, err)
 }
 
-func createFlashSaleEvent(t *testing.T, db *pgx.Conn, eventID, name string, startTime, endTime time.Time, status string) {
+func createFlashSaleEvent(t *testing.T, db *pgxpool.Pool, eventID, name string, startTime, endTime time.Time, status string) {
 	_, err := db.Exec(context.Background(), `
 		INSERT INTO flash_sale_events (id, name, description, start_time, end_time, status, event_type, created_at, updated_at, deleted_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW(), 0)
@@ -630,12 +1901,12 @@ func createFlashSaleEvent(t *testing.T, db *pgx.Conn, eventID, name string, star
 	assert.NoError(t, err)
 }
 
-func deleteFlashSaleEvent(t *testing.T, db *pgx.Conn, eventID string) {
+func deleteFlashSaleEvent(t *testing.T, db *pgxpool.Pool, eventID string) {
 	// _, err := db.Exec(context.Background(), "DELETE FROM flash_sale_events WHERE id = $1", eventID)
 	// assert.NoError(t, err)
 }
 
-func createDiscount(t *testing.T, db *pgx.Conn, discountID, name, discountType string, discountValue float32, isActive bool) {
+func createDiscount(t *testing.T, db *pgxpool.Pool, discountID, name, discountType string, discountValue float32, isActive bool) {
 	_, err := db.Exec(context.Background(), `
 		INSERT INTO discounts (id, name, description, discount_type, discount_value, start_date, end_date, is_active, created_at, updated_at, deleted_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW(), 0)
@@ -643,12 +1914,12 @@ func createDiscount(t *testing.T, db *pgx.Conn, discountID, name, discountType s
 	assert.NoError(t, err)
 }
 
-func deleteDiscount(t *testing.T, db *pgx.Conn, discountID string) {
+func deleteDiscount(t *testing.T, db *pgxpool.Pool, discountID string) {
 	_, err := db.Exec(context.Background(), "DELETE FROM discounts WHERE id = $1", discountID)
 	assert.NoError(t, err)
 }
 
-func createProductDiscount(t *testing.T, db *pgx.Conn, productDiscountID, productID, discountID string) {
+func createProductDiscount(t *testing.T, db *pgxpool.Pool, productDiscountID, productID, discountID string) {
 	_, err := db.Exec(context.Background(), `
 		INSERT INTO product_discounts (id, product_id, discount_id, created_at, updated_at, deleted_at)
 		VALUES ($1, $2, $3, NOW(), NOW(), 0)
@@ -656,12 +1927,12 @@ func createProductDiscount(t *testing.T, db *pgx.Conn, productDiscountID, produc
 	assert.NoError(t, err)
 }
 
-func deleteProductDiscount(t *testing.T, db *pgx.Conn, productDiscountID string) {
+func deleteProductDiscount(t *testing.T, db *pgxpool.Pool, productDiscountID string) {
 	_, err := db.Exec(context.Background(), "DELETE FROM product_discounts WHERE id = $1", productDiscountID)
 	assert.NoError(t, err)
 }
 
-func createFlashSaleEventProduct(t *testing.T, db *pgx.Conn, flashSaleEventProductID, eventID, productID string, discountPercentage, salePrice float32) {
+func createFlashSaleEventProduct(t *testing.T, db *pgxpool.Pool, flashSaleEventProductID, eventID, productID string, discountPercentage, salePrice float32) {
 	_, err := db.Exec(context.Background(), `
 		INSERT INTO flash_sale_event_products (id, event_id, product_id, discount_percentage, sale_price, available_quantity, original_stock, created_at, updated_at, deleted_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW(), 0)
@@ -669,12 +1940,44 @@ func createFlashSaleEventProduct(t *testing.T, db *pgx.Conn, flashSaleEventProdu
 	assert.NoError(t, err)
 }
 
-func deleteFlashSaleEventProduct(t *testing.T, db *pgx.Conn, flashSaleEventProductID string) {
+func productStock(t *testing.T, db *pgxpool.Pool, productID string) int32 {
+	var stock int32
+	err := db.QueryRow(context.Background(), "SELECT stock_quantity FROM products WHERE id = $1", productID).Scan(&stock)
+	assert.NoError(t, err)
+	return stock
+}
+
+func flashSaleAvailable(t *testing.T, db *pgxpool.Pool, flashSaleEventProductID string) int32 {
+	var available int32
+	err := db.QueryRow(context.Background(), "SELECT available_quantity FROM flash_sale_event_products WHERE id = $1", flashSaleEventProductID).Scan(&available)
+	assert.NoError(t, err)
+	return available
+}
+
+// stockHold returns the latest stock hold of a basket item.
+func stockHold(t *testing.T, db *pgxpool.Pool, basketItemID string) (string, int32, time.Time) {
+	var (
+		status    string
+		quantity  int32
+		expiresAt time.Time
+	)
+	err := db.QueryRow(context.Background(), `
+		SELECT status, quantity, expires_at
+		FROM stock_holds
+		WHERE basket_item_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, basketItemID).Scan(&status, &quantity, &expiresAt)
+	assert.NoError(t, err)
+	return status, quantity, expiresAt
+}
+
+func deleteFlashSaleEventProduct(t *testing.T, db *pgxpool.Pool, flashSaleEventProductID string) {
 	// _, err := db.Exec(context.Background(), "DELETE FROM flash_sale_event_products WHERE id = $1", flashSaleEventProductID)
 	// assert.NoError(t, err)
 }
 
-func createBasket(t *testing.T, db *pgx.Conn, basketID, userID, status string) {
+func createBasket(t *testing.T, db *pgxpool.Pool, basketID, userID, status string) {
 	_, err := db.Exec(context.Background(), `
 		INSERT INTO baskets (id, user_id, status, created_at, updated_at, deleted_at)
 		VALUES ($1, $2, $3, NOW(), NOW(), 0)
@@ -682,12 +1985,12 @@ func createBasket(t *testing.T, db *pgx.Conn, basketID, userID, status string) {
 	assert.NoError(t, err)
 }
 
-func deleteBasket(t *testing.T, db *pgx.Conn, basketID string) {
+func deleteBasket(t *testing.T, db *pgxpool.Pool, basketID string) {
 	// _, err := db.Exec(context.Background(), "DELETE FROM baskets WHERE id = $1", basketID)
 	// assert.NoError(t, err)
 }
 
-func createBasketItemRegular(t *testing.T, db *pgx.Conn, basketItemID, basketID, productID string, quantity int32, unitPrice, totalPrice float32) {
+func createBasketItemRegular(t *testing.T, db *pgxpool.Pool, basketItemID, basketID, productID string, quantity int32, unitPrice, totalPrice money.Amount) {
 	_, err := db.Exec(context.Background(), `
 		INSERT INTO basket_items (id, basket_id, product_id, quantity, unit_price, total_price, product_type, created_at, updated_at, deleted_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW(), 0)
@@ -695,7 +1998,7 @@ func createBasketItemRegular(t *testing.T, db *pgx.Conn, basketItemID, basketID,
 	assert.NoError(t, err)
 }
 
-func createBasketItemFlashSale(t *testing.T, db *pgx.Conn, basketItemID, basketID, productID, flashSaleEventProductID string, quantity int32, unitPrice, totalPrice float32) {
+func createBasketItemFlashSale(t *testing.T, db *pgxpool.Pool, basketItemID, basketID, productID, flashSaleEventProductID string, quantity int32, unitPrice, totalPrice money.Amount) {
 	_, err := db.Exec(context.Background(), `
 		INSERT INTO basket_items (id, basket_id, product_id, flash_sale_event_product_id, quantity, unit_price, total_price, product_type, created_at, updated_at, deleted_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW(), 0)
@@ -703,7 +2006,7 @@ func createBasketItemFlashSale(t *testing.T, db *pgx.Conn, basketItemID, basketI
 	assert.NoError(t, err)
 }
 
-func createBasketItemDiscount(t *testing.T, db *pgx.Conn, basketItemID, basketID, productID, discountProductID string, quantity int32, unitPrice, totalPrice float32) {
+func createBasketItemDiscount(t *testing.T, db *pgxpool.Pool, basketItemID, basketID, productID, discountProductID string, quantity int32, unitPrice, totalPrice money.Amount) {
 	_, err := db.Exec(context.Background(), `
 		INSERT INTO basket_items (id, basket_id, product_id, discount_product_id, quantity, unit_price, total_price, product_type, created_at, updated_at, deleted_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW(), 0)
@@ -711,12 +2014,12 @@ func createBasketItemDiscount(t *testing.T, db *pgx.Conn, basketItemID, basketID
 	assert.NoError(t, err)
 }
 
-func deleteBasketItem(t *testing.T, db *pgx.Conn, basketItemID string) {
+func deleteBasketItem(t *testing.T, db *pgxpool.Pool, basketItemID string) {
 	_, err := db.Exec(context.Background(), "DELETE FROM basket_items WHERE id = $1", basketItemID)
 	assert.NoError(t, err)
 }
 
-func createOrder(t *testing.T, db *pgx.Conn, orderID, clientID string, deliveryLatitude, deliveryLongitude float64, totalPrice float32, status string) {
+func createOrder(t *testing.T, db *pgxpool.Pool, orderID, clientID string, deliveryLatitude, deliveryLongitude float64, totalPrice money.Amount, status string) {
 	_, err := db.Exec(context.Background(), `
 		INSERT INTO orders (id, client_id, delivery_latitude, delivery_longitude, total_price, status, created_at, updated_at, deleted_at)
 		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW(), 0)
@@ -724,7 +2027,11 @@ func createOrder(t *testing.T, db *pgx.Conn, orderID, clientID string, deliveryL
 	assert.NoError(t, err)
 }
 
-func deleteOrder(t *testing.T, db *pgx.Conn, orderID string) {
+func deleteOrder(t *testing.T, db *pgxpool.Pool, orderID string) {
 	// _, err := db.Exec(context.Background(), "DELETE FROM orders WHERE id = $1", orderID)
 	// assert.NoError(t, err)
 }
+
+func usd(minorUnits int64) *order_service.Money {
+	return &order_service.Money{MinorUnits: minorUnits, CurrencyCode: "USD"}
+}
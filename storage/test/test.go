@@ -3,12 +3,14 @@ package test
 import (
 	"context"
 	"fmt"
+	"sync"
 	"testing"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/flash_sale/flash_sale_order_service/storage"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func createDBConnection(t *testing.T) *pgx.Conn {
+func createDBConnection(t *testing.T) *pgxpool.Pool {
 	dbCon := fmt.Sprintf("postgresql://%s:%s@%s:%d/%s",
 		"sayyidmuhammad",
 		"root",
@@ -18,9 +20,57 @@ func createDBConnection(t *testing.T) *pgx.Conn {
 	)
 
 	// Connecting to postgres
-	db, err := pgx.Connect(context.Background(), dbCon)
+	db, err := pgxpool.New(context.Background(), dbCon)
 	if err != nil {
 		t.Fatalf("Unable to connect to database: %v", err)
 	}
+	if err = db.Ping(context.Background()); err != nil {
+		t.Fatalf("Unable to connect to database: %v", err)
+	}
 	return db
 }
+
+// memoryStockCache is a storage.StockCacheI that keeps its counters in a map.
+type memoryStockCache struct {
+	mu     sync.Mutex
+	counts map[string]int32
+}
+
+func newMemoryStockCache() *memoryStockCache {
+	return &memoryStockCache{counts: make(map[string]int32)}
+}
+
+func (c *memoryStockCache) set(flashSaleEventProductID string, quantity int32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[flashSaleEventProductID] = quantity
+}
+
+func (c *memoryStockCache) get(flashSaleEventProductID string) int32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts[flashSaleEventProductID]
+}
+
+func (c *memoryStockCache) ReserveFlashSaleStock(ctx context.Context, flashSaleEventProductID string, quantity int32) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	available, ok := c.counts[flashSaleEventProductID]
+	if !ok {
+		return storage.ErrStockNotCached
+	}
+	if available < quantity {
+		return storage.ErrSoldOut
+	}
+	c.counts[flashSaleEventProductID] = available - quantity
+	return nil
+}
+
+func (c *memoryStockCache) ReleaseFlashSaleStock(ctx context.Context, flashSaleEventProductID string, quantity int32) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.counts[flashSaleEventProductID]; ok {
+		c.counts[flashSaleEventProductID] += quantity
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+// Package tax works out the tax owed on order items from the region they are delivered to.
+//
+// Each region has a rate per product tax category and says whether its prices already
+// include the tax. Order discounts are spread over the items in proportion to their
+// totals before the tax is worked out, so tax is only charged on what is actually paid.
+package tax
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/flash_sale/flash_sale_order_service/money"
+)
+
+// DefaultCategory is the tax category of products that have none, and the rate used for
+// categories a region does not list.
+const DefaultCategory = "STANDARD"
+
+// Bounds is a latitude and longitude box, edges included.
+type Bounds struct {
+	MinLatitude  float64 `json:"min_latitude"`
+	MinLongitude float64 `json:"min_longitude"`
+	MaxLatitude  float64 `json:"max_latitude"`
+	MaxLongitude float64 `json:"max_longitude"`
+}
+
+// Contains reports whether a point is inside the box.
+func (b Bounds) Contains(latitude, longitude float64) bool {
+	return latitude >= b.MinLatitude && latitude <= b.MaxLatitude &&
+		longitude >= b.MinLongitude && longitude <= b.MaxLongitude
+}
+
+// Region is an area with its own tax rates.
+type Region struct {
+	Name string
+	// Bounds is the area of the region; a region without bounds covers everywhere.
+	Bounds *Bounds
+	// Inclusive regions have the tax included in their prices; otherwise it is added on top.
+	Inclusive bool
+	// Rates holds the rate of each product tax category.
+	Rates map[string]money.Percent
+}
+
+// Rate returns the rate of a product tax category.
+func (r Region) Rate(category string) money.Percent {
+	if rate, ok := r.Rates[category]; ok {
+		return rate
+	}
+	return r.Rates[DefaultCategory]
+}
+
+// Tax returns the tax owed on amount for a product tax category.
+func (r Region) Tax(amount money.Amount, category string) money.Amount {
+	if r.Inclusive {
+		return amount.IncludedPercentage(r.Rate(category))
+	}
+	return amount.Percentage(r.Rate(category))
+}
+
+// Item is what an order item is taxed on.
+type Item struct {
+	Category string
+	Total    money.Amount
+}
+
+// Items returns the tax owed on each item once discount has been spread over them in
+// proportion to their totals.
+func (r Region) Items(items []Item, discount money.Amount) []money.Amount {
+	var subtotal money.Amount
+	for _, item := range items {
+		subtotal += item.Total
+	}
+	if discount > subtotal {
+		discount = subtotal
+	}
+
+	taxes := make([]money.Amount, len(items))
+	left := discount
+	for i, item := range items {
+		share := left
+		if i < len(items)-1 && subtotal > 0 {
+			share = discount.Share(int64(item.Total), int64(subtotal))
+			if share > left {
+				share = left
+			}
+		}
+		left -= share
+		taxes[i] = r.Tax(item.Total.Minus(share), item.Category)
+	}
+	return taxes
+}
+
+// Table holds the tax regions, checked in order.
+type Table struct {
+	regions []Region
+}
+
+// NewTable returns a Table of regions. The first region containing a point is the one
+// used for it.
+func NewTable(regions []Region) *Table {
+	return &Table{
+		regions: regions,
+	}
+}
+
+// Region returns the region a point is taxed in. A nil Table, or a point outside every
+// region, is not taxed.
+func (t *Table) Region(latitude, longitude float64) (Region, bool) {
+	if t == nil {
+		return Region{}, false
+	}
+	for _, region := range t.regions {
+		if region.Bounds == nil || region.Bounds.Contains(latitude, longitude) {
+			return region, true
+		}
+	}
+	return Region{}, false
+}
+
+// tableFile is the layout of a tax rates file, e.g.
+//
+//	{"regions": [{"name": "UZ", "inclusive": true,
+//		"bounds": {"min_latitude": 37.1, "min_longitude": 55.9, "max_latitude": 45.6, "max_longitude": 73.2},
+//		"rates": {"STANDARD": "12", "FOOD": "0"}}]}
+type tableFile struct {
+	Regions []struct {
+		Name      string            `json:"name"`
+		Bounds    *Bounds           `json:"bounds"`
+		Inclusive bool              `json:"inclusive"`
+		Rates     map[string]string `json:"rates"`
+	} `json:"regions"`
+}
+
+// LoadTable reads a Table from a JSON tax rates file.
+func LoadTable(path string) (*Table, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tax rates file: %w", err)
+	}
+
+	var file tableFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse tax rates file: %w", err)
+	}
+
+	regions := make([]Region, 0, len(file.Regions))
+	for _, r := range file.Regions {
+		if r.Name == "" {
+			return nil, fmt.Errorf("tax rates file has a region without a name")
+		}
+		if r.Bounds != nil && (r.Bounds.MinLatitude > r.Bounds.MaxLatitude || r.Bounds.MinLongitude > r.Bounds.MaxLongitude) {
+			return nil, fmt.Errorf("tax rates file has empty bounds for region %s", r.Name)
+		}
+		region := Region{
+			Name:      r.Name,
+			Bounds:    r.Bounds,
+			Inclusive: r.Inclusive,
+			Rates:     make(map[string]money.Percent, len(r.Rates)),
+		}
+		for category, value := range r.Rates {
+			rate, err := money.ParsePercent(value)
+			if err != nil || rate < 0 {
+				return nil, fmt.Errorf("tax rates file has an invalid rate %q for %s in region %s", value, category, r.Name)
+			}
+			region.Rates[category] = rate
+		}
+		regions = append(regions, region)
+	}
+
+	return NewTable(regions), nil
+}
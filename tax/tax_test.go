@@ -0,0 +1,108 @@
+package tax
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/flash_sale/flash_sale_order_service/money"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	exclusive = Region{
+		Name:  "exclusive",
+		Rates: map[string]money.Percent{DefaultCategory: 1000, "FOOD": 500}, // 10%, 5%
+	}
+	inclusive = Region{
+		Name:      "inclusive",
+		Inclusive: true,
+		Rates:     map[string]money.Percent{DefaultCategory: 1200}, // 12%
+	}
+)
+
+func TestTax(t *testing.T) {
+	assert.Equal(t, money.Amount(100), exclusive.Tax(1000, DefaultCategory))
+	assert.Equal(t, money.Amount(50), exclusive.Tax(1000, "FOOD"))
+	assert.Equal(t, money.Amount(100), exclusive.Tax(1000, "TOYS")) // unknown categories pay the default rate
+	assert.Equal(t, money.Amount(120), inclusive.Tax(1120, DefaultCategory))
+	assert.Equal(t, money.Amount(0), Region{}.Tax(1000, DefaultCategory))
+}
+
+func TestItems(t *testing.T) {
+	items := []Item{
+		{Category: DefaultCategory, Total: 3000},
+		{Category: "FOOD", Total: 1000},
+	}
+
+	assert.Equal(t, []money.Amount{300, 50}, exclusive.Items(items, 0))
+
+	// 4.00 off is spread as 3.00 and 1.00
+	assert.Equal(t, []money.Amount{270, 45}, exclusive.Items(items, 400))
+
+	// A discount larger than the items leaves nothing to tax
+	assert.Equal(t, []money.Amount{0, 0}, exclusive.Items(items, 5000))
+
+	// Rounding leftovers go to the last item, so the shares add up to the discount
+	thirds := []Item{{Total: 100}, {Total: 100}, {Total: 100}}
+	assert.Equal(t, []money.Amount{7, 7, 7}, exclusive.Items(thirds, 100)) // 33, 33 and 34 cents off
+}
+
+func TestTableRegion(t *testing.T) {
+	city := Region{Name: "city", Bounds: &Bounds{MinLatitude: 41, MinLongitude: 69, MaxLatitude: 42, MaxLongitude: 70}}
+	table := NewTable([]Region{city, exclusive})
+
+	got, ok := table.Region(41.3, 69.2)
+	assert.True(t, ok)
+	assert.Equal(t, "city", got.Name)
+
+	got, ok = table.Region(40, 69.2)
+	assert.True(t, ok)
+	assert.Equal(t, "exclusive", got.Name)
+
+	_, ok = NewTable([]Region{city}).Region(40, 69.2)
+	assert.False(t, ok)
+
+	var none *Table
+	_, ok = none.Region(41.3, 69.2)
+	assert.False(t, ok)
+}
+
+func TestLoadTable(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "taxes.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"regions": [
+		{"name": "city", "inclusive": true,
+			"bounds": {"min_latitude": 41, "min_longitude": 69, "max_latitude": 42, "max_longitude": 70},
+			"rates": {"STANDARD": "12", "FOOD": "0"}},
+		{"name": "elsewhere", "rates": {"STANDARD": "7.5"}}
+	]}`), 0o600))
+
+	table, err := LoadTable(path)
+	assert.NoError(t, err)
+
+	got, ok := table.Region(41.3, 69.2)
+	assert.True(t, ok)
+	assert.True(t, got.Inclusive)
+	assert.Equal(t, money.Percent(1200), got.Rate(DefaultCategory))
+	assert.Equal(t, money.Percent(0), got.Rate("FOOD"))
+
+	got, ok = table.Region(0, 0)
+	assert.True(t, ok)
+	assert.Equal(t, money.Percent(750), got.Rate("FOOD"))
+
+	for name, content := range map[string]string{
+		"bad rate":     `{"regions": [{"name": "x", "rates": {"STANDARD": "lots"}}]}`,
+		"no name":      `{"regions": [{"rates": {"STANDARD": "12"}}]}`,
+		"empty bounds": `{"regions": [{"name": "x", "bounds": {"min_latitude": 2, "max_latitude": 1}}]}`,
+		"not json":     `regions: []`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, "bad.json")
+			assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+			_, err := LoadTable(path)
+			assert.Error(t, err)
+		})
+	}
+}
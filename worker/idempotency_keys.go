@@ -0,0 +1,46 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/flash_sale/flash_sale_order_service/storage"
+)
+
+// IdempotencyKeySweeper removes idempotency keys once their replay window has passed.
+type IdempotencyKeySweeper struct {
+	storage  storage.StorageI
+	interval time.Duration
+}
+
+// NewIdempotencyKeySweeper creates a new IdempotencyKeySweeper instance.
+func NewIdempotencyKeySweeper(storage storage.StorageI, interval time.Duration) *IdempotencyKeySweeper {
+	return &IdempotencyKeySweeper{
+		storage:  storage,
+		interval: interval,
+	}
+}
+
+// Run removes expired keys every interval until ctx is done.
+func (s *IdempotencyKeySweeper) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		deleted, err := s.storage.Order().DeleteExpiredIdempotencyKeys(ctx)
+		if err != nil {
+			log.Printf("failed to delete expired idempotency keys: %v", err)
+			continue
+		}
+		if deleted > 0 {
+			log.Printf("deleted %d expired idempotency keys", deleted)
+		}
+	}
+}
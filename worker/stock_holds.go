@@ -0,0 +1,46 @@
+package worker
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/flash_sale/flash_sale_order_service/storage"
+)
+
+// StockHoldSweeper releases flash sale stock holds once they expire.
+type StockHoldSweeper struct {
+	storage  storage.StorageI
+	interval time.Duration
+}
+
+// NewStockHoldSweeper creates a new StockHoldSweeper instance.
+func NewStockHoldSweeper(storage storage.StorageI, interval time.Duration) *StockHoldSweeper {
+	return &StockHoldSweeper{
+		storage:  storage,
+		interval: interval,
+	}
+}
+
+// Run releases expired holds every interval until ctx is done.
+func (s *StockHoldSweeper) Run(ctx context.Context) error {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		released, err := s.storage.FlashSale().ReleaseExpiredStockHolds(ctx)
+		if err != nil {
+			log.Printf("failed to release expired stock holds: %v", err)
+			continue
+		}
+		if released > 0 {
+			log.Printf("released %d expired stock holds", released)
+		}
+	}
+}